@@ -0,0 +1,99 @@
+// Command egress-proxy runs next to a session with an egress policy. It is the session's
+// only way out, allowing connections to the destinations in its rules, and forwards the
+// session's published ports to it.
+//
+// Run with "stats" as its argument it prints the counters of the running proxy.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yourusername/session-manager/pkg/egress"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func main() {
+	listenAddr := fmt.Sprintf(":%d", egress.ProxyPort)
+	if value := os.Getenv(egress.EnvListen); value != "" {
+		listenAddr = value
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		if err := printStats(listenAddr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger := util.NewLogger()
+
+	rules, forwards, err := egress.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to read configuration: %v", err)
+	}
+	policy, err := egress.NewPolicy(rules)
+	if err != nil {
+		log.Fatalf("Invalid egress rules: %v", err)
+	}
+	proxy := egress.NewProxy(policy)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, forward := range forwards {
+		go func(forward egress.Forward) {
+			logger.Info("Forwarding port %d to %s", forward.ListenPort, forward.Target)
+			if err := proxy.Forward(ctx, forward); err != nil {
+				log.Fatalf("Failed to forward port %d: %v", forward.ListenPort, err)
+			}
+		}(forward)
+	}
+
+	srv := &http.Server{Addr: listenAddr, Handler: proxy}
+	go func() {
+		logger.Info("Egress proxy listening on %s with %d rules", listenAddr, len(rules))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start proxy: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdownCtx)
+}
+
+// printStats asks the running proxy for its counters and prints them
+func printStats(listenAddr string) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://127.0.0.1" + portOf(listenAddr) + egress.StatsPath)
+	if err != nil {
+		return fmt.Errorf("failed to reach proxy: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy returned status %d", resp.StatusCode)
+	}
+	_, err = io.Copy(os.Stdout, resp.Body)
+	return err
+}
+
+// portOf returns the ":port" part of a listen address
+func portOf(listenAddr string) string {
+	for i := len(listenAddr) - 1; i >= 0; i-- {
+		if listenAddr[i] == ':' {
+			return listenAddr[i:]
+		}
+	}
+	return ":" + listenAddr
+}
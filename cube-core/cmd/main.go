@@ -1,19 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/docker/docker/client"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/yourusername/session-manager/internal/config"
 	"github.com/yourusername/session-manager/internal/handler"
 	"github.com/yourusername/session-manager/internal/service"
+	"github.com/yourusername/session-manager/internal/store"
 	"github.com/yourusername/session-manager/pkg/docker"
 	"github.com/yourusername/session-manager/pkg/port"
 	"github.com/yourusername/session-manager/pkg/util"
@@ -24,12 +28,20 @@ func main() {
 	logger.Info("Starting session manager")
 
 	// Load configuration
-	cfg := config.DefaultConfig()
+	cfg := config.LoadConfig()
 	logger.Info("Using configuration: ServerPort=%d", cfg.ServerPort)
+	if err := service.ValidatePortURLTemplate(cfg.PortURLTemplate); err != nil {
+		logger.Error("Invalid configuration: %v", err)
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Initialize Docker manager
 	logger.Info("Initializing Docker manager")
-	dockerManager, err := docker.NewDockerManager()
+	var dockerOpts []client.Opt
+	if cfg.DockerHost != "" {
+		dockerOpts = append(dockerOpts, client.WithHost(cfg.DockerHost))
+	}
+	dockerManager, err := docker.NewDockerManager(dockerOpts...)
 	if err != nil {
 		logger.Error("Failed to create Docker manager: %v", err)
 		log.Fatalf("Failed to create Docker manager: %v", err)
@@ -39,9 +51,74 @@ func main() {
 	logger.Info("Initializing port manager")
 	portManager := port.NewPortManager()
 
+	// Initialize session store
+	var sessionStore store.SessionStore
+	if cfg.SessionStorePath != "" {
+		logger.Info("Initializing file session store at %s", cfg.SessionStorePath)
+		fileStore, err := store.NewFileStore(cfg.SessionStorePath)
+		if err != nil {
+			logger.Error("Failed to create session store: %v", err)
+			log.Fatalf("Failed to create session store: %v", err)
+		}
+		sessionStore = fileStore
+	} else {
+		logger.Warn("No session store path configured, sessions will not survive restarts")
+		sessionStore = store.NewMemoryStore()
+	}
+
+	// Initialize template store
+	var templateStore store.TemplateStore
+	if cfg.TemplateStorePath != "" {
+		logger.Info("Initializing file template store at %s", cfg.TemplateStorePath)
+		fileStore, err := store.NewFileTemplateStore(cfg.TemplateStorePath)
+		if err != nil {
+			logger.Error("Failed to create template store: %v", err)
+			log.Fatalf("Failed to create template store: %v", err)
+		}
+		templateStore = fileStore
+	} else {
+		logger.Warn("No template store path configured, templates created through the API will not survive restarts")
+		templateStore = store.NewMemoryTemplateStore()
+	}
+
 	// Initialize session service
 	logger.Info("Initializing session service")
-	sessionService := service.NewSessionService(dockerManager, portManager)
+	sessionService := service.NewSessionService(cfg, dockerManager, portManager, sessionStore, templateStore)
+	if _, err := sessionService.RestoreSessions(); err != nil {
+		logger.Error("Failed to restore sessions: %v", err)
+		log.Fatalf("Failed to restore sessions: %v", err)
+	}
+
+	// Load templates, then any new versions from the template directory
+	if _, err := sessionService.RestoreTemplates(); err != nil {
+		logger.Error("Failed to restore templates: %v", err)
+		log.Fatalf("Failed to restore templates: %v", err)
+	}
+	if cfg.TemplateDir != "" {
+		logger.Info("Loading templates from %s", cfg.TemplateDir)
+		if _, err := sessionService.LoadTemplateDir(cfg.TemplateDir); err != nil {
+			logger.Error("Failed to load templates: %v", err)
+			log.Fatalf("Failed to load templates: %v", err)
+		}
+	}
+
+	// Reconcile sessions with the containers Docker knows about
+	logger.Info("Reconciling sessions with managed containers")
+	if _, err := sessionService.ReconcileSessions(); err != nil {
+		logger.Error("Failed to reconcile sessions: %v", err)
+	}
+
+	// Background tasks run until shutdown
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Start reaping expired sessions
+	logger.Info("Starting session reaper (interval %s)", cfg.ReaperInterval)
+	sessionService.StartReaper(backgroundCtx)
+
+	// Start following container health
+	logger.Info("Starting health monitor (interval %s)", cfg.HealthCheckInterval)
+	sessionService.StartHealthMonitor(backgroundCtx)
 
 	// Initialize metrics service
 	logger.Info("Initializing metrics service")
@@ -51,9 +128,18 @@ func main() {
 		log.Fatalf("Failed to create metrics service: %v", err)
 	}
 
+	// Start suspending idle sessions
+	logger.Info("Initializing idle monitor")
+	idleMonitor, err := service.NewIdleMonitor(cfg, sessionService, metricsService)
+	if err != nil {
+		logger.Error("Failed to create idle monitor: %v", err)
+		log.Fatalf("Failed to create idle monitor: %v", err)
+	}
+	idleMonitor.Start(backgroundCtx)
+
 	// Initialize REST handlers
 	logger.Info("Initializing REST handlers")
-	restHandler := handler.NewRestHandler(sessionService)
+	restHandler := handler.NewRestHandler(sessionService, cfg.AllowedOrigins)
 	metricsHandler := handler.NewMetricsHandler(metricsService)
 
 	// Create router using Chi
@@ -65,11 +151,10 @@ func main() {
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.Timeout(60 * 1000))
 
 	// Add CORS middleware
 	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000", "http://127.0.0.1:3000"},
+		AllowedOrigins:   cfg.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
@@ -82,9 +167,16 @@ func main() {
 	apiRouter := chi.NewRouter()
 	router.Mount("/api/v1", apiRouter)
 
-	// Register routes on the API v1 subrouter
-	restHandler.RegisterRoutes(apiRouter)
-	metricsHandler.RegisterRoutes(apiRouter)
+	// Register routes on the API v1 subrouter. Regular requests time out; streaming
+	// requests run until the client disconnects.
+	apiRouter.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(60 * time.Second))
+		restHandler.RegisterRoutes(r)
+		metricsHandler.RegisterRoutes(r)
+	})
+	apiRouter.Group(func(r chi.Router) {
+		restHandler.RegisterStreamingRoutes(r)
+	})
 
 	// Add health check route
 	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -93,6 +185,26 @@ func main() {
 		w.Write([]byte(`{"status":"ok"}`))
 	})
 
+	// Serve session ports through the built-in reverse proxy on a port of its own. Proxied
+	// requests and WebSockets may run for long, so only headers and idle connections time out.
+	var proxySrv *http.Server
+	if cfg.ProxyPort > 0 {
+		proxyAddr := fmt.Sprintf(":%d", cfg.ProxyPort)
+		proxySrv = &http.Server{
+			Addr:              proxyAddr,
+			Handler:           handler.NewSessionProxy(sessionService, cfg.ProxyBaseDomain),
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		}
+		go func() {
+			logger.Info("Starting session proxy on %s", proxyAddr)
+			if err := proxySrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("Failed to start session proxy: %v", err)
+				log.Fatalf("Failed to start session proxy: %v", err)
+			}
+		}()
+	}
+
 	// Set up graceful shutdown
 	serverAddr := fmt.Sprintf(":%d", cfg.ServerPort)
 	srv := &http.Server{
@@ -116,13 +228,26 @@ func main() {
 	// Wait for signal
 	<-stop
 	logger.Info("Shutting down server...")
+	stopBackground()
 
-	// Clean up sessions
-	count, err := sessionService.DeleteAllSessions()
-	if err != nil {
-		logger.Error("Failed to delete all sessions: %v", err)
+	if proxySrv != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := proxySrv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("Failed to shut down session proxy: %v", err)
+		}
+		cancel()
+	}
+
+	// Clean up sessions unless they should survive the restart
+	if cfg.CleanupOnShutdown {
+		count, err := sessionService.DeleteAllSessions()
+		if err != nil {
+			logger.Error("Failed to delete all sessions: %v", err)
+		} else {
+			logger.Info("Deleted %d sessions", count)
+		}
 	} else {
-		logger.Info("Deleted %d sessions", count)
+		logger.Info("Keeping sessions for the next start")
 	}
 
 	logger.Info("Server shutdown complete")
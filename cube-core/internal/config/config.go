@@ -1,19 +1,292 @@
 package config
 
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Config represents the application configuration
 type Config struct {
 	ServerPort int
 	DockerHost string
 	MinPort    int
 	MaxPort    int
+
+	// AllowedOrigins are the browser origins allowed to call the API and open WebSockets
+	AllowedOrigins []string
+
+	// PublicHost is the host name or address session port URLs use. It defaults to the
+	// server's first non-loopback IPv4 address.
+	PublicHost string
+	// PortURLTemplate builds session port URLs from placeholders such as {host} and
+	// {host_port}, replacing both the direct and the reverse proxy URLs
+	PortURLTemplate string
+
+	// ProxyPort is where the built-in reverse proxy to session ports listens; zero disables it
+	// and session port URLs point at host ports instead
+	ProxyPort int
+	// ProxyBaseDomain routes <session-id>-<port>.<domain> to session ports; without it only
+	// /s/<session-id>/<port>/ paths are used in port URLs
+	ProxyBaseDomain string
+	// ProxyPublicURL is where clients reach the proxy, e.g. behind a load balancer. It
+	// defaults to the proxy port on the server's IP address.
+	ProxyPublicURL string
+	// ProxyTargetHost is the address the proxy and readiness checks reach session host ports at
+	ProxyTargetHost string
+
+	// SessionStorePath is the file used to persist sessions across restarts.
+	// An empty path keeps sessions in memory only.
+	SessionStorePath string
+	// CleanupOnShutdown deletes all sessions when the server shuts down
+	CleanupOnShutdown bool
+
+	// TemplateStorePath is the file used to persist session templates.
+	// An empty path keeps templates in memory only.
+	TemplateStorePath string
+	// TemplateDir holds YAML session templates loaded at startup
+	TemplateDir string
+
+	// DefaultSessionTTL is applied to sessions created without a TTL; zero means no expiry
+	DefaultSessionTTL time.Duration
+	// MaxSessionTTL caps how far in the future a session may expire; zero means no cap
+	MaxSessionTTL time.Duration
+	// ReaperInterval is how often expired sessions are looked for
+	ReaperInterval time.Duration
+	// ExpiredSessionRetention is how long expired sessions are still reported before being forgotten
+	ExpiredSessionRetention time.Duration
+
+	// IdleTimeout suspends sessions without activity for this long; zero disables idle detection
+	IdleTimeout time.Duration
+	// IdleAction is how idle sessions are suspended: "pause" or "stop"
+	IdleAction string
+	// IdleCheckInterval is how often session activity is sampled
+	IdleCheckInterval time.Duration
+	// IdleCPUThreshold is the CPU usage percent above which a session counts as active
+	IdleCPUThreshold float64
+	// IdleNetworkThreshold is the number of bytes received plus sent between two samples
+	// above which a session counts as active
+	IdleNetworkThreshold uint64
+
+	// HealthCheckInterval is how often container health is recorded and unhealthy containers recreated
+	HealthCheckInterval time.Duration
+
+	// Resource limits applied to sessions that do not request their own; zero means no limit
+	DefaultCPUs        float64
+	DefaultMemoryBytes int64
+	DefaultPidsLimit   int64
+	// Server-wide maximums session resource requests are validated against; zero means no maximum.
+	// A session without an explicit or default limit gets the maximum.
+	MaxCPUs        float64
+	MaxMemoryBytes int64
+	MaxPidsLimit   int64
+
+	// AllowedBindPaths lists the host directories (and their subdirectories) sessions may bind-mount
+	AllowedBindPaths []string
+
+	// SessionNetworks gives every session a private Docker network instead of the default bridge
+	SessionNetworks bool
+	// EgressProxyImage is the image of the proxy started next to sessions with an egress policy
+	EgressProxyImage string
+	// EgressNetwork is the shared network egress proxies reach the outside world through
+	EgressNetwork string
+
+	// PullMissingImages pulls a session's image when it is not present locally
+	PullMissingImages bool
+
+	// MaxUploadBytes and MaxDownloadBytes limit file transfers into and out of session
+	// containers; zero means no limit
+	MaxUploadBytes   int64
+	MaxDownloadBytes int64
+
+	// SnapshotRepository is the image repository session snapshots are committed to
+	SnapshotRepository string
+	// MaxSnapshots is how many snapshots the server keeps at most; zero means no limit
+	MaxSnapshots int
+
+	// ProvisionWorkers is the number of sessions whose containers are created concurrently
+	ProvisionWorkers int
+	// ProvisionQueueSize is how many sessions may wait for a provisioning worker
+	ProvisionQueueSize int
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		ServerPort: 8080,
-		DockerHost: "",
-		MinPort:    0,
-		MaxPort:    0,
+		ServerPort:        8080,
+		DockerHost:        "",
+		MinPort:           0,
+		MaxPort:           0,
+		AllowedOrigins:    []string{"http://localhost:3000", "http://127.0.0.1:3000"},
+		PublicHost:        "",
+		PortURLTemplate:   "",
+		ProxyPort:         0,
+		ProxyBaseDomain:   "",
+		ProxyPublicURL:    "",
+		ProxyTargetHost:   "127.0.0.1",
+		SessionStorePath:  "data/sessions.json",
+		CleanupOnShutdown: false,
+		TemplateStorePath: "data/templates.json",
+		TemplateDir:       "templates",
+
+		DefaultSessionTTL:       0,
+		MaxSessionTTL:           0,
+		ReaperInterval:          30 * time.Second,
+		ExpiredSessionRetention: time.Hour,
+
+		IdleTimeout:          0,
+		IdleAction:           "pause",
+		IdleCheckInterval:    time.Minute,
+		IdleCPUThreshold:     1.0,
+		IdleNetworkThreshold: 1024,
+
+		HealthCheckInterval: 15 * time.Second,
+
+		DefaultCPUs:        0,
+		DefaultMemoryBytes: 0,
+		DefaultPidsLimit:   0,
+		MaxCPUs:            0,
+		MaxMemoryBytes:     0,
+		MaxPidsLimit:       0,
+
+		AllowedBindPaths: nil,
+
+		SessionNetworks:  true,
+		EgressProxyImage: "cube-egress-proxy:latest",
+		EgressNetwork:    "cube-egress",
+
+		PullMissingImages: true,
+
+		MaxUploadBytes:   100 << 20,
+		MaxDownloadBytes: 1 << 30,
+
+		SnapshotRepository: "cube-snapshots",
+		MaxSnapshots:       20,
+
+		ProvisionWorkers:   4,
+		ProvisionQueueSize: 64,
+	}
+}
+
+// LoadConfig returns the default configuration overridden by CUBE_* environment variables
+func LoadConfig() *Config {
+	cfg := DefaultConfig()
+
+	cfg.ServerPort = envInt("CUBE_SERVER_PORT", cfg.ServerPort)
+	cfg.DockerHost = envString("CUBE_DOCKER_HOST", cfg.DockerHost)
+	cfg.AllowedOrigins = envList("CUBE_ALLOWED_ORIGINS", cfg.AllowedOrigins)
+	cfg.PublicHost = envString("CUBE_PUBLIC_HOST", cfg.PublicHost)
+	cfg.PortURLTemplate = envString("CUBE_PORT_URL_TEMPLATE", cfg.PortURLTemplate)
+	cfg.ProxyPort = envInt("CUBE_PROXY_PORT", cfg.ProxyPort)
+	cfg.ProxyBaseDomain = strings.TrimPrefix(envString("CUBE_PROXY_BASE_DOMAIN", cfg.ProxyBaseDomain), ".")
+	cfg.ProxyPublicURL = envString("CUBE_PROXY_PUBLIC_URL", cfg.ProxyPublicURL)
+	cfg.ProxyTargetHost = envString("CUBE_PROXY_TARGET_HOST", cfg.ProxyTargetHost)
+	cfg.SessionStorePath = envString("CUBE_SESSION_STORE_PATH", cfg.SessionStorePath)
+	cfg.CleanupOnShutdown = envBool("CUBE_CLEANUP_ON_SHUTDOWN", cfg.CleanupOnShutdown)
+	cfg.TemplateStorePath = envString("CUBE_TEMPLATE_STORE_PATH", cfg.TemplateStorePath)
+	cfg.TemplateDir = envString("CUBE_TEMPLATE_DIR", cfg.TemplateDir)
+
+	cfg.DefaultSessionTTL = envDuration("CUBE_DEFAULT_SESSION_TTL", cfg.DefaultSessionTTL)
+	cfg.MaxSessionTTL = envDuration("CUBE_MAX_SESSION_TTL", cfg.MaxSessionTTL)
+	cfg.ReaperInterval = envDuration("CUBE_REAPER_INTERVAL", cfg.ReaperInterval)
+	cfg.ExpiredSessionRetention = envDuration("CUBE_EXPIRED_SESSION_RETENTION", cfg.ExpiredSessionRetention)
+
+	cfg.IdleTimeout = envDuration("CUBE_IDLE_TIMEOUT", cfg.IdleTimeout)
+	cfg.IdleAction = envString("CUBE_IDLE_ACTION", cfg.IdleAction)
+	cfg.IdleCheckInterval = envDuration("CUBE_IDLE_CHECK_INTERVAL", cfg.IdleCheckInterval)
+	cfg.IdleCPUThreshold = envFloat("CUBE_IDLE_CPU_THRESHOLD", cfg.IdleCPUThreshold)
+	cfg.IdleNetworkThreshold = uint64(envInt("CUBE_IDLE_NETWORK_THRESHOLD", int(cfg.IdleNetworkThreshold)))
+
+	cfg.HealthCheckInterval = envDuration("CUBE_HEALTH_CHECK_INTERVAL", cfg.HealthCheckInterval)
+
+	cfg.DefaultCPUs = envFloat("CUBE_DEFAULT_CPUS", cfg.DefaultCPUs)
+	cfg.DefaultMemoryBytes = int64(envInt("CUBE_DEFAULT_MEMORY_BYTES", int(cfg.DefaultMemoryBytes)))
+	cfg.DefaultPidsLimit = int64(envInt("CUBE_DEFAULT_PIDS_LIMIT", int(cfg.DefaultPidsLimit)))
+	cfg.MaxCPUs = envFloat("CUBE_MAX_CPUS", cfg.MaxCPUs)
+	cfg.MaxMemoryBytes = int64(envInt("CUBE_MAX_MEMORY_BYTES", int(cfg.MaxMemoryBytes)))
+	cfg.MaxPidsLimit = int64(envInt("CUBE_MAX_PIDS_LIMIT", int(cfg.MaxPidsLimit)))
+
+	cfg.AllowedBindPaths = envList("CUBE_ALLOWED_BIND_PATHS", cfg.AllowedBindPaths)
+
+	cfg.SessionNetworks = envBool("CUBE_SESSION_NETWORKS", cfg.SessionNetworks)
+	cfg.EgressProxyImage = envString("CUBE_EGRESS_PROXY_IMAGE", cfg.EgressProxyImage)
+	cfg.EgressNetwork = envString("CUBE_EGRESS_NETWORK", cfg.EgressNetwork)
+
+	cfg.PullMissingImages = envBool("CUBE_PULL_MISSING_IMAGES", cfg.PullMissingImages)
+
+	cfg.MaxUploadBytes = int64(envInt("CUBE_MAX_UPLOAD_BYTES", int(cfg.MaxUploadBytes)))
+	cfg.MaxDownloadBytes = int64(envInt("CUBE_MAX_DOWNLOAD_BYTES", int(cfg.MaxDownloadBytes)))
+
+	cfg.SnapshotRepository = envString("CUBE_SNAPSHOT_REPOSITORY", cfg.SnapshotRepository)
+	cfg.MaxSnapshots = envInt("CUBE_MAX_SNAPSHOTS", cfg.MaxSnapshots)
+
+	cfg.ProvisionWorkers = envInt("CUBE_PROVISION_WORKERS", cfg.ProvisionWorkers)
+	cfg.ProvisionQueueSize = envInt("CUBE_PROVISION_QUEUE_SIZE", cfg.ProvisionQueueSize)
+
+	return cfg
+}
+
+// envString returns the value of an environment variable or a fallback if it is unset
+func envString(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// envList returns the comma-separated values of an environment variable or a fallback if it is unset
+func envList(key string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// envInt returns the integer value of an environment variable or a fallback if it is unset or invalid
+func envInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// envFloat returns the float value of an environment variable or a fallback if it is unset or invalid
+func envFloat(key string, fallback float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// envBool returns the boolean value of an environment variable or a fallback if it is unset or invalid
+func envBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// envDuration returns the duration value (e.g. "90s", "2h") of an environment variable or a fallback if it is unset or invalid
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
 	}
+	return fallback
 }
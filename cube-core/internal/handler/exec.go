@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+// defaultTerminalCommand is run when a terminal request does not name a command
+var defaultTerminalCommand = []string{"/bin/sh"}
+
+// ExecCommand handles POST /api/v1/sessions/{id}/exec
+func (h *RestHandler) ExecCommand(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling ExecCommand request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	var req model.ExecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request body: %v", err)
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	result, err := h.sessionService.ExecCommand(r.Context(), id, &req)
+	if err != nil {
+		h.logger.Error("Failed to run command: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Terminal handles GET /api/v1/sessions/{id}/exec, upgrading to a WebSocket attached to a
+// TTY running ?cmd (repeatable, default /bin/sh) with the optional ?user, ?working_dir, ?rows
+// and ?cols. Terminal output is sent as binary messages. Binary messages from the client are
+// terminal input; text messages are model.TerminalMessage control messages ("stdin",
+// "resize"). An "exit" message with the exit code is sent before the socket is closed, and
+// closing the socket hangs up the terminal.
+func (h *RestHandler) Terminal(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling Terminal request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	query := r.URL.Query()
+	req := model.ExecRequest{
+		Command:    query["cmd"],
+		User:       query.Get("user"),
+		WorkingDir: query.Get("working_dir"),
+	}
+	if len(req.Command) == 0 {
+		req.Command = defaultTerminalCommand
+	}
+	rows, _ := strconv.ParseUint(query.Get("rows"), 10, 16)
+	cols, _ := strconv.ParseUint(query.Get("cols"), 10, 16)
+
+	// Start the command before upgrading so failures get a proper status code
+	exec, err := h.sessionService.StartTerminal(r.Context(), id, &req, uint(rows), uint(cols))
+	if err != nil {
+		h.logger.Error("Failed to open terminal: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+	defer exec.Close()
+
+	upgrader := websocket.Upgrader{CheckOrigin: h.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		h.logger.Error("Failed to upgrade terminal connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	h.logger.Info("Opened terminal in session %s", id)
+
+	// Client to terminal. When the client goes away the terminal is hung up, which also ends
+	// the output loop below.
+	go func() {
+		defer exec.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if messageType == websocket.BinaryMessage {
+				if _, err := exec.Write(data); err != nil {
+					return
+				}
+				continue
+			}
+
+			var msg model.TerminalMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				h.logger.Debug("Ignoring invalid terminal message: %v", err)
+				continue
+			}
+			switch msg.Type {
+			case model.TerminalMessageStdin:
+				if _, err := exec.Write([]byte(msg.Data)); err != nil {
+					return
+				}
+			case model.TerminalMessageResize:
+				if msg.Rows > 0 && msg.Cols > 0 {
+					if err := exec.Resize(msg.Rows, msg.Cols); err != nil {
+						h.logger.Warn("Failed to resize terminal in session %s: %v", id, err)
+					}
+				}
+			}
+		}
+	}()
+
+	// Terminal to client; only this goroutine writes to the socket
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := exec.Read(buf)
+		if n > 0 {
+			if writeErr := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); writeErr != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	exit := model.TerminalMessage{Type: model.TerminalMessageExit}
+	if code, ok := exec.ExitCode(); ok {
+		exit.ExitCode = &code
+	}
+	conn.WriteJSON(exit)
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+	h.logger.Info("Closed terminal in session %s", id)
+}
+
+// checkOrigin allows WebSockets from the configured browser origins and from clients that
+// do not send an origin at all
+func (h *RestHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
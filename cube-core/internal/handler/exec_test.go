@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/internal/service"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{name: "no origin", want: true},
+		{name: "no origin with allowlist", allowed: []string{"https://cube.example.com"}, want: true},
+		{name: "allowed", allowed: []string{"https://cube.example.com"}, origin: "https://cube.example.com", want: true},
+		{name: "case insensitive", allowed: []string{"https://Cube.example.com"}, origin: "https://cube.example.com", want: true},
+		{name: "wildcard", allowed: []string{"*"}, origin: "https://anywhere.example.com", want: true},
+		{name: "not allowed", allowed: []string{"https://cube.example.com"}, origin: "https://evil.example.com", want: false},
+		{name: "browser without allowlist", origin: "https://cube.example.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewRestHandler(nil, tt.allowed)
+			r := httptest.NewRequest(http.MethodGet, "/sessions/s1/exec", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := h.checkOrigin(r); got != tt.want {
+				t.Fatalf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecRoutes(t *testing.T) {
+	fake, sessionService, router := newSessionTestRouter(t)
+	fake.AddImage("nginx:latest")
+	stopped, err := sessionService.CreateSession(&model.CreateSessionRequest{ImageName: "nginx:latest"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, err := sessionService.WaitForSession(t.Context(), stopped.ID, func(s *model.Session) bool {
+		return s.Status != model.SessionStatusProvisioning
+	}); err != nil {
+		t.Fatalf("WaitForSession: %v", err)
+	}
+	if _, err := sessionService.ApplySessionOperation(stopped.ID, service.OperationStop); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{name: "invalid body", method: http.MethodPost, path: "/sessions/" + stopped.ID + "/exec", body: "{", wantCode: http.StatusBadRequest},
+		{name: "unknown session", method: http.MethodPost, path: "/sessions/missing/exec", body: `{"command": ["true"]}`, wantCode: http.StatusNotFound},
+		{name: "stopped session", method: http.MethodPost, path: "/sessions/" + stopped.ID + "/exec", body: `{"command": ["true"]}`, wantCode: http.StatusConflict},
+		{name: "terminal in unknown session", method: http.MethodGet, path: "/sessions/missing/exec", wantCode: http.StatusNotFound},
+		{name: "terminal in stopped session", method: http.MethodGet, path: "/sessions/" + stopped.ID + "/exec?cmd=bash", wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// UploadFiles handles PUT /api/v1/sessions/{id}/files?path=
+func (h *RestHandler) UploadFiles(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling UploadFiles request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	query := r.URL.Query()
+	archive, err := parseArchiveFlag(query.Get("archive"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "application/x-tar" {
+		archive = true
+	}
+
+	if err := h.sessionService.UploadFiles(r.Context(), id, query.Get("path"), r.Body, archive); err != nil {
+		h.logger.Error("Failed to upload files: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DownloadFiles handles GET /api/v1/sessions/{id}/files?path=
+func (h *RestHandler) DownloadFiles(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling DownloadFiles request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	query := r.URL.Query()
+	archive, err := parseArchiveFlag(query.Get("archive"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	download, err := h.sessionService.DownloadFiles(r.Context(), id, query.Get("path"), archive)
+	if err != nil {
+		h.logger.Error("Failed to download files: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+	defer download.Body.Close()
+
+	if download.Archive {
+		w.Header().Set("Content-Type", "application/x-tar")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(download.Size, 10))
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": download.Name}))
+	w.WriteHeader(http.StatusOK)
+
+	// Headers are sent, so a failure part way can only cut the body short
+	if _, err := io.Copy(w, download.Body); err != nil && r.Context().Err() == nil {
+		h.logger.Error("Failed to stream files: %v", err)
+	}
+}
+
+// parseArchiveFlag parses the optional archive query parameter
+func parseArchiveFlag(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	archive, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("invalid archive value %q", value)
+	}
+	return archive, nil
+}
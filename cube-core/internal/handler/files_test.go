@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"archive/tar"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+func TestParseArchiveFlag(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{value: "", want: false},
+		{value: "true", want: true},
+		{value: "1", want: true},
+		{value: "false", want: false},
+		{value: " true ", want: true},
+		{value: "tar", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseArchiveFlag(tt.value)
+			if tt.wantErr != (err != nil) || got != tt.want {
+				t.Fatalf("parseArchiveFlag(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileRoutes(t *testing.T) {
+	fake, sessionService, router := newSessionTestRouter(t)
+	fake.AddImage("node:20")
+	session, err := sessionService.CreateSession(&model.CreateSessionRequest{ImageName: "node:20"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	session, err = sessionService.WaitForSession(t.Context(), session.ID, func(s *model.Session) bool {
+		return s.ContainerID != ""
+	})
+	if err != nil {
+		t.Fatalf("WaitForSession: %v", err)
+	}
+	fake.SetContainerFile(session.ContainerID, "/app/report.txt", "all tests passed")
+
+	var fixtures bytes.Buffer
+	tw := tar.NewWriter(&fixtures)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "users.json", Mode: 0644, Size: 2})
+	tw.Write([]byte("[]"))
+	tw.Close()
+
+	files := "/sessions/" + session.ID + "/files"
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantCode    int
+		wantHeaders map[string]string
+		wantBody    string
+		// wantFile is a file expected in the container afterwards, holding wantContent
+		wantFile    string
+		wantContent string
+	}{
+		{
+			name:        "download file",
+			method:      http.MethodGet,
+			path:        files + "?path=/app/report.txt",
+			wantCode:    http.StatusOK,
+			wantHeaders: map[string]string{"Content-Type": "application/octet-stream", "Content-Length": "16", "Content-Disposition": "attachment; filename=report.txt"},
+			wantBody:    "all tests passed",
+		},
+		{
+			name:        "download directory",
+			method:      http.MethodGet,
+			path:        files + "?path=/app",
+			wantCode:    http.StatusOK,
+			wantHeaders: map[string]string{"Content-Type": "application/x-tar", "Content-Disposition": "attachment; filename=app.tar"},
+		},
+		{name: "download missing file", method: http.MethodGet, path: files + "?path=/app/missing", wantCode: http.StatusNotFound},
+		{name: "download without path", method: http.MethodGet, path: files, wantCode: http.StatusBadRequest},
+		{name: "invalid archive flag", method: http.MethodGet, path: files + "?path=/app&archive=zip", wantCode: http.StatusBadRequest},
+		{
+			name:        "upload file",
+			method:      http.MethodPut,
+			path:        files + "?path=/app/seed.sql",
+			body:        "insert",
+			wantCode:    http.StatusNoContent,
+			wantFile:    "/app/seed.sql",
+			wantContent: "insert",
+		},
+		{
+			name:        "upload tar by content type",
+			method:      http.MethodPut,
+			path:        files + "?path=/app",
+			contentType: "application/x-tar",
+			body:        fixtures.String(),
+			wantCode:    http.StatusNoContent,
+			wantFile:    "/app/users.json",
+			wantContent: "[]",
+		},
+		{name: "upload over a directory", method: http.MethodPut, path: files + "?path=/app", body: "x", wantCode: http.StatusBadRequest},
+		{name: "upload to unknown session", method: http.MethodPut, path: "/sessions/missing/files?path=/app/seed.sql", body: "x", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			for key, want := range tt.wantHeaders {
+				if got := rec.Header().Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantFile == "" {
+				return
+			}
+			if content, exists := fake.ContainerFile(session.ContainerID, tt.wantFile); !exists || content != tt.wantContent {
+				t.Fatalf("%s holds %q (exists %v), want %q", tt.wantFile, content, exists, tt.wantContent)
+			}
+		})
+	}
+}
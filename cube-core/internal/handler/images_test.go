@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/service"
+	"github.com/yourusername/session-manager/pkg/docker/dockertest"
+	"github.com/yourusername/session-manager/pkg/port"
+)
+
+func newImageTestRouter(t *testing.T) (*dockertest.FakeAPI, http.Handler) {
+	t.Helper()
+
+	fake, dockerManager := dockertest.NewFakeAPI(t)
+	cfg := config.DefaultConfig()
+	cfg.SessionStorePath = ""
+	sessionService := service.NewSessionService(cfg, dockerManager, port.NewPortManager(), nil, nil)
+
+	r := chi.NewRouter()
+	NewRestHandler(sessionService, nil).RegisterStreamingRoutes(r)
+	return fake, r
+}
+
+// sseEvents reads the event names of a server-sent event stream
+func sseEvents(t *testing.T, body string) []string {
+	t.Helper()
+
+	var events []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, name)
+		}
+	}
+	return events
+}
+
+func TestPullImageStreamsProgress(t *testing.T) {
+	fake, router := newImageTestRouter(t)
+	fake.SetPullMessages("alpine:3.19",
+		`{"status":"Pulling from library/alpine","id":"3.19"}`,
+		`{"status":"Downloading","id":"abc","progressDetail":{"current":512,"total":1024}}`,
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/images/pull", strings.NewReader(`{"image":"alpine:3.19"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	events := sseEvents(t, rec.Body.String())
+	want := []string{"progress", "progress", "complete"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	if !strings.Contains(rec.Body.String(), `"current":512`) {
+		t.Fatalf("progress events do not carry layer progress: %s", rec.Body.String())
+	}
+}
+
+func TestPullImageStreamsError(t *testing.T) {
+	fake, router := newImageTestRouter(t)
+	fake.SetPullMessages("private/app:1", `{"errorDetail":{"message":"denied"},"error":"denied"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/images/pull", strings.NewReader(`{"image":"private/app:1"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	events := sseEvents(t, rec.Body.String())
+	if len(events) == 0 || events[len(events)-1] != "error" {
+		t.Fatalf("events = %v, want the stream to end with an error", events)
+	}
+	if !strings.Contains(rec.Body.String(), "denied") {
+		t.Fatalf("error event does not carry the pull error: %s", rec.Body.String())
+	}
+}
+
+func TestPullImageRequiresImage(t *testing.T) {
+	_, router := newImageTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/images/pull", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+)
+
+// SessionLogs handles GET /api/v1/sessions/{id}/logs
+func (h *RestHandler) SessionLogs(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling SessionLogs request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	h.streamLogs(w, r, func(ctx context.Context, opts model.LogOptions) (*docker.LogStream, error) {
+		return h.sessionService.SessionLogs(ctx, id, opts)
+	})
+}
+
+// ContainerLogs handles GET /api/v1/containers/{id}/logs
+func (h *RestHandler) ContainerLogs(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling ContainerLogs request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "container ID is required")
+		return
+	}
+
+	h.streamLogs(w, r, func(ctx context.Context, opts model.LogOptions) (*docker.LogStream, error) {
+		return h.sessionService.ContainerLogs(ctx, id, opts)
+	})
+}
+
+// streamLogs opens a log stream and copies it to the client, as chunked plain text or, if the
+// client asks for text/event-stream or ?format=sse, as one "log" event per line. The stream
+// is tied to the request context, so a client disconnect cancels the Docker request.
+func (h *RestHandler) streamLogs(w http.ResponseWriter, r *http.Request, open func(context.Context, model.LogOptions) (*docker.LogStream, error)) {
+	opts, err := parseLogOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stream, err := open(r.Context(), opts)
+	if err != nil {
+		h.logger.Error("Failed to open logs: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+	defer stream.Close()
+
+	if r.URL.Query().Get("format") == "sse" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		sse, ok := newSSEWriter(w)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, "streaming is not supported")
+			return
+		}
+
+		stdout := &sseLogWriter{sse: sse, stream: "stdout"}
+		stderr := &sseLogWriter{sse: sse, stream: "stderr"}
+		if err := stream.Copy(stdout, stderr); err != nil {
+			if r.Context().Err() == nil {
+				h.logger.Error("Failed to stream logs: %v", err)
+				sse.writeEvent("error", map[string]string{"error": err.Error()})
+			}
+			return
+		}
+		stdout.flush()
+		stderr.flush()
+		sse.writeEvent("end", map[string]string{})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	out := &flushWriter{w: w, flusher: flusher}
+	if err := stream.Copy(out, out); err != nil && r.Context().Err() == nil {
+		// Headers are already sent, so the error can only be logged
+		h.logger.Error("Failed to stream logs: %v", err)
+	}
+}
+
+// parseLogOptions reads the follow, since, tail, timestamps, stdout and stderr query parameters
+func parseLogOptions(r *http.Request) (model.LogOptions, error) {
+	query := r.URL.Query()
+	opts := model.LogOptions{
+		Since:  query.Get("since"),
+		Tail:   query.Get("tail"),
+		Stdout: true,
+		Stderr: true,
+	}
+
+	flags := map[string]*bool{
+		"follow":     &opts.Follow,
+		"timestamps": &opts.Timestamps,
+		"stdout":     &opts.Stdout,
+		"stderr":     &opts.Stderr,
+	}
+	for name, target := range flags {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s value %q", name, raw)
+		}
+		*target = value
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/internal/service"
+)
+
+func TestParseLogOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    model.LogOptions
+		wantErr bool
+	}{
+		{query: "", want: model.LogOptions{Stdout: true, Stderr: true}},
+		{query: "?follow=true&timestamps=1", want: model.LogOptions{Follow: true, Timestamps: true, Stdout: true, Stderr: true}},
+		{query: "?stdout=false", want: model.LogOptions{Stderr: true}},
+		{query: "?stderr=0&tail=50&since=10m", want: model.LogOptions{Stdout: true, Tail: "50", Since: "10m"}},
+		{query: "?follow=yes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			opts, err := parseLogOptions(httptest.NewRequest(http.MethodGet, "/sessions/s1/logs"+tt.query, nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseLogOptions = %+v, want an error", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLogOptions: %v", err)
+			}
+			if !reflect.DeepEqual(opts, tt.want) {
+				t.Fatalf("parseLogOptions = %+v, want %+v", opts, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionLogsRoute(t *testing.T) {
+	fake, sessionService, router := newSessionTestRouter(t)
+	fake.AddImage("nginx:latest")
+	session, err := sessionService.CreateSession(&model.CreateSessionRequest{ImageName: "nginx:latest"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	session, err = sessionService.WaitForSession(ctx, session.ID, service.IsSessionSettled)
+	if err != nil {
+		t.Fatalf("WaitForSession: %v", err)
+	}
+	fake.SetContainerLogs(session.ContainerID, "out line\n", "err line\n")
+
+	tests := []struct {
+		name       string
+		path       string
+		accept     string
+		wantCode   int
+		wantBody   []string
+		wantEvents []string
+	}{
+		{name: "plain text", path: "/sessions/" + session.ID + "/logs", wantCode: http.StatusOK, wantBody: []string{"out line", "err line"}},
+		{name: "stderr only", path: "/sessions/" + session.ID + "/logs?stdout=false", wantCode: http.StatusOK, wantBody: []string{"err line"}},
+		{name: "container logs", path: "/containers/" + session.ContainerID + "/logs", wantCode: http.StatusOK, wantBody: []string{"out line"}},
+		{name: "sse query", path: "/sessions/" + session.ID + "/logs?format=sse", wantCode: http.StatusOK, wantEvents: []string{"log", "log", "end"}},
+		{name: "sse accept", path: "/sessions/" + session.ID + "/logs", accept: "text/event-stream", wantCode: http.StatusOK, wantEvents: []string{"log", "log", "end"}},
+		{name: "invalid option", path: "/sessions/" + session.ID + "/logs?follow=maybe", wantCode: http.StatusBadRequest},
+		{name: "invalid tail", path: "/sessions/" + session.ID + "/logs?tail=-1", wantCode: http.StatusBadRequest},
+		{name: "unknown session", path: "/sessions/missing/logs", wantCode: http.StatusNotFound},
+		{name: "unknown container", path: "/containers/missing/logs", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("body %q does not contain %q", rec.Body.String(), want)
+				}
+			}
+			if tt.wantEvents != nil {
+				if events := sseEvents(t, rec.Body.String()); !reflect.DeepEqual(events, tt.wantEvents) {
+					t.Errorf("events = %v, want %v", events, tt.wantEvents)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/service"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// sessionIDLength is the length of the UUIDs sessions are identified by
+const sessionIDLength = 36
+
+// SessionProxy is a reverse proxy to the published ports of sessions. It routes requests for
+// <session-id>-<port>.<base-domain>, and for paths under /s/<session-id>/<port>/, where port is
+// the port's slug or its container port number. WebSocket upgrades are passed through.
+type SessionProxy struct {
+	sessionService *service.SessionService
+	baseDomain     string
+	transport      *http.Transport
+	logger         *util.Logger
+}
+
+// NewSessionProxy creates a reverse proxy to session ports
+func NewSessionProxy(sessionService *service.SessionService, baseDomain string) *SessionProxy {
+	return &SessionProxy{
+		sessionService: sessionService,
+		baseDomain:     strings.ToLower(baseDomain),
+		transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+			MaxIdleConnsPerHost: 16,
+			IdleConnTimeout:     90 * time.Second,
+			// HTTPS ports of sessions serve whatever certificate their image comes with
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		logger: util.NewLogger(),
+	}
+}
+
+// ServeHTTP forwards a request to the session port it is routed to
+func (p *SessionProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sessionID, portKey, prefix, ok := p.route(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no session route for this request")
+		return
+	}
+
+	target, err := p.sessionService.ResolveProxyTarget(sessionID, portKey)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	// Relative links only resolve below the prefix if it ends in a slash
+	if prefix != "" && r.URL.Path == prefix {
+		location := prefix + "/"
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, location, http.StatusPermanentRedirect)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if prefix != "" {
+				pr.Out.URL.Path = strings.TrimPrefix(pr.In.URL.Path, prefix)
+				pr.Out.URL.RawPath = ""
+				pr.Out.Header.Set("X-Forwarded-Prefix", prefix)
+			}
+			pr.SetURL(target)
+			pr.SetXForwarded()
+		},
+		Transport: p.transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			p.logger.Warn("Failed to proxy to session %s port %s: %v", sessionID, portKey, err)
+			writeError(w, http.StatusBadGateway, "session port is not answering")
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// route works out the session and port a request is for, and the path prefix to strip
+func (p *SessionProxy) route(r *http.Request) (sessionID, portKey, prefix string, ok bool) {
+	if p.baseDomain != "" {
+		host := strings.ToLower(r.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if label, found := strings.CutSuffix(host, "."+p.baseDomain); found && !strings.Contains(label, ".") {
+			if len(label) > sessionIDLength+1 && label[sessionIDLength] == '-' {
+				return label[:sessionIDLength], label[sessionIDLength+1:], "", true
+			}
+		}
+	}
+
+	rest, found := strings.CutPrefix(r.URL.Path, "/s/")
+	if !found {
+		return "", "", "", false
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], "/s/" + parts[0] + "/" + parts[1], true
+}
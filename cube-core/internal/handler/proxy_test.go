@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/internal/service"
+)
+
+func TestSessionProxyRoute(t *testing.T) {
+	const sessionID = "0b7ff3b4-5f6a-4a53-9d3c-4d6b6c0e1a2f"
+	tests := []struct {
+		name       string
+		baseDomain string
+		host       string
+		path       string
+		wantOK     bool
+		wantPort   string
+		wantPrefix string
+	}{
+		{name: "path", path: "/s/" + sessionID + "/web/index.html", wantOK: true, wantPort: "web", wantPrefix: "/s/" + sessionID + "/web"},
+		{name: "path without slash", path: "/s/" + sessionID + "/80", wantOK: true, wantPort: "80", wantPrefix: "/s/" + sessionID + "/80"},
+		{name: "host", baseDomain: "apps.example.com", host: sessionID + "-web.apps.example.com", path: "/index.html", wantOK: true, wantPort: "web"},
+		{name: "host with port", baseDomain: "apps.example.com", host: sessionID + "-8080.apps.example.com:8081", path: "/", wantOK: true, wantPort: "8080"},
+		{name: "host in upper case", baseDomain: "Apps.Example.com", host: sessionID + "-web.APPS.example.com", path: "/", wantOK: true, wantPort: "web"},
+		{
+			name:       "path on the base domain",
+			baseDomain: "apps.example.com",
+			host:       "apps.example.com",
+			path:       "/s/" + sessionID + "/web/",
+			wantOK:     true,
+			wantPort:   "web",
+			wantPrefix: "/s/" + sessionID + "/web",
+		},
+		{name: "host without port", baseDomain: "apps.example.com", host: sessionID + ".apps.example.com", path: "/"},
+		{name: "nested host", baseDomain: "apps.example.com", host: "x." + sessionID + "-web.apps.example.com", path: "/"},
+		{name: "host without base domain", host: sessionID + "-web.apps.example.com", path: "/"},
+		{name: "root", path: "/"},
+		{name: "no port", path: "/s/" + sessionID + "/"},
+		{name: "no session", path: "/s//web/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSessionProxy(nil, tt.baseDomain)
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.host != "" {
+				r.Host = tt.host
+			}
+
+			gotSession, gotPort, gotPrefix, ok := p.route(r)
+			if ok != tt.wantOK {
+				t.Fatalf("route = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if gotSession != sessionID || gotPort != tt.wantPort || gotPrefix != tt.wantPrefix {
+				t.Fatalf("route = %s, %s, %s; want %s, %s, %s", gotSession, gotPort, gotPrefix, sessionID, tt.wantPort, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestSessionProxy(t *testing.T) {
+	fake, sessionService, _ := newSessionTestRouter(t)
+	fake.AddImage("nginx:latest")
+
+	createSession := func() *model.Session {
+		session, err := sessionService.CreateSession(&model.CreateSessionRequest{
+			ImageName:    "nginx:latest",
+			PortMappings: []model.PortMapping{{ContainerPort: 80, Description: "web"}},
+		})
+		if err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		session, err = sessionService.WaitForSession(t.Context(), session.ID, service.IsSessionSettled)
+		if err != nil {
+			t.Fatalf("WaitForSession: %v", err)
+		}
+		return session
+	}
+	running := createSession()
+	silent := createSession()
+	stopped := createSession()
+	if _, err := sessionService.ApplySessionOperation(stopped.ID, service.OperationStop); err != nil {
+		t.Fatalf("stop session: %v", err)
+	}
+
+	// The fake containers publish nothing, so serve the running session's host port here
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(running.Ports[0].HostPort)))
+	if err != nil {
+		t.Fatalf("listen on the session port: %v", err)
+	}
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.URL.RequestURI(), r.Header.Get("X-Forwarded-Prefix"))
+	}))
+	backend.Listener.Close()
+	backend.Listener = listener
+	backend.Start()
+	defer backend.Close()
+
+	proxy := NewSessionProxy(sessionService, "apps.example.com")
+	prefix := "/s/" + running.ID + "/web"
+
+	tests := []struct {
+		name         string
+		host         string
+		path         string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{name: "path", path: prefix + "/index.html?q=1", wantCode: http.StatusOK, wantBody: "/index.html?q=1 " + prefix},
+		{name: "container port", path: "/s/" + running.ID + "/80/", wantCode: http.StatusOK, wantBody: "/ /s/" + running.ID + "/80"},
+		{name: "host", host: running.ID + "-web.apps.example.com", path: "/index.html", wantCode: http.StatusOK, wantBody: "/index.html "},
+		{name: "prefix without slash", path: prefix + "?q=1", wantCode: http.StatusPermanentRedirect, wantLocation: prefix + "/?q=1"},
+		{name: "port not answering", path: "/s/" + silent.ID + "/web/", wantCode: http.StatusBadGateway},
+		{name: "stopped session", path: "/s/" + stopped.ID + "/web/", wantCode: http.StatusServiceUnavailable},
+		{name: "unknown port", path: "/s/" + running.ID + "/admin/", wantCode: http.StatusNotFound},
+		{name: "unknown session", path: "/s/missing/web/", wantCode: http.StatusNotFound},
+		{name: "no route", path: "/", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.host != "" {
+				r.Host = tt.host
+			}
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Fatalf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if location := rec.Header().Get("Location"); location != tt.wantLocation {
+				t.Fatalf("location = %q, want %q", location, tt.wantLocation)
+			}
+		})
+	}
+}
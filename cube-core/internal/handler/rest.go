@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/yourusername/session-manager/internal/model"
@@ -13,13 +17,16 @@ import (
 // RestHandler handles HTTP requests for the session manager
 type RestHandler struct {
 	sessionService *service.SessionService
+	// allowedOrigins are the browser origins allowed to open WebSockets
+	allowedOrigins []string
 	logger         *util.Logger
 }
 
 // NewRestHandler creates a new REST handler
-func NewRestHandler(sessionService *service.SessionService) *RestHandler {
+func NewRestHandler(sessionService *service.SessionService, allowedOrigins []string) *RestHandler {
 	return &RestHandler{
 		sessionService: sessionService,
+		allowedOrigins: allowedOrigins,
 		logger:         util.NewLogger(),
 	}
 }
@@ -31,9 +38,32 @@ func (h *RestHandler) RegisterRoutes(r chi.Router) {
 	// Sessions
 	r.Get("/sessions", h.ListSessions)
 	r.Post("/sessions", h.CreateSession)
+	r.Get("/sessions/{id}", h.GetSession)
 	r.Delete("/sessions/{id}", h.DeleteSession)
+	r.Post("/sessions/{id}/extend", h.ExtendSession)
+	r.Post("/sessions/{id}/resume", h.ResumeSession)
+	r.Post("/sessions/{id}/stop", h.SessionOperation(service.OperationStop))
+	r.Post("/sessions/{id}/start", h.SessionOperation(service.OperationStart))
+	r.Post("/sessions/{id}/pause", h.SessionOperation(service.OperationPause))
+	r.Post("/sessions/{id}/unpause", h.SessionOperation(service.OperationUnpause))
+	r.Post("/sessions/{id}/restart", h.SessionOperation(service.OperationRestart))
+	r.Post("/sessions/{id}/exec", h.ExecCommand)
+	r.Get("/sessions/{id}/snapshots", h.ListSessionSnapshots)
 	r.Delete("/sessions", h.DeleteAllSessions)
 
+	// Templates
+	r.Get("/templates", h.ListTemplates)
+	r.Post("/templates", h.CreateTemplate)
+	r.Get("/templates/{name}", h.GetTemplate)
+	r.Put("/templates/{name}", h.UpdateTemplate)
+	r.Delete("/templates/{name}", h.DeleteTemplate)
+	r.Get("/templates/{name}/versions", h.ListTemplateVersions)
+
+	// Snapshots
+	r.Get("/snapshots", h.ListSnapshots)
+	r.Get("/snapshots/{id}", h.GetSnapshot)
+	r.Delete("/snapshots/{id}", h.DeleteSnapshot)
+
 	// Images
 	r.Get("/images", h.ListImages)
 
@@ -42,6 +72,30 @@ func (h *RestHandler) RegisterRoutes(r chi.Router) {
 	r.Delete("/containers/{id}", h.DeleteContainer)
 }
 
+// RegisterStreamingRoutes registers routes that stream their responses, or may run for
+// longer than the request timeout, and must not be subject to it
+func (h *RestHandler) RegisterStreamingRoutes(r chi.Router) {
+	h.logger.Info("Registering streaming routes")
+
+	// Images
+	r.Post("/images/pull", h.PullImage)
+
+	// Interactive terminals
+	r.Get("/sessions/{id}/exec", h.Terminal)
+
+	// Logs
+	r.Get("/sessions/{id}/logs", h.SessionLogs)
+	r.Get("/containers/{id}/logs", h.ContainerLogs)
+
+	// Files
+	r.Put("/sessions/{id}/files", h.UploadFiles)
+	r.Get("/sessions/{id}/files", h.DownloadFiles)
+
+	// Committing a large container filesystem can outlast the request timeout
+	r.Post("/sessions/{id}/snapshots", h.CreateSnapshot)
+	r.Post("/sessions/{id}/clone", h.CloneSession)
+}
+
 // ListSessions handles GET /api/v1/sessions
 func (h *RestHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
 	h.logger.Debug("Handling ListSessions request")
@@ -70,25 +124,133 @@ func (h *RestHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Creating session for image: %s", req.ImageName)
+	if req.Template != "" {
+		h.logger.Info("Creating session from template: %s", req.Template)
+	} else {
+		h.logger.Info("Creating session for image: %s", req.ImageName)
+	}
 	session, err := h.sessionService.CreateSession(&req)
 	if err != nil {
 		h.logger.Error("Failed to create session: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	h.writeCreatedSession(w, r, session)
+}
+
+// CloneSession handles POST /api/v1/sessions/{id}/clone
+func (h *RestHandler) CloneSession(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling CloneSession request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	// The body is optional
+	var req model.CloneSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("Invalid request body: %v", err)
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	h.logger.Info("Cloning session %s", id)
+	session, err := h.sessionService.CloneSession(r.Context(), id, &req)
+	if err != nil {
+		h.logger.Error("Failed to clone session: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	h.writeCreatedSession(w, r, session)
+}
+
+// writeCreatedSession responds to a request that created a session. Without ?wait=ready
+// the session is still provisioning; with it, the response waits for the session to settle.
+func (h *RestHandler) writeCreatedSession(w http.ResponseWriter, r *http.Request, session *model.Session) {
+	if !wantsWait(r) {
+		writeJSON(w, http.StatusAccepted, model.CreateSessionResponse{Session: *session})
+		return
+	}
 
-		if util.IsInvalidRequestError(err) {
-			writeError(w, http.StatusBadRequest, err.Error())
+	ctx, cancel := waitContext(r)
+	defer cancel()
+
+	session, err := h.sessionService.WaitForSession(ctx, session.ID, service.IsSessionSettled)
+	if err != nil {
+		h.logger.Error("Failed to wait for session: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	switch {
+	case session.Status == model.SessionStatusError:
+		writeError(w, http.StatusInternalServerError, session.StatusReason)
+	case service.IsSessionSettled(session):
+		writeJSON(w, http.StatusCreated, model.CreateSessionResponse{Session: *session})
+	default:
+		writeJSON(w, http.StatusAccepted, model.CreateSessionResponse{Session: *session})
+	}
+}
+
+// GetSession handles GET /api/v1/sessions/{id}, returning the session together with the
+// live state of its container. With ?wait=ready it long-polls until the
+// session has finished provisioning or ?timeout (default 30s) passes.
+func (h *RestHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling GetSession request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.logger.Error("Session ID is required")
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	if wantsWait(r) {
+		ctx, cancel := waitContext(r)
+		defer cancel()
+		if _, err := h.sessionService.WaitForSession(ctx, id, service.IsSessionSettled); err != nil {
+			h.logger.Error("Failed to wait for session: %v", err)
+			writeServiceError(w, err)
 			return
 		}
+	}
 
-		writeError(w, http.StatusInternalServerError, err.Error())
+	// Fetch once more so the response includes the live container state
+	session, err := h.sessionService.GetSession(id)
+	if err != nil {
+		h.logger.Error("Failed to get session: %v", err)
+		writeServiceError(w, err)
 		return
 	}
 
-	response := model.CreateSessionResponse{
-		Session: *session,
-	}
+	writeJSON(w, http.StatusOK, model.SessionResponse{Session: *session})
+}
 
-	writeJSON(w, http.StatusCreated, response)
+// Long-poll limits; the maximum stays below the request timeout
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 55 * time.Second
+)
+
+// wantsWait reports whether the client asked to wait for the session to be ready
+func wantsWait(r *http.Request) bool {
+	return r.URL.Query().Get("wait") == "ready"
+}
+
+// waitContext derives the long-poll context from the request and its ?timeout parameter
+func waitContext(r *http.Request) (context.Context, context.CancelFunc) {
+	timeout := defaultWaitTimeout
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			timeout = parsed
+		}
+	}
+	if timeout > maxWaitTimeout {
+		timeout = maxWaitTimeout
+	}
+	return context.WithTimeout(r.Context(), timeout)
 }
 
 // DeleteSession handles DELETE /api/v1/sessions/{id}
@@ -121,6 +283,90 @@ func (h *RestHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, response)
 }
 
+// ExtendSession handles POST /api/v1/sessions/{id}/extend
+func (h *RestHandler) ExtendSession(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling ExtendSession request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.logger.Error("Session ID is required")
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	var req model.ExtendSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request body: %v", err)
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	h.logger.Info("Extending session %s by %d seconds", id, req.TTLSeconds)
+	session, err := h.sessionService.ExtendSession(id, req.TTLSeconds)
+	if err != nil {
+		h.logger.Error("Failed to extend session: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	response := model.SessionResponse{
+		Session: *session,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ResumeSession handles POST /api/v1/sessions/{id}/resume
+func (h *RestHandler) ResumeSession(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling ResumeSession request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.logger.Error("Session ID is required")
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	h.logger.Info("Resuming session: %s", id)
+	session, err := h.sessionService.ResumeSession(id)
+	if err != nil {
+		h.logger.Error("Failed to resume session: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	response := model.SessionResponse{
+		Session: *session,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// SessionOperation returns a handler for POST /api/v1/sessions/{id}/{op} that applies a lifecycle operation
+func (h *RestHandler) SessionOperation(op string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Debug("Handling SessionOperation %s request", op)
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			h.logger.Error("Session ID is required")
+			writeError(w, http.StatusBadRequest, "session ID is required")
+			return
+		}
+
+		h.logger.Info("Applying %s to session: %s", op, id)
+		session, err := h.sessionService.ApplySessionOperation(id, op)
+		if err != nil {
+			h.logger.Error("Failed to %s session: %v", op, err)
+			writeServiceError(w, err)
+			return
+		}
+
+		response := model.SessionResponse{
+			Session: *session,
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
 // DeleteAllSessions handles DELETE /api/v1/sessions
 func (h *RestHandler) DeleteAllSessions(w http.ResponseWriter, r *http.Request) {
 	h.logger.Debug("Handling DeleteAllSessions request")
@@ -159,6 +405,43 @@ func (h *RestHandler) ListImages(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, response)
 }
 
+// PullImage handles POST /api/v1/images/pull, streaming pull progress as server-sent events.
+// Events are "progress" for every update, then "complete" or "error".
+func (h *RestHandler) PullImage(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling PullImage request")
+	var req model.PullImageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request body: %v", err)
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Image == "" {
+		writeError(w, http.StatusBadRequest, "image is required")
+		return
+	}
+
+	sse, ok := newSSEWriter(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	h.logger.Info("Pulling image: %s", req.Image)
+	err := h.sessionService.PullImage(r.Context(), &req, func(event model.PullProgressEvent) {
+		if err := sse.writeEvent("progress", event); err != nil {
+			h.logger.Debug("Failed to write pull progress: %v", err)
+		}
+	})
+	if err != nil {
+		h.logger.Error("Failed to pull image: %v", err)
+		sse.writeEvent("error", map[string]string{"error": err.Error()})
+		return
+	}
+
+	sse.writeEvent("complete", map[string]string{"image": req.Image})
+}
+
 // ListAllContainers handles GET /api/v1/containers
 func (h *RestHandler) ListAllContainers(w http.ResponseWriter, r *http.Request) {
 	h.logger.Debug("Handling ListAllContainers request")
@@ -217,6 +500,24 @@ func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
+// writeServiceError writes an error response with the status code matching a service error
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case util.IsNotFoundError(err):
+		writeError(w, http.StatusNotFound, err.Error())
+	case util.IsInvalidRequestError(err):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case util.IsOperationNotValidError(err):
+		writeError(w, http.StatusConflict, err.Error())
+	case util.IsUnavailableError(err):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case util.IsTooLargeError(err):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 // writeError writes an error response
 func writeError(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/internal/service"
+	"github.com/yourusername/session-manager/pkg/docker/dockertest"
+	"github.com/yourusername/session-manager/pkg/port"
+)
+
+func newSessionTestRouter(t *testing.T) (*dockertest.FakeAPI, *service.SessionService, http.Handler) {
+	t.Helper()
+
+	fake, dockerManager := dockertest.NewFakeAPI(t)
+	cfg := config.DefaultConfig()
+	cfg.SessionStorePath = ""
+	cfg.TemplateStorePath = ""
+	sessionService := service.NewSessionService(cfg, dockerManager, port.NewPortManager(), nil, nil)
+
+	r := chi.NewRouter()
+	h := NewRestHandler(sessionService, nil)
+	h.RegisterRoutes(r)
+	h.RegisterStreamingRoutes(r)
+	return fake, sessionService, r
+}
+
+func TestGetSessionRoute(t *testing.T) {
+	fake, sessionService, router := newSessionTestRouter(t)
+	fake.AddImage("nginx:latest")
+	release := fake.HoldPulls()
+	defer release()
+
+	running, err := sessionService.CreateSession(&model.CreateSessionRequest{ImageName: "nginx:latest"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	// The pull of redis:7 is held, so this session stays provisioning
+	provisioning, err := sessionService.CreateSession(&model.CreateSessionRequest{ImageName: "redis:7"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		wantCode        int
+		wantStatus      string
+		wantContainer   string
+		wantMinDuration time.Duration
+	}{
+		{name: "wait for running session", path: "/sessions/" + running.ID + "?wait=ready", wantCode: http.StatusOK, wantStatus: model.SessionStatusRunning, wantContainer: "running"},
+		{name: "running session", path: "/sessions/" + running.ID, wantCode: http.StatusOK, wantStatus: model.SessionStatusRunning, wantContainer: "running"},
+		{name: "provisioning session", path: "/sessions/" + provisioning.ID, wantCode: http.StatusOK, wantStatus: model.SessionStatusProvisioning},
+		{
+			name:            "wait times out",
+			path:            "/sessions/" + provisioning.ID + "?wait=ready&timeout=100ms",
+			wantCode:        http.StatusOK,
+			wantStatus:      model.SessionStatusProvisioning,
+			wantMinDuration: 100 * time.Millisecond,
+		},
+		{name: "unknown session", path: "/sessions/missing", wantCode: http.StatusNotFound},
+		{name: "wait for unknown session", path: "/sessions/missing?wait=ready", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if elapsed := time.Since(start); elapsed < tt.wantMinDuration {
+				t.Fatalf("answered after %s, want at least %s", elapsed, tt.wantMinDuration)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var resp model.SessionResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Session.Status != tt.wantStatus {
+				t.Errorf("session status = %s, want %s", resp.Session.Status, tt.wantStatus)
+			}
+			container := ""
+			if resp.Session.Container != nil {
+				container = resp.Session.Container.Status
+			}
+			if container != tt.wantContainer {
+				t.Errorf("container status = %q, want %q", container, tt.wantContainer)
+			}
+		})
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	tests := []struct {
+		query string
+		want  time.Duration
+	}{
+		{query: "", want: defaultWaitTimeout},
+		{query: "?timeout=5s", want: 5 * time.Second},
+		{query: "?timeout=10m", want: maxWaitTimeout},
+		{query: "?timeout=-1s", want: defaultWaitTimeout},
+		{query: "?timeout=soon", want: defaultWaitTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			ctx, cancel := waitContext(httptest.NewRequest(http.MethodGet, "/sessions/s1"+tt.query, nil))
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("no deadline")
+			}
+			if remaining := time.Until(deadline); remaining > tt.want || remaining < tt.want-time.Second {
+				t.Fatalf("deadline in %s, want %s", remaining, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloneSessionRoute(t *testing.T) {
+	fake, sessionService, router := newSessionTestRouter(t)
+	fake.AddImage("nginx:latest")
+	source, err := sessionService.CreateSession(&model.CreateSessionRequest{ImageName: "nginx:latest"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, err := sessionService.WaitForSession(t.Context(), source.ID, service.IsSessionSettled); err != nil {
+		t.Fatalf("WaitForSession: %v", err)
+	}
+	clone := "/sessions/" + source.ID + "/clone"
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "without body", path: clone, wantCode: http.StatusAccepted, wantStatus: model.SessionStatusProvisioning},
+		{name: "wait for clone", path: clone + "?wait=ready", body: `{"ttl_seconds": 600}`, wantCode: http.StatusCreated, wantStatus: model.SessionStatusRunning},
+		{name: "with filesystem", path: clone + "?wait=ready", body: `{"include_filesystem": true}`, wantCode: http.StatusCreated, wantStatus: model.SessionStatusRunning},
+		{name: "invalid body", path: clone, body: "{", wantCode: http.StatusBadRequest},
+		{name: "invalid ttl", path: clone, body: `{"ttl_seconds": -1}`, wantCode: http.StatusBadRequest},
+		{name: "unknown session", path: "/sessions/missing/clone", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantStatus == "" {
+				return
+			}
+
+			var resp model.CreateSessionResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Session.ID == source.ID || resp.Session.Status != tt.wantStatus {
+				t.Fatalf("clone %s is %s, want a new session that is %s", resp.Session.ID, resp.Session.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+// CreateSnapshot handles POST /api/v1/sessions/{id}/snapshots
+func (h *RestHandler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling CreateSnapshot request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	// The body is optional
+	var req model.CreateSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("Invalid request body: %v", err)
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	h.logger.Info("Creating snapshot of session %s", id)
+	snapshot, err := h.sessionService.CreateSnapshot(r.Context(), id, &req)
+	if err != nil {
+		h.logger.Error("Failed to create snapshot: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, model.SnapshotResponse{Snapshot: *snapshot})
+}
+
+// ListSessionSnapshots handles GET /api/v1/sessions/{id}/snapshots
+func (h *RestHandler) ListSessionSnapshots(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling ListSessionSnapshots request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "session ID is required")
+		return
+	}
+
+	snapshots, err := h.sessionService.ListSessionSnapshots(id)
+	if err != nil {
+		h.logger.Error("Failed to list snapshots: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.ListSnapshotsResponse{Snapshots: snapshots})
+}
+
+// ListSnapshots handles GET /api/v1/snapshots
+func (h *RestHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling ListSnapshots request")
+	snapshots, err := h.sessionService.ListSnapshots()
+	if err != nil {
+		h.logger.Error("Failed to list snapshots: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.ListSnapshotsResponse{Snapshots: snapshots})
+}
+
+// GetSnapshot handles GET /api/v1/snapshots/{id}
+func (h *RestHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling GetSnapshot request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "snapshot ID is required")
+		return
+	}
+
+	snapshot, err := h.sessionService.GetSnapshot(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.SnapshotResponse{Snapshot: *snapshot})
+}
+
+// DeleteSnapshot handles DELETE /api/v1/snapshots/{id}
+func (h *RestHandler) DeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling DeleteSnapshot request")
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "snapshot ID is required")
+		return
+	}
+
+	h.logger.Info("Deleting snapshot %s", id)
+	if err := h.sessionService.DeleteSnapshot(id); err != nil {
+		h.logger.Error("Failed to delete snapshot: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "snapshot deleted successfully"})
+}
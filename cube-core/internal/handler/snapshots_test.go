@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+func TestSnapshotRoutes(t *testing.T) {
+	fake, sessionService, router := newSessionTestRouter(t)
+	fake.AddImage("nginx:latest")
+	session, err := sessionService.CreateSession(&model.CreateSessionRequest{ImageName: "nginx:latest"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, err := sessionService.WaitForSession(t.Context(), session.ID, func(s *model.Session) bool {
+		return s.ContainerID != ""
+	}); err != nil {
+		t.Fatalf("WaitForSession: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sessions/"+session.ID+"/snapshots", strings.NewReader(`{"name": "seeded"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status code = %d: %s", rec.Code, rec.Body.String())
+	}
+	var created model.SnapshotResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if created.Snapshot.Name != "seeded" || created.Snapshot.SessionID != session.ID {
+		t.Fatalf("created snapshot = %+v", created.Snapshot)
+	}
+	snapshot := "/snapshots/" + created.Snapshot.ID
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		// wantCount is how many snapshots a list returns
+		wantCount int
+	}{
+		{name: "create without body", method: http.MethodPost, path: "/sessions/" + session.ID + "/snapshots", wantCode: http.StatusCreated},
+		{name: "create with invalid body", method: http.MethodPost, path: "/sessions/" + session.ID + "/snapshots", body: "{", wantCode: http.StatusBadRequest},
+		{name: "create of unknown session", method: http.MethodPost, path: "/sessions/missing/snapshots", wantCode: http.StatusNotFound},
+		{name: "list", method: http.MethodGet, path: "/snapshots", wantCode: http.StatusOK, wantCount: 2},
+		{name: "list of session", method: http.MethodGet, path: "/sessions/" + session.ID + "/snapshots", wantCode: http.StatusOK, wantCount: 2},
+		{name: "list of unknown session", method: http.MethodGet, path: "/sessions/missing/snapshots", wantCode: http.StatusNotFound},
+		{name: "get", method: http.MethodGet, path: snapshot, wantCode: http.StatusOK},
+		{name: "get unknown", method: http.MethodGet, path: "/snapshots/missing", wantCode: http.StatusNotFound},
+		{name: "delete", method: http.MethodDelete, path: snapshot, wantCode: http.StatusOK},
+		{name: "get deleted", method: http.MethodGet, path: snapshot, wantCode: http.StatusNotFound},
+		{name: "delete again", method: http.MethodDelete, path: snapshot, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCount == 0 {
+				return
+			}
+
+			var resp model.ListSnapshotsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Snapshots) != tt.wantCount {
+				t.Fatalf("listed %d snapshots, want %d", len(resp.Snapshots), tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+// sseWriter writes server-sent events to a response, flushing after every event
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newSSEWriter prepares a response for server-sent events. It returns false if the
+// response writer cannot stream.
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &sseWriter{w: w, flusher: flusher}, true
+}
+
+// writeEvent sends a named event with a JSON-encoded payload
+func (s *sseWriter) writeEvent(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// flushWriter writes to a response and flushes after every write, so chunked output
+// reaches the client as soon as it is produced
+type flushWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.flusher.Flush()
+	return n, err
+}
+
+// sseLogWriter turns container output into one "log" event per line
+type sseLogWriter struct {
+	sse     *sseWriter
+	stream  string
+	partial []byte
+}
+
+func (lw *sseLogWriter) Write(p []byte) (int, error) {
+	lw.partial = append(lw.partial, p...)
+	for {
+		i := bytes.IndexByte(lw.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if err := lw.writeLine(lw.partial[:i]); err != nil {
+			return 0, err
+		}
+		lw.partial = lw.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush sends a trailing line that did not end with a newline
+func (lw *sseLogWriter) flush() error {
+	if len(lw.partial) == 0 {
+		return nil
+	}
+	err := lw.writeLine(lw.partial)
+	lw.partial = nil
+	return err
+}
+
+func (lw *sseLogWriter) writeLine(line []byte) error {
+	return lw.sse.writeEvent("log", model.LogEvent{
+		Stream: lw.stream,
+		Line:   strings.TrimSuffix(string(line), "\r"),
+	})
+}
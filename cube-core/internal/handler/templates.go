@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+// ListTemplates handles GET /api/v1/templates
+func (h *RestHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling ListTemplates request")
+	templates := h.sessionService.ListTemplates()
+	writeJSON(w, http.StatusOK, model.ListTemplatesResponse{Templates: templates})
+}
+
+// CreateTemplate handles POST /api/v1/templates
+func (h *RestHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling CreateTemplate request")
+	var req model.SessionTemplate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request body: %v", err)
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	h.logger.Info("Creating template %s", req.Name)
+	template, err := h.sessionService.CreateTemplate(&req)
+	if err != nil {
+		h.logger.Error("Failed to create template: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, model.TemplateResponse{Template: *template})
+}
+
+// GetTemplate handles GET /api/v1/templates/{name}, returning the latest version unless
+// ?version= asks for an earlier one
+func (h *RestHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling GetTemplate request")
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "template name is required")
+		return
+	}
+
+	version := 0
+	if raw := r.URL.Query().Get("version"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid version")
+			return
+		}
+		version = parsed
+	}
+
+	template, err := h.sessionService.GetTemplate(name, version)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.TemplateResponse{Template: *template})
+}
+
+// ListTemplateVersions handles GET /api/v1/templates/{name}/versions
+func (h *RestHandler) ListTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling ListTemplateVersions request")
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "template name is required")
+		return
+	}
+
+	versions, err := h.sessionService.ListTemplateVersions(name)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.ListTemplatesResponse{Templates: versions})
+}
+
+// UpdateTemplate handles PUT /api/v1/templates/{name}
+func (h *RestHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling UpdateTemplate request")
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "template name is required")
+		return
+	}
+
+	var req model.SessionTemplate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request body: %v", err)
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	h.logger.Info("Updating template %s", name)
+	template, err := h.sessionService.UpdateTemplate(name, &req)
+	if err != nil {
+		h.logger.Error("Failed to update template: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.TemplateResponse{Template: *template})
+}
+
+// DeleteTemplate handles DELETE /api/v1/templates/{name}
+func (h *RestHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("Handling DeleteTemplate request")
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "template name is required")
+		return
+	}
+
+	h.logger.Info("Deleting template %s", name)
+	if err := h.sessionService.DeleteTemplate(name); err != nil {
+		h.logger.Error("Failed to delete template: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "template deleted successfully"})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+func TestTemplateRoutes(t *testing.T) {
+	_, _, router := newSessionTestRouter(t)
+
+	// The cases run in order against the same server
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		wantCode    int
+		wantVersion int
+		// wantCount is how many templates a list returns
+		wantCount int
+	}{
+		{name: "create", method: http.MethodPost, path: "/templates", body: `{"name": "todo-app", "image_name": "todo:1"}`, wantCode: http.StatusCreated, wantVersion: 1},
+		{name: "create existing", method: http.MethodPost, path: "/templates", body: `{"name": "todo-app", "image_name": "todo:1"}`, wantCode: http.StatusConflict},
+		{name: "create invalid", method: http.MethodPost, path: "/templates", body: `{"name": "Todo App", "image_name": "todo:1"}`, wantCode: http.StatusBadRequest},
+		{name: "create with invalid body", method: http.MethodPost, path: "/templates", body: "{", wantCode: http.StatusBadRequest},
+		{name: "update", method: http.MethodPut, path: "/templates/todo-app", body: `{"image_name": "todo:2"}`, wantCode: http.StatusOK, wantVersion: 2},
+		{name: "update unknown", method: http.MethodPut, path: "/templates/missing", body: `{"image_name": "todo:2"}`, wantCode: http.StatusNotFound},
+		{name: "update with other name", method: http.MethodPut, path: "/templates/todo-app", body: `{"name": "other", "image_name": "todo:2"}`, wantCode: http.StatusBadRequest},
+		{name: "get latest", method: http.MethodGet, path: "/templates/todo-app", wantCode: http.StatusOK, wantVersion: 2},
+		{name: "get version", method: http.MethodGet, path: "/templates/todo-app?version=1", wantCode: http.StatusOK, wantVersion: 1},
+		{name: "get unknown version", method: http.MethodGet, path: "/templates/todo-app?version=7", wantCode: http.StatusNotFound},
+		{name: "get invalid version", method: http.MethodGet, path: "/templates/todo-app?version=latest", wantCode: http.StatusBadRequest},
+		{name: "list", method: http.MethodGet, path: "/templates", wantCode: http.StatusOK, wantCount: 1},
+		{name: "list versions", method: http.MethodGet, path: "/templates/todo-app/versions", wantCode: http.StatusOK, wantCount: 2},
+		{name: "list versions of unknown", method: http.MethodGet, path: "/templates/missing/versions", wantCode: http.StatusNotFound},
+		{name: "create session from unknown template", method: http.MethodPost, path: "/sessions", body: `{"template": "missing"}`, wantCode: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, path: "/templates/todo-app", wantCode: http.StatusOK},
+		{name: "get deleted", method: http.MethodGet, path: "/templates/todo-app", wantCode: http.StatusNotFound},
+		{name: "delete again", method: http.MethodDelete, path: "/templates/todo-app", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+
+			switch {
+			case tt.wantVersion > 0:
+				var resp model.TemplateResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if resp.Template.Name != "todo-app" || resp.Template.Version != tt.wantVersion {
+					t.Fatalf("template %s version %d, want todo-app version %d", resp.Template.Name, resp.Template.Version, tt.wantVersion)
+				}
+			case tt.wantCount > 0:
+				var resp model.ListTemplatesResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if len(resp.Templates) != tt.wantCount {
+					t.Fatalf("listed %d templates, want %d", len(resp.Templates), tt.wantCount)
+				}
+			}
+		})
+	}
+}
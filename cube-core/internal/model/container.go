@@ -15,6 +15,8 @@ type ContainerInfo struct {
 	CreatedAt time.Time `json:"created_at"`
 	Ports     []Port    `json:"ports"`
 	IsManaged bool      `json:"is_managed"`
+	// Networks are the Docker networks the container is attached to
+	Networks []string `json:"networks,omitempty"`
 }
 
 // ListAllContainersResponse is the response for the ListAllContainers API
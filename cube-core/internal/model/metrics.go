@@ -56,9 +56,20 @@ type ContainerMetrics struct {
 	BlockIO       BlockIOMetrics  `json:"block_io"`
 	RestartCount  int             `json:"restart_count"`
 	Status        string          `json:"status"`
+	Health        *HealthStatus   `json:"health,omitempty"`
 	Uptime        int64           `json:"uptime_seconds"`
 	UptimeDisplay string          `json:"uptime_display"`
 	Timestamp     int64           `json:"timestamp"`
+	// Egress counts the outbound connections of sessions with an egress policy
+	Egress *EgressMetrics `json:"egress,omitempty"`
+}
+
+// EgressMetrics represents the outbound connections a session's egress proxy allowed and denied
+type EgressMetrics struct {
+	Allowed uint64 `json:"allowed"`
+	// Denied counts policy violations; LastDenied is the most recent denied destination
+	Denied     uint64 `json:"denied"`
+	LastDenied string `json:"last_denied,omitempty"`
 }
 
 // CPUUsageMetrics represents CPU usage metrics for a container
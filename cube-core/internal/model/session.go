@@ -9,27 +9,339 @@ type Port struct {
 	Protocol      string `json:"protocol"`
 	Description   string `json:"description"`
 	URL           string `json:"url,omitempty"`
+	// Scheme overrides the URL scheme guessed from the container port
+	Scheme string `json:"scheme,omitempty"`
+	// Service is the stack service publishing the port, for multi-container sessions
+	Service string `json:"service,omitempty"`
 }
 
+// Session statuses
+const (
+	SessionStatusProvisioning = "provisioning"
+	SessionStatusRunning      = "running"
+	SessionStatusStopped      = "stopped"
+	SessionStatusPaused       = "paused"
+	SessionStatusError        = "error"
+	SessionStatusUnknown      = "unknown"
+	SessionStatusExpired      = "expired"
+	// Sessions with readiness checks go from starting to ready or failed instead of running
+	SessionStatusStarting = "starting"
+	SessionStatusReady    = "ready"
+	SessionStatusFailed   = "failed"
+	// The container's main process exited, or keeps exiting and being restarted by Docker
+	SessionStatusExited    = "exited"
+	SessionStatusCrashLoop = "crashloop"
+)
+
 // Session represents a container session
 type Session struct {
-	ID          string    `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	ImageName   string    `json:"image_name"`
-	ContainerID string    `json:"container_id"`
-	Ports       []Port    `json:"ports"`
-	Status      string    `json:"status"` // "running", "stopped", "error"
+	ID           string     `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ImageName    string     `json:"image_name"`
+	ContainerID  string     `json:"container_id"`
+	Ports        []Port     `json:"ports"`
+	Status       string     `json:"status"` // "provisioning", "starting", "running", "ready", "failed", "paused", "stopped", "exited", "crashloop", "error", "expired"
+	StatusReason string     `json:"status_reason,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	// LastActivityAt is the last time network or CPU activity was seen in the session
+	LastActivityAt *time.Time      `json:"last_activity_at,omitempty"`
+	Resources      *ResourceLimits `json:"resources,omitempty"`
+	// Env holds the session's environment with secret values redacted
+	Env        []EnvVar `json:"env,omitempty"`
+	Command    []string `json:"command,omitempty"`
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	WorkingDir string   `json:"working_dir,omitempty"`
+	User       string   `json:"user,omitempty"`
+	Mounts     []Mount  `json:"mounts,omitempty"`
+	// SessionVolume is the named volume created for this session, if any
+	SessionVolume *SessionVolume `json:"session_volume,omitempty"`
+	// Readiness holds the checks that decide when the session is ready
+	Readiness *ReadinessConfig `json:"readiness,omitempty"`
+	// Health is the latest result of the container's HEALTHCHECK, if the image declares one
+	Health        *HealthStatus  `json:"health,omitempty"`
+	RestartPolicy *RestartPolicy `json:"restart_policy,omitempty"`
+	// RecreateCount is how often the container was recreated under the restart policy
+	RecreateCount int `json:"recreate_count,omitempty"`
+	// ExitCode is the exit status of the container's main process once it has exited
+	ExitCode *int `json:"exit_code,omitempty"`
+	// RestartCount is how often Docker restarted the container under its restart policy
+	RestartCount int `json:"restart_count"`
+	// Container is the live container state. Listings only fill in its status; a single
+	// session fetched has all of it.
+	Container *ContainerState `json:"container,omitempty"`
+	// SnapshotID is the snapshot the session was started from, if any
+	SnapshotID string `json:"snapshot_id,omitempty"`
+	// OwnsSnapshot marks a snapshot taken just to start this session, as for filesystem
+	// clones, and shared with clones of it. It is deleted along with the last session
+	// that owns it.
+	OwnsSnapshot bool `json:"owns_snapshot,omitempty"`
+	// Template and TemplateVersion record the template the session was created from, if any
+	Template        string `json:"template,omitempty"`
+	TemplateVersion int    `json:"template_version,omitempty"`
+	// Services are the containers of a multi-container session in start order. ContainerID
+	// is then the primary service's container.
+	Services []ServiceContainer `json:"services,omitempty"`
+	// Network is the private network the session's containers share, if any
+	Network string `json:"network,omitempty"`
+	// InternalNetwork means the session's network has no route out and publishes no ports
+	InternalNetwork bool `json:"internal_network,omitempty"`
+	// Egress is the session's outbound traffic policy, enforced by the egress proxy container
+	Egress        *EgressPolicy `json:"egress,omitempty"`
+	EgressProxyID string        `json:"egress_proxy_id,omitempty"`
+}
+
+// ServiceContainer represents one service of a multi-container session
+type ServiceContainer struct {
+	Name        string `json:"name"`
+	ImageName   string `json:"image_name"`
+	ContainerID string `json:"container_id"`
+	Primary     bool   `json:"primary,omitempty"`
+	// Env holds the service's environment with secret values redacted
+	Env       []EnvVar        `json:"env,omitempty"`
+	DependsOn []string        `json:"depends_on,omitempty"`
+	Resources *ResourceLimits `json:"resources,omitempty"`
+	// Container is the live container state, only filled in when a single session is fetched
+	Container *ContainerState `json:"container,omitempty"`
+}
+
+// ContainerState represents the live state of a session's container
+type ContainerState struct {
+	Status       string     `json:"status"`
+	Running      bool       `json:"running"`
+	Paused       bool       `json:"paused"`
+	Restarting   bool       `json:"restarting"`
+	OOMKilled    bool       `json:"oom_killed"`
+	ExitCode     int        `json:"exit_code"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	RestartCount int        `json:"restart_count"`
+}
+
+// Container health statuses reported by Docker
+const (
+	HealthStatusStarting  = "starting"
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// HealthStatus represents the result of a container's Docker HEALTHCHECK
+type HealthStatus struct {
+	Status        string     `json:"status"` // "starting", "healthy" or "unhealthy"
+	FailingStreak int        `json:"failing_streak"`
+	LastOutput    string     `json:"last_output,omitempty"`
+	LastExitCode  int        `json:"last_exit_code"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+}
+
+// Docker restart policies
+const (
+	RestartPolicyNo            = "no"
+	RestartPolicyAlways        = "always"
+	RestartPolicyOnFailure     = "on-failure"
+	RestartPolicyUnlessStopped = "unless-stopped"
+)
+
+// RestartPolicy represents how a session's container is brought back when it fails
+type RestartPolicy struct {
+	// Name is the Docker restart policy: "no", "always", "on-failure" (or "on-failure:N")
+	// or "unless-stopped"
+	Name string `json:"name,omitempty"`
+	// MaxRetries limits the restarts of the "on-failure" policy; zero means no limit
+	MaxRetries int `json:"max_retries,omitempty"`
+	// RecreateOnUnhealthy replaces the container with a fresh one when its healthcheck
+	// reports it unhealthy
+	RecreateOnUnhealthy bool `json:"recreate_on_unhealthy,omitempty"`
+	// MaxRecreates limits how often the container is recreated; zero means no limit
+	MaxRecreates int `json:"max_recreates,omitempty"`
+}
+
+// Mount types
+const (
+	MountTypeVolume = "volume"
+	MountTypeBind   = "bind"
+	MountTypeTmpfs  = "tmpfs"
+)
+
+// Mount represents a volume, host directory or tmpfs mounted into a session's container
+type Mount struct {
+	Type           string `json:"type"`             // "volume", "bind" or "tmpfs"
+	Source         string `json:"source,omitempty"` // volume name or host path; unused for tmpfs
+	Target         string `json:"target"`           // absolute path inside the container
+	ReadOnly       bool   `json:"read_only,omitempty"`
+	TmpfsSizeBytes int64  `json:"tmpfs_size_bytes,omitempty"`
+}
+
+// SessionVolume represents a named volume that lives as long as its session
+type SessionVolume struct {
+	Name   string `json:"name,omitempty"` // assigned by the server
+	Target string `json:"target"`         // absolute path inside the container
+	// Retain keeps the volume when the session is deleted
+	Retain bool `json:"retain,omitempty"`
+}
+
+// Readiness check types
+const (
+	ReadinessCheckTCP  = "tcp"
+	ReadinessCheckHTTP = "http"
+	ReadinessCheckExec = "exec"
+)
+
+// ReadinessConfig represents the checks that must all pass before a session is reported ready
+type ReadinessConfig struct {
+	Checks []ReadinessCheck `json:"checks"`
+	// TimeoutSeconds is how long the session may take to become ready before it fails
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// IntervalSeconds is the time between attempts, which also bounds a single attempt
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+}
+
+// ReadinessCheck represents a single readiness check
+type ReadinessCheck struct {
+	Type string `json:"type"` // "tcp", "http" or "exec"
+	// Port is the container port to probe for tcp and http checks
+	Port int `json:"port,omitempty"`
+	// Path and ExpectedStatus apply to http checks; any 2xx status passes by default
+	Path           string `json:"path,omitempty"`
+	ExpectedStatus int    `json:"expected_status,omitempty"`
+	// Command is run inside the container for exec checks and must exit with status 0
+	Command []string `json:"command,omitempty"`
+}
+
+// RedactedValue replaces secret environment values in API responses
+const RedactedValue = "[REDACTED]"
+
+// EnvVar represents an environment variable set in a session's container
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Secret values are never returned by the API
+	Secret bool `json:"secret,omitempty"`
+}
+
+// ResourceLimits represents the resources a session's container may use. Zero values mean
+// the server default (or no limit if there is none).
+type ResourceLimits struct {
+	CPUs            float64 `json:"cpus,omitempty"`              // CPU quota in cores, e.g. 0.5
+	MemoryBytes     int64   `json:"memory_bytes,omitempty"`      // hard memory limit
+	MemorySwapBytes int64   `json:"memory_swap_bytes,omitempty"` // memory plus swap; -1 for unlimited swap
+	PidsLimit       int64   `json:"pids_limit,omitempty"`        // maximum number of processes
+	BlkioWeight     uint16  `json:"blkio_weight,omitempty"`      // relative block IO weight, 10-1000
 }
 
 // CreateSessionRequest represents a request to create a new session
 type CreateSessionRequest struct {
-	ImageName    string `json:"image_name"`
-	NumPorts     int    `json:"num_ports,omitempty"`
-	PortMappings []struct {
-		ContainerPort int    `json:"container_port"`
-		Protocol      string `json:"protocol,omitempty"`
-		Description   string `json:"description,omitempty"`
-	} `json:"port_mappings,omitempty"`
+	ImageName    string        `json:"image_name"`
+	SnapshotID   string        `json:"snapshot_id,omitempty"` // starts the session from a snapshot instead of ImageName
+	NumPorts     int           `json:"num_ports,omitempty"`
+	PortMappings []PortMapping `json:"port_mappings,omitempty"`
+	// TTLSeconds and ExpiresAt are mutually exclusive ways to limit the session lifetime
+	TTLSeconds int             `json:"ttl_seconds,omitempty"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	Resources  *ResourceLimits `json:"resources,omitempty"`
+	Env        []EnvVar        `json:"env,omitempty"`
+	Command    []string        `json:"command,omitempty"`    // overrides the image CMD
+	Entrypoint []string        `json:"entrypoint,omitempty"` // overrides the image ENTRYPOINT
+	WorkingDir string          `json:"working_dir,omitempty"`
+	User       string          `json:"user,omitempty"` // user or user:group to run as
+	Mounts     []Mount         `json:"mounts,omitempty"`
+	// SessionVolume asks for a fresh named volume created for and removed with the session
+	SessionVolume *SessionVolume `json:"session_volume,omitempty"`
+	// Readiness delays reporting the session as ready until its checks pass
+	Readiness     *ReadinessConfig `json:"readiness,omitempty"`
+	RestartPolicy *RestartPolicy   `json:"restart_policy,omitempty"`
+	// RegistryAuth is used if the image has to be pulled; it is never stored
+	RegistryAuth *RegistryAuth `json:"registry_auth,omitempty"`
+	// Template starts from a server-side template, at its latest version unless
+	// TemplateVersion is set. Overrides, like the other fields of the request, replace
+	// the template's values.
+	Template        string                `json:"template,omitempty"`
+	TemplateVersion int                   `json:"template_version,omitempty"`
+	Overrides       *CreateSessionRequest `json:"overrides,omitempty"`
+	// Stack makes the session a group of containers instead of a single image
+	Stack *StackSpec `json:"stack,omitempty"`
+	// InternalNetwork puts the session on a private network without egress. Docker does not
+	// publish ports of containers on such a network, so the session gets no host ports.
+	InternalNetwork bool `json:"internal_network,omitempty"`
+	// Egress restricts outbound traffic; without it the session has full network access
+	Egress *EgressPolicy `json:"egress,omitempty"`
+	// OwnsSnapshot hands the snapshot to the session, which deletes it when it is torn
+	// down unless other sessions still use it. It is set for clones and cannot be
+	// requested through the API.
+	OwnsSnapshot bool `json:"-"`
+}
+
+// Egress policy modes
+const (
+	EgressModeFull      = "full"
+	EgressModeNone      = "none"
+	EgressModeAllowlist = "allowlist"
+)
+
+// EgressPolicy represents which outbound connections a session may make
+type EgressPolicy struct {
+	// Mode is "full" (no restriction), "none" (no outbound connections) or "allowlist"
+	Mode  string       `json:"mode"`
+	Allow []EgressRule `json:"allow,omitempty"` // only used with "allowlist"
+}
+
+// EgressRule allows connections to a network, on the listed ports or on any port
+type EgressRule struct {
+	CIDR  string `json:"cidr"` // a CIDR or a single IP address
+	Ports []int  `json:"ports,omitempty"`
+}
+
+// StackSpec represents a compose-like group of services run as one session on a private
+// network, where services reach each other by name
+type StackSpec struct {
+	Services map[string]StackService `json:"services"`
+	// Primary is the service that exec, logs, files, readiness and health checks apply to.
+	// It defaults to the first service in start order that publishes a port.
+	Primary string `json:"primary,omitempty"`
+}
+
+// StackService represents one container of a stack
+type StackService struct {
+	ImageName  string   `json:"image_name"`
+	Env        []EnvVar `json:"env,omitempty"`
+	Command    []string `json:"command,omitempty"`
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	WorkingDir string   `json:"working_dir,omitempty"`
+	User       string   `json:"user,omitempty"`
+	// DependsOn lists the services started before this one
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Ports are the container ports published on host ports
+	Ports     []PortMapping   `json:"ports,omitempty"`
+	Resources *ResourceLimits `json:"resources,omitempty"` // defaults to the session's resources
+}
+
+// PortMapping represents a container port to publish on a host port
+type PortMapping struct {
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol,omitempty"`
+	Description   string `json:"description,omitempty"`
+	// Scheme is the URL scheme the port is served with, http or https; by default https is
+	// assumed for container ports 443 and 8443 only
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// CloneSessionRequest represents a request to clone a session
+type CloneSessionRequest struct {
+	// IncludeFilesystem snapshots the source container first so the clone starts from its
+	// current filesystem instead of the source image
+	IncludeFilesystem bool `json:"include_filesystem,omitempty"`
+	// TTLSeconds limits the clone's lifetime; the server default applies if it is zero
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+// ExtendSessionRequest represents a request to extend a session's lease
+type ExtendSessionRequest struct {
+	TTLSeconds int `json:"ttl_seconds"`
+}
+
+// SessionResponse represents a response containing a single session
+type SessionResponse struct {
+	Session Session `json:"session"`
 }
 
 // CreateSessionResponse represents the response for a create session request
@@ -63,6 +375,110 @@ type DockerImageInfo struct {
 	ExposedPorts []int  `json:"exposed_ports,omitempty"`
 }
 
+// RegistryAuth represents credentials for pulling from a private registry
+type RegistryAuth struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	ServerAddress string `json:"server_address,omitempty"`
+	IdentityToken string `json:"identity_token,omitempty"`
+}
+
+// PullImageRequest represents a request to pull a Docker image
+type PullImageRequest struct {
+	Image        string        `json:"image"`
+	RegistryAuth *RegistryAuth `json:"registry_auth,omitempty"`
+}
+
+// PullProgressEvent represents a progress update streamed while pulling an image
+type PullProgressEvent struct {
+	ID       string `json:"id,omitempty"`
+	Status   string `json:"status"`
+	Progress string `json:"progress,omitempty"`
+	Current  int64  `json:"current,omitempty"`
+	Total    int64  `json:"total,omitempty"`
+}
+
+// LogOptions represents the query parameters of a container logs request
+type LogOptions struct {
+	Follow     bool
+	Since      string // RFC 3339 or Unix timestamp, or a duration such as "10m"
+	Tail       string // number of lines from the end, or "all"
+	Timestamps bool
+	Stdout     bool
+	Stderr     bool
+}
+
+// LogEvent represents a line of container output streamed as a server-sent event
+type LogEvent struct {
+	Stream string `json:"stream"` // "stdout" or "stderr"
+	Line   string `json:"line"`
+}
+
+// ExecRequest represents a command to run inside a session's container
+type ExecRequest struct {
+	Command    []string `json:"command"`
+	Env        []EnvVar `json:"env,omitempty"`
+	User       string   `json:"user,omitempty"`
+	WorkingDir string   `json:"working_dir,omitempty"`
+	// TimeoutSeconds limits how long a non-interactive command may run
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// ExecResponse represents the outcome of a non-interactive command
+type ExecResponse struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// Terminal message types exchanged over the exec WebSocket
+const (
+	TerminalMessageStdin  = "stdin"
+	TerminalMessageResize = "resize"
+	TerminalMessageExit   = "exit"
+	TerminalMessageError  = "error"
+)
+
+// TerminalMessage represents a control message on the exec WebSocket. Terminal output is
+// sent as binary messages; input may be sent as binary messages or as "stdin" messages.
+type TerminalMessage struct {
+	Type     string `json:"type"`
+	Data     string `json:"data,omitempty"`
+	Rows     uint   `json:"rows,omitempty"`
+	Cols     uint   `json:"cols,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Snapshot represents a session's container filesystem committed to an image
+type Snapshot struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	SessionID string `json:"session_id"`
+	// SourceImage is the image the snapshotted session was running
+	SourceImage string    `json:"source_image"`
+	Image       string    `json:"image"` // reference of the committed image
+	ImageID     string    `json:"image_id"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateSnapshotRequest represents a request to snapshot a session
+type CreateSnapshotRequest struct {
+	Name    string `json:"name,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// SnapshotResponse represents a response containing a single snapshot
+type SnapshotResponse struct {
+	Snapshot Snapshot `json:"snapshot"`
+}
+
+// ListSnapshotsResponse represents the response for a list snapshots request
+type ListSnapshotsResponse struct {
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
 // ListImagesResponse represents the response for a list images request
 type ListImagesResponse struct {
 	Images []DockerImageInfo `json:"images"`
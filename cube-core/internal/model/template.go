@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// SessionTemplate represents a named set of defaults for new sessions. Every change to a
+// template creates a new version; sessions record the version they were created from.
+type SessionTemplate struct {
+	Name        string    `json:"name"`
+	Version     int       `json:"version"` // assigned by the server
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"` // when this version was created
+	// Source is the file this version was loaded from, empty for versions created through the API
+	Source string `json:"source,omitempty"`
+
+	ImageName     string           `json:"image_name"`
+	PortMappings  []PortMapping    `json:"port_mappings,omitempty"`
+	Env           []EnvVar         `json:"env,omitempty"`
+	Resources     *ResourceLimits  `json:"resources,omitempty"`
+	Readiness     *ReadinessConfig `json:"readiness,omitempty"`
+	TTLSeconds    int              `json:"ttl_seconds,omitempty"`
+	Command       []string         `json:"command,omitempty"`
+	Entrypoint    []string         `json:"entrypoint,omitempty"`
+	WorkingDir    string           `json:"working_dir,omitempty"`
+	User          string           `json:"user,omitempty"`
+	RestartPolicy *RestartPolicy   `json:"restart_policy,omitempty"`
+}
+
+// TemplateResponse represents a response containing a single template
+type TemplateResponse struct {
+	Template SessionTemplate `json:"template"`
+}
+
+// ListTemplatesResponse represents the response for a list templates request
+type ListTemplatesResponse struct {
+	Templates []SessionTemplate `json:"templates"`
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// CloneSession creates a new session with the image, ports, environment, limits and mounts
+// of an existing one. The clone gets fresh host ports and, if the source has a session
+// volume, a fresh empty volume at the same target. With IncludeFilesystem the source
+// container is snapshotted first and the clone starts from that snapshot.
+func (ss *SessionService) CloneSession(ctx context.Context, sessionID string, clone *model.CloneSessionRequest) (*model.Session, error) {
+	ss.mu.Lock()
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		ss.mu.Unlock()
+		return nil, util.ErrNotFound
+	}
+	if session.ContainerID == "" || session.Status == model.SessionStatusExpired {
+		ss.mu.Unlock()
+		return nil, util.WrapError(util.ErrOperationNotValid, "session %s has no container to clone", sessionID)
+	}
+	if len(session.Services) > 0 {
+		ss.mu.Unlock()
+		return nil, util.WrapError(util.ErrOperationNotValid, "cannot clone stack session %s", sessionID)
+	}
+	source := *session
+	ss.mu.Unlock()
+
+	env, err := ss.cloneEnv(&source)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &model.CreateSessionRequest{
+		TTLSeconds: clone.TTLSeconds,
+		Resources:  source.Resources,
+		Env:        env,
+		Command:    source.Command,
+		Entrypoint: source.Entrypoint,
+		WorkingDir: source.WorkingDir,
+		User:       source.User,
+		Mounts:     source.Mounts,
+		Readiness:  source.Readiness,
+
+		InternalNetwork: source.InternalNetwork,
+		Egress:          source.Egress,
+	}
+	if source.RestartPolicy != nil {
+		policy := *source.RestartPolicy
+		req.RestartPolicy = &policy
+	}
+	if source.SessionVolume != nil {
+		req.SessionVolume = &model.SessionVolume{
+			Target: source.SessionVolume.Target,
+			Retain: source.SessionVolume.Retain,
+		}
+	}
+
+	// Publish the same container ports; the clone gets its own host ports
+	req.PortMappings = make([]model.PortMapping, len(source.Ports))
+	for i, p := range source.Ports {
+		req.PortMappings[i] = model.PortMapping{
+			ContainerPort: p.ContainerPort,
+			Protocol:      p.Protocol,
+			Description:   p.Description,
+			Scheme:        p.Scheme,
+		}
+	}
+
+	var snapshotID string
+	switch {
+	case clone.IncludeFilesystem:
+		snapshot, err := ss.CreateSnapshot(ctx, source.ID, &model.CreateSnapshotRequest{
+			Name:    fmt.Sprintf("clone of %s", source.ID),
+			Comment: fmt.Sprintf("filesystem of session %s for a clone", source.ID),
+		})
+		if err != nil {
+			return nil, err
+		}
+		snapshotID = snapshot.ID
+		req.SnapshotID = snapshot.ID
+		req.OwnsSnapshot = true
+	case source.SnapshotID != "":
+		// A snapshot owned by the source is shared with the clone, and deleted with the last of them
+		req.SnapshotID = source.SnapshotID
+		req.OwnsSnapshot = source.OwnsSnapshot
+	default:
+		req.ImageName = source.ImageName
+	}
+
+	cloned, err := ss.CreateSession(req)
+	if err != nil {
+		// Do not leave behind a snapshot nobody asked to keep
+		if snapshotID != "" {
+			if err := ss.DeleteSnapshot(snapshotID); err != nil {
+				ss.logger.Warn("Failed to delete snapshot %s of failed clone: %v", snapshotID, err)
+			}
+		}
+		return nil, err
+	}
+
+	ss.logger.Info("Cloned session %s into %s", source.ID, cloned.ID)
+	return cloned, nil
+}
+
+// cloneEnv returns a session's environment with the values of secret variables, which the
+// session record only holds redacted, read back from its container
+func (ss *SessionService) cloneEnv(session *model.Session) ([]model.EnvVar, error) {
+	env := make([]model.EnvVar, len(session.Env))
+	copy(env, session.Env)
+
+	hasSecrets := false
+	for _, e := range env {
+		if e.Secret {
+			hasSecrets = true
+			break
+		}
+	}
+	if !hasSecrets {
+		return env, nil
+	}
+
+	containerEnv, err := ss.dockerManager.ContainerEnv(session.ContainerID)
+	if err != nil {
+		return nil, util.WrapError(err, "failed to read the environment of session %s", session.ID)
+	}
+
+	values := make(map[string]string, len(containerEnv))
+	for _, kv := range containerEnv {
+		if name, value, found := strings.Cut(kv, "="); found {
+			values[name] = value
+		}
+	}
+
+	for i, e := range env {
+		if !e.Secret {
+			continue
+		}
+		value, found := values[e.Name]
+		if !found {
+			return nil, util.WrapError(util.ErrOperationNotValid, "secret %s of session %s is missing from its container", e.Name, session.ID)
+		}
+		env[i].Value = value
+	}
+	return env, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestCloneSharesOwnedSnapshot(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	source := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1})
+	first, err := ss.CloneSession(context.Background(), source.ID, &model.CloneSessionRequest{IncludeFilesystem: true})
+	if err != nil {
+		t.Fatalf("CloneSession: %v", err)
+	}
+	first = waitSettled(t, ss, first.ID)
+	second, err := ss.CloneSession(context.Background(), first.ID, &model.CloneSessionRequest{})
+	if err != nil {
+		t.Fatalf("CloneSession of the clone: %v", err)
+	}
+	second = waitSettled(t, ss, second.ID)
+
+	if second.SnapshotID != first.SnapshotID || !second.OwnsSnapshot {
+		t.Fatalf("second clone runs from %q (owned: %v), want the shared snapshot %s", second.SnapshotID, second.OwnsSnapshot, first.SnapshotID)
+	}
+
+	// The snapshot stays while the second clone still runs from it
+	if err := ss.DeleteSession(first.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := ss.GetSnapshot(first.SnapshotID); err != nil {
+		t.Fatalf("snapshot was deleted with the first clone: %v", err)
+	}
+
+	if err := ss.DeleteSession(second.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := ss.GetSnapshot(first.SnapshotID); err == nil {
+		t.Fatal("snapshot was kept after its last session was deleted")
+	}
+}
+
+func TestCloneEnv(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	source := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName: "nginx:latest",
+		Env: []model.EnvVar{
+			{Name: "MODE", Value: "dev"},
+			{Name: "API_TOKEN", Value: "s3cret", Secret: true},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		session *model.Session
+		want    []model.EnvVar
+		wantErr error
+	}{
+		{
+			name:    "no secrets",
+			session: &model.Session{ID: "plain", Env: []model.EnvVar{{Name: "MODE", Value: "dev"}}},
+			want:    []model.EnvVar{{Name: "MODE", Value: "dev"}},
+		},
+		{
+			name:    "secret read back from the container",
+			session: source,
+			want:    []model.EnvVar{{Name: "MODE", Value: "dev"}, {Name: "API_TOKEN", Value: "s3cret", Secret: true}},
+		},
+		{
+			name: "secret missing from the container",
+			session: &model.Session{
+				ID:          "drifted",
+				ContainerID: source.ContainerID,
+				Env:         []model.EnvVar{{Name: "DB_PASSWORD", Value: model.RedactedValue, Secret: true}},
+			},
+			wantErr: util.ErrOperationNotValid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := append([]model.EnvVar(nil), tt.session.Env...)
+			env, err := ss.cloneEnv(tt.session)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("cloneEnv = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(env, tt.want) {
+				t.Fatalf("cloneEnv = %+v, want %+v", env, tt.want)
+			}
+			// The session record keeps its redacted values
+			if !reflect.DeepEqual(tt.session.Env, before) {
+				t.Fatalf("session env changed to %+v", tt.session.Env)
+			}
+		})
+	}
+}
+
+func TestCloneSessionCopiesSettings(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("node:20")
+
+	source := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName:     "node:20",
+		PortMappings:  []model.PortMapping{{ContainerPort: 3000, Description: "app"}, {ContainerPort: 9229, Description: "debugger"}},
+		Env:           []model.EnvVar{{Name: "NODE_ENV", Value: "development"}},
+		Command:       []string{"npm", "run", "dev"},
+		WorkingDir:    "/app",
+		User:          "node",
+		RestartPolicy: &model.RestartPolicy{Name: "on-failure", MaxRetries: 2},
+	})
+	cloned, err := ss.CloneSession(context.Background(), source.ID, &model.CloneSessionRequest{})
+	if err != nil {
+		t.Fatalf("CloneSession: %v", err)
+	}
+	cloned = waitSettled(t, ss, cloned.ID)
+
+	if cloned.ID == source.ID || cloned.ContainerID == source.ContainerID {
+		t.Fatalf("clone shares the ID or container of its source")
+	}
+	if cloned.ImageName != "node:20" || cloned.SnapshotID != "" {
+		t.Errorf("clone runs %q from snapshot %q, want node:20", cloned.ImageName, cloned.SnapshotID)
+	}
+	if len(cloned.Ports) != len(source.Ports) {
+		t.Fatalf("clone has %d ports, want %d", len(cloned.Ports), len(source.Ports))
+	}
+	for i, p := range cloned.Ports {
+		if p.ContainerPort != source.Ports[i].ContainerPort || p.Description != source.Ports[i].Description {
+			t.Errorf("port %d = %d (%s), want %d (%s)", i, p.ContainerPort, p.Description, source.Ports[i].ContainerPort, source.Ports[i].Description)
+		}
+		if p.HostPort == source.Ports[i].HostPort {
+			t.Errorf("port %d reuses host port %d of the source", i, p.HostPort)
+		}
+	}
+	if !reflect.DeepEqual(cloned.Env, source.Env) || !reflect.DeepEqual(cloned.Command, source.Command) ||
+		cloned.WorkingDir != "/app" || cloned.User != "node" {
+		t.Errorf("clone env %+v, command %v, working dir %q, user %q differ from the source", cloned.Env, cloned.Command, cloned.WorkingDir, cloned.User)
+	}
+	if cloned.RestartPolicy == nil || *cloned.RestartPolicy != *source.RestartPolicy || cloned.RestartPolicy == source.RestartPolicy {
+		t.Errorf("clone restart policy = %+v, want a copy of %+v", cloned.RestartPolicy, source.RestartPolicy)
+	}
+}
+
+func TestCloneSessionRejections(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	source := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest"})
+	ss.sessions["provisioning"] = &model.Session{ID: "provisioning", Status: model.SessionStatusProvisioning}
+	ss.sessions["expired"] = &model.Session{ID: "expired", Status: model.SessionStatusExpired, ContainerID: "c1"}
+	ss.sessions["stack"] = &model.Session{ID: "stack", Status: model.SessionStatusRunning, ContainerID: "c2", Services: []model.ServiceContainer{{Name: "db"}}}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		req       model.CloneSessionRequest
+		wantErr   error
+	}{
+		{name: "unknown session", sessionID: "missing", wantErr: util.ErrNotFound},
+		{name: "no container yet", sessionID: "provisioning", wantErr: util.ErrOperationNotValid},
+		{name: "expired", sessionID: "expired", wantErr: util.ErrOperationNotValid},
+		{name: "stack", sessionID: "stack", wantErr: util.ErrOperationNotValid},
+		{name: "invalid ttl", sessionID: source.ID, req: model.CloneSessionRequest{TTLSeconds: -1, IncludeFilesystem: true}, wantErr: util.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ss.CloneSession(context.Background(), tt.sessionID, &tt.req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CloneSession = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The snapshot taken for the failed clone is not left behind
+	if snapshots, err := ss.ListSnapshots(); err != nil || len(snapshots) != 0 {
+		t.Fatalf("snapshots = %+v, %v; want none", snapshots, err)
+	}
+}
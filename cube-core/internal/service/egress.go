@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/egress"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+const (
+	// egressProxyAlias is the name session containers reach their egress proxy under
+	egressProxyAlias = "egress"
+	// egressSessionAlias is the name the egress proxy reaches a single-container session under
+	egressSessionAlias = "session"
+)
+
+// resolveEgress validates the egress policy of a request. Sessions with full network access
+// get no policy at all.
+func resolveEgress(req *model.CreateSessionRequest) (*model.EgressPolicy, error) {
+	policy := req.Egress
+	if policy == nil {
+		return nil, nil
+	}
+
+	switch policy.Mode {
+	case model.EgressModeFull:
+		if len(policy.Allow) > 0 {
+			return nil, util.WrapError(util.ErrInvalidRequest, "egress allow rules need the %s mode", model.EgressModeAllowlist)
+		}
+		return nil, nil
+	case model.EgressModeNone:
+		if len(policy.Allow) > 0 {
+			return nil, util.WrapError(util.ErrInvalidRequest, "egress allow rules need the %s mode", model.EgressModeAllowlist)
+		}
+	case model.EgressModeAllowlist:
+		if len(policy.Allow) == 0 {
+			return nil, util.WrapError(util.ErrInvalidRequest, "egress allowlist has no rules")
+		}
+	default:
+		return nil, util.WrapError(util.ErrInvalidRequest, "egress mode must be %s, %s or %s",
+			model.EgressModeNone, model.EgressModeFull, model.EgressModeAllowlist)
+	}
+
+	if req.InternalNetwork {
+		return nil, util.WrapError(util.ErrInvalidRequest, "internal_network already blocks all egress")
+	}
+	if _, err := egress.NewPolicy(egressRules(policy)); err != nil {
+		return nil, util.WrapError(util.ErrInvalidRequest, "%v", err)
+	}
+
+	// Published ports are forwarded by the egress proxy, which only handles TCP
+	for _, mapping := range req.PortMappings {
+		if mapping.Protocol != "" && mapping.Protocol != "tcp" {
+			return nil, util.WrapError(util.ErrInvalidRequest, "sessions with an egress policy can only publish tcp ports")
+		}
+	}
+	if req.Stack != nil {
+		for name, service := range req.Stack.Services {
+			if name == egressProxyAlias {
+				return nil, util.WrapError(util.ErrInvalidRequest, "service name %s is reserved for the egress proxy", name)
+			}
+			for _, mapping := range service.Ports {
+				if mapping.Protocol != "" && mapping.Protocol != "tcp" {
+					return nil, util.WrapError(util.ErrInvalidRequest, "sessions with an egress policy can only publish tcp ports")
+				}
+			}
+		}
+	}
+
+	return &model.EgressPolicy{Mode: policy.Mode, Allow: policy.Allow}, nil
+}
+
+// egressRules converts a policy to the rules the proxy enforces; "none" has no rules
+func egressRules(policy *model.EgressPolicy) []egress.Rule {
+	rules := make([]egress.Rule, len(policy.Allow))
+	for i, rule := range policy.Allow {
+		rules[i] = egress.Rule{CIDR: rule.CIDR, Ports: rule.Ports}
+	}
+	return rules
+}
+
+// egressClientEnv points a session's containers at its egress proxy. Other containers of the
+// session are reached directly.
+func egressClientEnv(services []model.ServiceContainer) []model.EnvVar {
+	proxyURL := fmt.Sprintf("http://%s:%d", egressProxyAlias, egress.ProxyPort)
+
+	noProxy := []string{"localhost", "127.0.0.1", egressSessionAlias}
+	for _, service := range services {
+		noProxy = append(noProxy, service.Name)
+	}
+
+	env := []model.EnvVar{}
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		env = append(env, model.EnvVar{Name: name, Value: proxyURL})
+	}
+	for _, name := range []string{"NO_PROXY", "no_proxy"} {
+		env = append(env, model.EnvVar{Name: name, Value: strings.Join(noProxy, ",")})
+	}
+	return env
+}
+
+// withEgressEnv prepends the egress proxy settings to a container's environment, so the
+// session's own variables still take precedence
+func withEgressEnv(session *model.Session, env []model.EnvVar) []model.EnvVar {
+	if session.Egress == nil {
+		return env
+	}
+	return append(egressClientEnv(session.Services), env...)
+}
+
+// startEgressProxy creates the egress proxy of a session, which publishes the session's ports
+// and forwards them into the session's internal network. The network must already exist.
+func (ss *SessionService) startEgressProxy(ctx context.Context, session *model.Session, ports []model.Port) (string, error) {
+	if err := ss.ensureImage(ctx, ss.cfg.EgressProxyImage, nil); err != nil {
+		return "", util.WrapError(err, "egress proxy image %s is not available", ss.cfg.EgressProxyImage)
+	}
+
+	// The proxies of all sessions share one network with a route out
+	networkLabels := map[string]string{docker.LabelManaged: "true"}
+	if _, err := ss.dockerManager.CreateNetwork(ss.cfg.EgressNetwork, false, networkLabels); err != nil && !docker.IsConflict(err) {
+		return "", util.WrapError(err, "failed to create egress network")
+	}
+
+	forwards := make([]egress.Forward, len(ports))
+	mappings := make([]docker.PortMapping, len(ports))
+	for i, p := range ports {
+		target := egressSessionAlias
+		if p.Service != "" {
+			target = p.Service
+		}
+		forwards[i] = egress.Forward{
+			ListenPort: egress.ForwardBasePort + i,
+			Target:     fmt.Sprintf("%s:%d", target, p.ContainerPort),
+		}
+		mappings[i] = docker.PortMapping{
+			HostPort:      p.HostPort,
+			ContainerPort: egress.ForwardBasePort + i,
+			Protocol:      "tcp",
+		}
+	}
+
+	env, err := egress.EncodeEnv(egressRules(session.Egress), forwards)
+	if err != nil {
+		return "", err
+	}
+
+	ss.logger.Info("Creating egress proxy for session %s with %s policy", session.ID, session.Egress.Mode)
+	proxyID, err := ss.dockerManager.CreateContainer(docker.ContainerOptions{
+		Image:        ss.cfg.EgressProxyImage,
+		PortMappings: mappings,
+		Labels: map[string]string{
+			docker.LabelManaged:     "true",
+			docker.LabelSessionID:   session.ID,
+			docker.LabelEgressProxy: "true",
+		},
+		Env: env,
+		Resources: docker.ResourceLimits{
+			NanoCPUs:  5e8,
+			Memory:    64 << 20,
+			PidsLimit: 128,
+		},
+		RestartPolicy: "unless-stopped",
+		Network:       ss.cfg.EgressNetwork,
+	})
+	if err != nil {
+		ss.logger.Error("Failed to create egress proxy: %v", err)
+		return "", util.WrapError(err, "failed to create egress proxy")
+	}
+
+	if err := ss.dockerManager.ConnectNetwork(sessionNetworkName(session.ID), proxyID, []string{egressProxyAlias}); err != nil {
+		ss.logger.Error("Failed to connect egress proxy: %v", err)
+		if err := ss.dockerManager.RemoveContainer(proxyID); err != nil && !docker.IsNotFound(err) {
+			ss.logger.Error("Failed to remove container %s: %v", proxyID, err)
+		}
+		return "", util.WrapError(err, "failed to connect egress proxy")
+	}
+	return proxyID, nil
+}
+
+// egressMetrics asks a session's egress proxy how many connections it allowed and denied
+func (ss *SessionService) egressMetrics(ctx context.Context, session *model.Session) (*model.EgressMetrics, error) {
+	result, err := ss.dockerManager.RunCommand(ctx, session.EgressProxyID, docker.ExecOptions{
+		Cmd: []string{"/egress-proxy", "stats"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("egress proxy stats exited with status %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+
+	var stats egress.Stats
+	if err := json.Unmarshal([]byte(result.Stdout), &stats); err != nil {
+		return nil, fmt.Errorf("failed to decode egress proxy stats: %v", err)
+	}
+	return &model.EgressMetrics{
+		Allowed:    stats.Allowed,
+		Denied:     stats.Denied,
+		LastDenied: stats.LastDenied,
+	}, nil
+}
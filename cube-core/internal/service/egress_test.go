@@ -0,0 +1,293 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/docker/dockertest"
+	"github.com/yourusername/session-manager/pkg/egress"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestEgressSessionsShareEgressNetwork(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	fake.AddImage(ss.cfg.EgressProxyImage)
+
+	// The egress network is created by the first session and reused by the second
+	var sessions []*model.Session
+	for i := 0; i < 2; i++ {
+		session := createTestSession(t, ss, &model.CreateSessionRequest{
+			ImageName:    "nginx:latest",
+			PortMappings: []model.PortMapping{{ContainerPort: 80}},
+			Egress:       &model.EgressPolicy{Mode: model.EgressModeNone},
+		})
+		if session.Status != model.SessionStatusRunning {
+			t.Fatalf("session %d is %s: %s", i+1, session.Status, session.StatusReason)
+		}
+		if session.EgressProxyID == "" {
+			t.Fatalf("session %d has no egress proxy", i+1)
+		}
+		sessions = append(sessions, session)
+	}
+
+	want := map[string]bool{
+		ss.cfg.EgressNetwork:               true,
+		sessionNetworkName(sessions[0].ID): true,
+		sessionNetworkName(sessions[1].ID): true,
+	}
+	networks := fake.Networks()
+	if len(networks) != len(want) {
+		t.Fatalf("networks = %v, want %d", networks, len(want))
+	}
+	for _, name := range networks {
+		if !want[name] {
+			t.Errorf("unexpected network %s", name)
+		}
+	}
+}
+
+func TestResolveEgress(t *testing.T) {
+	allow := []model.EgressRule{{CIDR: "10.0.0.0/8", Ports: []int{443}}, {CIDR: "1.1.1.1"}}
+	tests := []struct {
+		name    string
+		req     model.CreateSessionRequest
+		want    *model.EgressPolicy
+		wantErr bool
+	}{
+		{name: "no policy", req: model.CreateSessionRequest{ImageName: "nginx:latest"}},
+		{name: "full access", req: model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: model.EgressModeFull}}},
+		{name: "none", req: model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: model.EgressModeNone}}, want: &model.EgressPolicy{Mode: model.EgressModeNone}},
+		{
+			name: "allowlist",
+			req:  model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: model.EgressModeAllowlist, Allow: allow}},
+			want: &model.EgressPolicy{Mode: model.EgressModeAllowlist, Allow: allow},
+		},
+		{
+			name: "tcp ports",
+			req: model.CreateSessionRequest{
+				PortMappings: []model.PortMapping{{ContainerPort: 80}, {ContainerPort: 443, Protocol: "tcp"}},
+				Egress:       &model.EgressPolicy{Mode: model.EgressModeNone},
+			},
+			want: &model.EgressPolicy{Mode: model.EgressModeNone},
+		},
+		{name: "full with rules", req: model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: model.EgressModeFull, Allow: allow}}, wantErr: true},
+		{name: "none with rules", req: model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: model.EgressModeNone, Allow: allow}}, wantErr: true},
+		{name: "empty allowlist", req: model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: model.EgressModeAllowlist}}, wantErr: true},
+		{name: "unknown mode", req: model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: "some"}}, wantErr: true},
+		{name: "no mode", req: model.CreateSessionRequest{Egress: &model.EgressPolicy{}}, wantErr: true},
+		{
+			name:    "internal network",
+			req:     model.CreateSessionRequest{InternalNetwork: true, Egress: &model.EgressPolicy{Mode: model.EgressModeNone}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid cidr",
+			req:     model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: model.EgressModeAllowlist, Allow: []model.EgressRule{{CIDR: "10.0.0.0/33"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid port",
+			req:     model.CreateSessionRequest{Egress: &model.EgressPolicy{Mode: model.EgressModeAllowlist, Allow: []model.EgressRule{{CIDR: "10.0.0.0/8", Ports: []int{70000}}}}},
+			wantErr: true,
+		},
+		{
+			name: "udp port",
+			req: model.CreateSessionRequest{
+				PortMappings: []model.PortMapping{{ContainerPort: 53, Protocol: "udp"}},
+				Egress:       &model.EgressPolicy{Mode: model.EgressModeNone},
+			},
+			wantErr: true,
+		},
+		{
+			name: "udp stack port",
+			req: model.CreateSessionRequest{
+				Stack:  stackService("dns", model.StackService{ImageName: "coredns:latest", Ports: []model.PortMapping{{ContainerPort: 53, Protocol: "udp"}}}),
+				Egress: &model.EgressPolicy{Mode: model.EgressModeNone},
+			},
+			wantErr: true,
+		},
+		{
+			name: "service named egress",
+			req: model.CreateSessionRequest{
+				Stack:  stackService(egressProxyAlias, model.StackService{ImageName: "nginx:latest"}),
+				Egress: &model.EgressPolicy{Mode: model.EgressModeNone},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveEgress(&tt.req)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("resolveEgress = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("resolveEgress = %v, want ErrInvalidRequest", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("resolveEgress = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// stackService returns a stack of a single service
+func stackService(name string, service model.StackService) *model.StackSpec {
+	return &model.StackSpec{Services: map[string]model.StackService{name: service}}
+}
+
+func TestEgressRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy model.EgressPolicy
+		want   []egress.Rule
+	}{
+		{name: "none", policy: model.EgressPolicy{Mode: model.EgressModeNone}, want: []egress.Rule{}},
+		{
+			name: "allowlist",
+			policy: model.EgressPolicy{Mode: model.EgressModeAllowlist, Allow: []model.EgressRule{
+				{CIDR: "10.0.0.0/8", Ports: []int{80, 443}},
+				{CIDR: "1.1.1.1"},
+			}},
+			want: []egress.Rule{{CIDR: "10.0.0.0/8", Ports: []int{80, 443}}, {CIDR: "1.1.1.1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := egressRules(&tt.policy); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("egressRules = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithEgressEnv(t *testing.T) {
+	own := []model.EnvVar{{Name: "HTTP_PROXY", Value: "http://mine:8080"}}
+	tests := []struct {
+		name    string
+		session model.Session
+		want    map[string]string
+	}{
+		{
+			name:    "no policy",
+			session: model.Session{},
+			want:    map[string]string{"HTTP_PROXY": "http://mine:8080"},
+		},
+		{
+			name:    "single container",
+			session: model.Session{Egress: &model.EgressPolicy{Mode: model.EgressModeNone}},
+			want: map[string]string{
+				"HTTP_PROXY":  "http://mine:8080",
+				"HTTPS_PROXY": "http://egress:3128",
+				"http_proxy":  "http://egress:3128",
+				"https_proxy": "http://egress:3128",
+				"NO_PROXY":    "localhost,127.0.0.1,session",
+				"no_proxy":    "localhost,127.0.0.1,session",
+			},
+		},
+		{
+			name: "stack",
+			session: model.Session{
+				Egress:   &model.EgressPolicy{Mode: model.EgressModeNone},
+				Services: []model.ServiceContainer{{Name: "db"}, {Name: "web"}},
+			},
+			want: map[string]string{
+				"HTTP_PROXY":  "http://mine:8080",
+				"HTTPS_PROXY": "http://egress:3128",
+				"http_proxy":  "http://egress:3128",
+				"https_proxy": "http://egress:3128",
+				"NO_PROXY":    "localhost,127.0.0.1,session,db,web",
+				"no_proxy":    "localhost,127.0.0.1,session,db,web",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The session's own variables come last, so Docker lets them win
+			if got := envMap(dockerEnv(withEgressEnv(&tt.session, own))); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("withEgressEnv = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEgressProxyContainer(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	fake.AddImage(ss.cfg.EgressProxyImage)
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName:    "nginx:latest",
+		PortMappings: []model.PortMapping{{ContainerPort: 80}, {ContainerPort: 443}},
+		Egress: &model.EgressPolicy{
+			Mode:  model.EgressModeAllowlist,
+			Allow: []model.EgressRule{{CIDR: "10.0.0.0/8", Ports: []int{443}}},
+		},
+	})
+	if session.Status != model.SessionStatusRunning {
+		t.Fatalf("session is %s: %s", session.Status, session.StatusReason)
+	}
+
+	containers := make(map[string]dockertest.ContainerInfo)
+	for _, c := range fake.Containers() {
+		containers[c.ID] = c
+	}
+	proxy, exists := containers[session.EgressProxyID]
+	if !exists {
+		t.Fatalf("egress proxy %s not found", session.EgressProxyID)
+	}
+	if proxy.Image != ss.cfg.EgressProxyImage || proxy.Labels[docker.LabelEgressProxy] != "true" || proxy.Labels[docker.LabelSessionID] != session.ID {
+		t.Errorf("egress proxy runs %s with labels %v", proxy.Image, proxy.Labels)
+	}
+	wantNetworks := []string{ss.cfg.EgressNetwork, sessionNetworkName(session.ID)}
+	sort.Strings(wantNetworks)
+	if !reflect.DeepEqual(proxy.Networks, wantNetworks) {
+		t.Errorf("egress proxy networks = %v, want %v", proxy.Networks, wantNetworks)
+	}
+
+	// The proxy forwards each published port to the session container
+	env := envMap(proxy.Env)
+	var forwards []egress.Forward
+	if err := json.Unmarshal([]byte(env[egress.EnvForwards]), &forwards); err != nil {
+		t.Fatalf("decode forwards: %v", err)
+	}
+	wantForwards := []egress.Forward{
+		{ListenPort: egress.ForwardBasePort, Target: "session:80"},
+		{ListenPort: egress.ForwardBasePort + 1, Target: "session:443"},
+	}
+	if !reflect.DeepEqual(forwards, wantForwards) {
+		t.Errorf("forwards = %+v, want %+v", forwards, wantForwards)
+	}
+	var rules []egress.Rule
+	if err := json.Unmarshal([]byte(env[egress.EnvRules]), &rules); err != nil {
+		t.Fatalf("decode rules: %v", err)
+	}
+	if want := []egress.Rule{{CIDR: "10.0.0.0/8", Ports: []int{443}}}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %+v, want %+v", rules, want)
+	}
+
+	// The session container only sees its internal network and goes out through the proxy
+	c := containers[session.ContainerID]
+	if len(c.Networks) != 1 || c.Networks[0] != sessionNetworkName(session.ID) {
+		t.Errorf("session container is on %v, want the session network", c.Networks)
+	}
+	if got := envMap(c.Env)["HTTPS_PROXY"]; got != "http://egress:3128" {
+		t.Errorf("session container HTTPS_PROXY = %q", got)
+	}
+	if len(session.Ports) != 2 || session.Ports[0].HostPort == 0 || session.Ports[1].HostPort == 0 {
+		t.Errorf("session ports = %+v, want two published ports", session.Ports)
+	}
+
+	if err := ss.DeleteSession(session.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if remaining := fake.Containers(); len(remaining) != 0 {
+		t.Fatalf("containers left after delete: %+v", remaining)
+	}
+}
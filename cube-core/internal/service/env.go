@@ -0,0 +1,72 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// sensitiveEnvMarkers are name fragments that mark an environment variable as secret
+// even when the client did not flag it
+var sensitiveEnvMarkers = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "API_KEY", "PRIVATE_KEY", "CREDENTIAL"}
+
+// validateEnv checks that environment variable names are usable by Docker
+func validateEnv(env []model.EnvVar) error {
+	seen := make(map[string]bool, len(env))
+	for _, e := range env {
+		if e.Name == "" || strings.ContainsAny(e.Name, "= \t\n") {
+			return util.WrapError(util.ErrInvalidRequest, "invalid environment variable name %q", e.Name)
+		}
+		if seen[e.Name] {
+			return util.WrapError(util.ErrInvalidRequest, "duplicate environment variable %q", e.Name)
+		}
+		seen[e.Name] = true
+	}
+	return nil
+}
+
+// dockerEnv converts environment variables to Docker's KEY=value form
+func dockerEnv(env []model.EnvVar) []string {
+	if len(env) == 0 {
+		return nil
+	}
+
+	result := make([]string, len(env))
+	for i, e := range env {
+		result[i] = e.Name + "=" + e.Value
+	}
+	return result
+}
+
+// isSecretEnv reports whether an environment variable's value must not be exposed
+func isSecretEnv(e model.EnvVar) bool {
+	if e.Secret {
+		return true
+	}
+
+	name := strings.ToUpper(e.Name)
+	for _, marker := range sensitiveEnvMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactEnv returns a copy of env with secret values replaced by model.RedactedValue
+func redactEnv(env []model.EnvVar) []model.EnvVar {
+	if len(env) == 0 {
+		return nil
+	}
+
+	result := make([]model.EnvVar, len(env))
+	for i, e := range env {
+		result[i] = e
+		if isSecretEnv(e) {
+			result[i].Value = model.RedactedValue
+			result[i].Secret = true
+		}
+	}
+	return result
+}
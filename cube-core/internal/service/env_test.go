@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestValidateEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     []model.EnvVar
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "valid", env: []model.EnvVar{{Name: "PORT", Value: "8080"}, {Name: "EMPTY"}, {Name: "lower_case", Value: "a=b"}}},
+		{name: "empty name", env: []model.EnvVar{{Value: "x"}}, wantErr: true},
+		{name: "equals sign in name", env: []model.EnvVar{{Name: "A=B"}}, wantErr: true},
+		{name: "space in name", env: []model.EnvVar{{Name: "MY VAR"}}, wantErr: true},
+		{name: "newline in name", env: []model.EnvVar{{Name: "MY\nVAR"}}, wantErr: true},
+		{name: "duplicate", env: []model.EnvVar{{Name: "PORT", Value: "1"}, {Name: "PORT", Value: "2"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEnv(tt.env)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateEnv = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("validateEnv = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestIsSecretEnv(t *testing.T) {
+	tests := []struct {
+		env  model.EnvVar
+		want bool
+	}{
+		{env: model.EnvVar{Name: "PORT"}, want: false},
+		{env: model.EnvVar{Name: "PORT", Secret: true}, want: true},
+		{env: model.EnvVar{Name: "DB_PASSWORD"}, want: true},
+		{env: model.EnvVar{Name: "github_token"}, want: true},
+		{env: model.EnvVar{Name: "STRIPE_API_KEY"}, want: true},
+		{env: model.EnvVar{Name: "AWS_SECRET_ACCESS_KEY"}, want: true},
+		{env: model.EnvVar{Name: "SSH_PRIVATE_KEY"}, want: true},
+		{env: model.EnvVar{Name: "GOOGLE_APPLICATION_CREDENTIALS"}, want: true},
+		{env: model.EnvVar{Name: "TOKENIZER_MODEL"}, want: true},
+		{env: model.EnvVar{Name: "KEYBOARD_LAYOUT"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env.Name, func(t *testing.T) {
+			if got := isSecretEnv(tt.env); got != tt.want {
+				t.Fatalf("isSecretEnv(%+v) = %v, want %v", tt.env, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	env := []model.EnvVar{
+		{Name: "PORT", Value: "8080"},
+		{Name: "DB_PASSWORD", Value: "hunter2"},
+		{Name: "LICENSE", Value: "abc", Secret: true},
+	}
+	want := []model.EnvVar{
+		{Name: "PORT", Value: "8080"},
+		{Name: "DB_PASSWORD", Value: model.RedactedValue, Secret: true},
+		{Name: "LICENSE", Value: model.RedactedValue, Secret: true},
+	}
+
+	if got := redactEnv(env); !reflect.DeepEqual(got, want) {
+		t.Fatalf("redactEnv = %+v, want %+v", got, want)
+	}
+	if env[1].Value != "hunter2" {
+		t.Fatal("redactEnv changed its input")
+	}
+	if got := redactEnv(nil); got != nil {
+		t.Fatalf("redactEnv(nil) = %+v, want nil", got)
+	}
+	if got := dockerEnv(env); !reflect.DeepEqual(got, []string{"PORT=8080", "DB_PASSWORD=hunter2", "LICENSE=abc"}) {
+		t.Fatalf("dockerEnv = %v", got)
+	}
+}
+
+func TestCreateSessionWithEnvAndOverrides(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("python:3.12")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName:  "python:3.12",
+		Env:        []model.EnvVar{{Name: "MODE", Value: "dev"}, {Name: "API_TOKEN", Value: "s3cret"}},
+		Command:    []string{"-m", "http.server"},
+		Entrypoint: []string{"python3"},
+		WorkingDir: "/srv",
+		User:       "1000:1000",
+	})
+
+	// The container gets the real values; the session only shows redacted ones
+	container := fake.Containers()[0]
+	if env := envMap(container.Env); env["MODE"] != "dev" || env["API_TOKEN"] != "s3cret" {
+		t.Fatalf("container env = %v", container.Env)
+	}
+	if !reflect.DeepEqual(container.Cmd, []string{"-m", "http.server"}) || !reflect.DeepEqual(container.Entrypoint, []string{"python3"}) ||
+		container.WorkingDir != "/srv" || container.User != "1000:1000" {
+		t.Fatalf("container = %+v, want the overrides", container)
+	}
+
+	wantEnv := []model.EnvVar{{Name: "MODE", Value: "dev"}, {Name: "API_TOKEN", Value: model.RedactedValue, Secret: true}}
+	if !reflect.DeepEqual(session.Env, wantEnv) {
+		t.Fatalf("session env = %+v, want %+v", session.Env, wantEnv)
+	}
+	if !reflect.DeepEqual(session.Command, []string{"-m", "http.server"}) || session.WorkingDir != "/srv" || session.User != "1000:1000" {
+		t.Fatalf("session = %+v, want the overrides", session)
+	}
+
+	_, err := ss.CreateSession(&model.CreateSessionRequest{ImageName: "python:3.12", Env: []model.EnvVar{{Name: "A B"}}})
+	if !errors.Is(err, util.ErrInvalidRequest) {
+		t.Fatalf("CreateSession with an invalid env name = %v, want ErrInvalidRequest", err)
+	}
+}
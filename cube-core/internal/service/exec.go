@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// Limits for non-interactive commands; the maximum stays below the request timeout
+const (
+	defaultExecTimeout = 30 * time.Second
+	maxExecTimeout     = 55 * time.Second
+)
+
+// ExecCommand runs a command inside a session's container and returns its exit code and output
+func (ss *SessionService) ExecCommand(ctx context.Context, sessionID string, req *model.ExecRequest) (*model.ExecResponse, error) {
+	containerID, err := ss.execContainer(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateExec(req); err != nil {
+		return nil, err
+	}
+	if req.TimeoutSeconds < 0 {
+		return nil, util.WrapError(util.ErrInvalidRequest, "timeout_seconds must not be negative")
+	}
+
+	timeout := defaultExecTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+	if timeout > maxExecTimeout {
+		timeout = maxExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ss.logger.Info("Running %v in session %s", req.Command, sessionID)
+	result, err := ss.dockerManager.RunCommand(ctx, containerID, dockerExecOptions(req))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, util.WrapError(util.ErrUnavailable, "command did not finish within %s", timeout)
+		}
+		ss.logger.Error("Failed to run command in session %s: %v", sessionID, err)
+		return nil, util.WrapError(err, "failed to run command")
+	}
+
+	return &model.ExecResponse{
+		ExitCode: result.ExitCode,
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+	}, nil
+}
+
+// StartTerminal starts an interactive command with a TTY of rows x cols inside a session's
+// container. The caller must close the returned exec, which ends the command.
+func (ss *SessionService) StartTerminal(ctx context.Context, sessionID string, req *model.ExecRequest, rows, cols uint) (*docker.InteractiveExec, error) {
+	containerID, err := ss.execContainer(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateExec(req); err != nil {
+		return nil, err
+	}
+
+	ss.logger.Info("Opening terminal running %v in session %s", req.Command, sessionID)
+	exec, err := ss.dockerManager.StartInteractiveExec(ctx, containerID, dockerExecOptions(req), rows, cols)
+	if err != nil {
+		ss.logger.Error("Failed to open terminal in session %s: %v", sessionID, err)
+		return nil, util.WrapError(err, "failed to start command")
+	}
+	return exec, nil
+}
+
+// execContainer returns the container of a session that commands can be run in
+func (ss *SessionService) execContainer(sessionID string) (string, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		return "", util.ErrNotFound
+	}
+
+	switch session.Status {
+	case model.SessionStatusRunning, model.SessionStatusReady, model.SessionStatusStarting, model.SessionStatusFailed:
+		return session.ContainerID, nil
+	default:
+		return "", util.WrapError(util.ErrOperationNotValid, "cannot run commands in session in status %s", session.Status)
+	}
+}
+
+// validateExec checks the command and environment of an exec request
+func validateExec(req *model.ExecRequest) error {
+	if len(req.Command) == 0 {
+		return util.WrapError(util.ErrInvalidRequest, "command is required")
+	}
+	return validateEnv(req.Env)
+}
+
+// dockerExecOptions converts an exec request to Docker exec options
+func dockerExecOptions(req *model.ExecRequest) docker.ExecOptions {
+	return docker.ExecOptions{
+		Cmd:        req.Command,
+		Env:        dockerEnv(req.Env),
+		User:       req.User,
+		WorkingDir: req.WorkingDir,
+	}
+}
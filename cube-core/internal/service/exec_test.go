@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestValidateExec(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     model.ExecRequest
+		wantErr bool
+	}{
+		{name: "command", req: model.ExecRequest{Command: []string{"ls", "-la"}}},
+		{name: "command with env", req: model.ExecRequest{Command: []string{"env"}, Env: []model.EnvVar{{Name: "DEBUG", Value: "1"}}}},
+		{name: "no command", req: model.ExecRequest{}, wantErr: true},
+		{name: "invalid env name", req: model.ExecRequest{Command: []string{"env"}, Env: []model.EnvVar{{Name: "A=B"}}}, wantErr: true},
+		{name: "duplicate env", req: model.ExecRequest{Command: []string{"env"}, Env: []model.EnvVar{{Name: "A"}, {Name: "A"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExec(&tt.req)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateExec = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("validateExec = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestDockerExecOptions(t *testing.T) {
+	req := &model.ExecRequest{
+		Command:        []string{"psql", "-c", "select 1"},
+		Env:            []model.EnvVar{{Name: "PGPASSWORD", Value: "secret", Secret: true}},
+		User:           "postgres",
+		WorkingDir:     "/tmp",
+		TimeoutSeconds: 10,
+	}
+	want := docker.ExecOptions{
+		Cmd:        []string{"psql", "-c", "select 1"},
+		Env:        []string{"PGPASSWORD=secret"},
+		User:       "postgres",
+		WorkingDir: "/tmp",
+	}
+	if got := dockerExecOptions(req); !reflect.DeepEqual(got, want) {
+		t.Fatalf("dockerExecOptions = %+v, want %+v", got, want)
+	}
+}
+
+func TestExecContainer(t *testing.T) {
+	tests := []struct {
+		status  string
+		wantErr error
+	}{
+		{status: model.SessionStatusRunning},
+		{status: model.SessionStatusReady},
+		{status: model.SessionStatusStarting},
+		{status: model.SessionStatusFailed},
+		{status: model.SessionStatusProvisioning, wantErr: util.ErrOperationNotValid},
+		{status: model.SessionStatusStopped, wantErr: util.ErrOperationNotValid},
+		{status: model.SessionStatusPaused, wantErr: util.ErrOperationNotValid},
+		{status: model.SessionStatusExited, wantErr: util.ErrOperationNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			_, ss := newTestService(t, nil)
+			ss.sessions["s1"] = &model.Session{ID: "s1", Status: tt.status, ContainerID: "c1"}
+
+			containerID, err := ss.execContainer("s1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("execContainer = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && containerID != "c1" {
+				t.Fatalf("execContainer = %q, want c1", containerID)
+			}
+		})
+	}
+
+	_, ss := newTestService(t, nil)
+	if _, err := ss.execContainer("missing"); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("execContainer of unknown session = %v, want ErrNotFound", err)
+	}
+}
+
+func TestExecCommandRejections(t *testing.T) {
+	_, ss := newTestService(t, nil)
+	ss.sessions["s1"] = &model.Session{ID: "s1", Status: model.SessionStatusRunning, ContainerID: "c1"}
+	ss.sessions["stopped"] = &model.Session{ID: "stopped", Status: model.SessionStatusStopped, ContainerID: "c2"}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		req       model.ExecRequest
+		wantErr   error
+	}{
+		{name: "unknown session", sessionID: "missing", req: model.ExecRequest{Command: []string{"true"}}, wantErr: util.ErrNotFound},
+		{name: "stopped session", sessionID: "stopped", req: model.ExecRequest{Command: []string{"true"}}, wantErr: util.ErrOperationNotValid},
+		{name: "no command", sessionID: "s1", wantErr: util.ErrInvalidRequest},
+		{name: "negative timeout", sessionID: "s1", req: model.ExecRequest{Command: []string{"true"}, TimeoutSeconds: -1}, wantErr: util.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ss.ExecCommand(context.Background(), tt.sessionID, &tt.req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExecCommand = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
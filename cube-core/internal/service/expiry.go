@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// operationExpire marks a session whose containers are being torn down after it expired
+const operationExpire = "expire"
+
+// resolveExpiry works out when a new session should expire based on the request and server limits
+func (ss *SessionService) resolveExpiry(req *model.CreateSessionRequest) (*time.Time, error) {
+	if req.TTLSeconds < 0 {
+		return nil, util.WrapError(util.ErrInvalidRequest, "ttl_seconds must not be negative")
+	}
+	if req.TTLSeconds > 0 && req.ExpiresAt != nil {
+		return nil, util.WrapError(util.ErrInvalidRequest, "ttl_seconds and expires_at are mutually exclusive")
+	}
+
+	now := time.Now()
+	var expiresAt time.Time
+	switch {
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return nil, util.WrapError(util.ErrInvalidRequest, "expires_at must be in the future")
+		}
+		expiresAt = *req.ExpiresAt
+	case req.TTLSeconds > 0:
+		expiresAt = now.Add(time.Duration(req.TTLSeconds) * time.Second)
+	case ss.cfg.DefaultSessionTTL > 0:
+		expiresAt = now.Add(ss.cfg.DefaultSessionTTL)
+	default:
+		return nil, nil
+	}
+
+	if err := ss.checkMaxTTL(now, expiresAt); err != nil {
+		return nil, err
+	}
+	return &expiresAt, nil
+}
+
+// checkMaxTTL rejects expiry times further away than the configured maximum TTL
+func (ss *SessionService) checkMaxTTL(now, expiresAt time.Time) error {
+	if ss.cfg.MaxSessionTTL > 0 && expiresAt.Sub(now) > ss.cfg.MaxSessionTTL {
+		return util.WrapError(util.ErrInvalidRequest, "session lifetime exceeds the maximum of %s", ss.cfg.MaxSessionTTL)
+	}
+	return nil
+}
+
+// ExtendSession pushes back the expiry of a session by ttlSeconds, counting from the
+// current expiry or from now if the session has no expiry yet. Docker labels cannot be
+// changed, so the extension only survives a restart through the session store.
+func (ss *SessionService) ExtendSession(sessionID string, ttlSeconds int) (*model.Session, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ttlSeconds <= 0 {
+		return nil, util.WrapError(util.ErrInvalidRequest, "ttl_seconds must be positive")
+	}
+
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		return nil, util.ErrNotFound
+	}
+
+	if session.Status == model.SessionStatusExpired || ss.transitions[sessionID] == operationExpire {
+		return nil, util.WrapError(util.ErrOperationNotValid, "session %s has already expired", sessionID)
+	}
+
+	now := time.Now()
+	base := now
+	if session.ExpiresAt != nil && session.ExpiresAt.After(now) {
+		base = *session.ExpiresAt
+	}
+
+	expiresAt := base.Add(time.Duration(ttlSeconds) * time.Second)
+	if err := ss.checkMaxTTL(now, expiresAt); err != nil {
+		return nil, err
+	}
+
+	previous := session.ExpiresAt
+	session.ExpiresAt = &expiresAt
+	if err := ss.persistSession(session); err != nil {
+		session.ExpiresAt = previous
+		return nil, err
+	}
+
+	ss.logger.Info("Extended session %s until %s", sessionID, expiresAt.Format(time.RFC3339))
+	if ss.cfg.SessionStorePath == "" {
+		ss.logger.Warn("Session %s will return to its original expiry if the server restarts, as no session store is configured", sessionID)
+	}
+	result := *session
+	return &result, nil
+}
+
+// StartReaper periodically tears down expired sessions until ctx is cancelled
+func (ss *SessionService) StartReaper(ctx context.Context) {
+	interval := ss.cfg.ReaperInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ss.reapExpiredSessions()
+			}
+		}
+	}()
+}
+
+// reapExpiredSessions tears down sessions past their expiry and forgets expired
+// sessions once the retention period has passed. The containers are torn down without
+// holding ss.mu, with the sessions marked as busy expiring.
+func (ss *SessionService) reapExpiredSessions() {
+	ss.mu.Lock()
+	now := time.Now()
+	var expiring []model.Session
+	for id, session := range ss.sessions {
+		// Provisioning sessions are reaped once their worker is done with them
+		if session.ExpiresAt == nil || session.ExpiresAt.After(now) || session.Status == model.SessionStatusProvisioning {
+			continue
+		}
+
+		if session.Status == model.SessionStatusExpired {
+			if now.Sub(*session.ExpiresAt) > ss.cfg.ExpiredSessionRetention {
+				ss.logger.Info("Forgetting expired session %s", id)
+				ss.forgetSession(id)
+			}
+			continue
+		}
+
+		// Sessions in the middle of a lifecycle operation are reaped on a later tick
+		if ss.transitions[id] != "" {
+			continue
+		}
+		ss.stopReadinessProbe(id)
+		ss.transitions[id] = operationExpire
+		expiring = append(expiring, ss.releaseCopy(session))
+	}
+	ss.mu.Unlock()
+
+	for i := range expiring {
+		ss.expireSession(&expiring[i])
+	}
+}
+
+// expireSession tears down a copy of a session marked as busy expiring and records the
+// session as expired
+func (ss *SessionService) expireSession(snapshot *model.Session) {
+	ss.logger.Info("Session %s expired at %s, tearing it down", snapshot.ID, snapshot.ExpiresAt.Format(time.RFC3339))
+	teardownErr := ss.releaseSession(snapshot)
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.transitions, snapshot.ID)
+
+	// A session deleted in the meantime has been torn down by the delete as well
+	session, exists := ss.sessions[snapshot.ID]
+	if !exists {
+		return
+	}
+	if teardownErr != nil {
+		// Try again on the next tick
+		ss.logger.Error("Failed to tear down expired session %s: %v", snapshot.ID, teardownErr)
+		return
+	}
+
+	session.Status = model.SessionStatusExpired
+	session.StatusReason = fmt.Sprintf("session lifetime ended at %s", snapshot.ExpiresAt.Format(time.RFC3339))
+	if err := ss.persistSession(session); err != nil {
+		ss.logger.Warn("Expired status of session %s will not survive a restart: %v", snapshot.ID, err)
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestResolveExpiry(t *testing.T) {
+	future := time.Now().Add(2 * time.Hour)
+	farFuture := time.Now().Add(48 * time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		defaultTTL time.Duration
+		maxTTL     time.Duration
+		req        model.CreateSessionRequest
+		// want is the expected lifetime from now; zero means no expiry
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "no expiry", want: 0},
+		{name: "server default", defaultTTL: time.Hour, want: time.Hour},
+		{name: "ttl", defaultTTL: time.Hour, req: model.CreateSessionRequest{TTLSeconds: 600}, want: 10 * time.Minute},
+		{name: "expires at", req: model.CreateSessionRequest{ExpiresAt: &future}, want: 2 * time.Hour},
+		{name: "within maximum", maxTTL: 3 * time.Hour, req: model.CreateSessionRequest{ExpiresAt: &future}, want: 2 * time.Hour},
+		{name: "ttl over maximum", maxTTL: time.Hour, req: model.CreateSessionRequest{TTLSeconds: 7200}, wantErr: true},
+		{name: "expires at over maximum", maxTTL: 24 * time.Hour, req: model.CreateSessionRequest{ExpiresAt: &farFuture}, wantErr: true},
+		{name: "default over maximum", defaultTTL: 2 * time.Hour, maxTTL: time.Hour, wantErr: true},
+		{name: "negative ttl", req: model.CreateSessionRequest{TTLSeconds: -1}, wantErr: true},
+		{name: "expires at in the past", req: model.CreateSessionRequest{ExpiresAt: &past}, wantErr: true},
+		{name: "ttl and expires at", req: model.CreateSessionRequest{TTLSeconds: 60, ExpiresAt: &future}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &SessionService{cfg: &config.Config{DefaultSessionTTL: tt.defaultTTL, MaxSessionTTL: tt.maxTTL}}
+			expiresAt, err := ss.resolveExpiry(&tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrInvalidRequest) {
+					t.Fatalf("resolveExpiry = %v, %v; want ErrInvalidRequest", expiresAt, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveExpiry: %v", err)
+			}
+			if tt.want == 0 {
+				if expiresAt != nil {
+					t.Fatalf("expires at %s, want no expiry", expiresAt)
+				}
+				return
+			}
+			if expiresAt == nil {
+				t.Fatal("no expiry, want one")
+			}
+			if lifetime := time.Until(*expiresAt); lifetime > tt.want || lifetime < tt.want-time.Minute {
+				t.Errorf("lifetime %s, want %s", lifetime, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendSession(t *testing.T) {
+	now := time.Now()
+	inAnHour := now.Add(time.Hour)
+	anHourAgo := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		status    string
+		expiresAt *time.Time
+		ttl       int
+		maxTTL    time.Duration
+		// want is the expected lifetime from now after the extension
+		want    time.Duration
+		wantErr error
+	}{
+		{name: "from current expiry", status: model.SessionStatusRunning, expiresAt: &inAnHour, ttl: 1800, want: 90 * time.Minute},
+		{name: "without expiry", status: model.SessionStatusRunning, ttl: 1800, want: 30 * time.Minute},
+		{name: "past expiry counts from now", status: model.SessionStatusRunning, expiresAt: &anHourAgo, ttl: 1800, want: 30 * time.Minute},
+		{name: "stopped session", status: model.SessionStatusStopped, expiresAt: &inAnHour, ttl: 60, want: 61 * time.Minute},
+		{name: "over maximum", status: model.SessionStatusRunning, expiresAt: &inAnHour, ttl: 3600, maxTTL: 90 * time.Minute, wantErr: util.ErrInvalidRequest},
+		{name: "zero ttl", status: model.SessionStatusRunning, ttl: 0, wantErr: util.ErrInvalidRequest},
+		{name: "expired", status: model.SessionStatusExpired, expiresAt: &anHourAgo, ttl: 60, wantErr: util.ErrOperationNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ss := newTestService(t, func(cfg *config.Config) {
+				cfg.MaxSessionTTL = tt.maxTTL
+			})
+			ss.sessions["s1"] = &model.Session{ID: "s1", Status: tt.status, ExpiresAt: tt.expiresAt}
+
+			extended, err := ss.ExtendSession("s1", tt.ttl)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ExtendSession = %v, want %v", err, tt.wantErr)
+				}
+				if ss.sessions["s1"].ExpiresAt != tt.expiresAt {
+					t.Fatal("a rejected extension changed the expiry")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtendSession: %v", err)
+			}
+			if lifetime := time.Until(*extended.ExpiresAt); lifetime > tt.want || lifetime < tt.want-time.Minute {
+				t.Errorf("lifetime %s, want %s", lifetime, tt.want)
+			}
+		})
+	}
+
+	_, ss := newTestService(t, nil)
+	if _, err := ss.ExtendSession("missing", 60); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("ExtendSession of a missing session = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReapExpiredSessions(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	expired := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1, TTLSeconds: 60})
+	alive := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1, TTLSeconds: 60})
+
+	past := time.Now().Add(-time.Second)
+	ss.mu.Lock()
+	ss.sessions[expired.ID].ExpiresAt = &past
+	ss.mu.Unlock()
+
+	ss.reapExpiredSessions()
+
+	session, err := ss.GetSession(expired.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if session.Status != model.SessionStatusExpired {
+		t.Fatalf("status = %s, want expired", session.Status)
+	}
+	if ss.transitions[expired.ID] != "" {
+		t.Fatalf("session is still busy with %s", ss.transitions[expired.ID])
+	}
+	if containers := fake.Containers(); len(containers) != 1 || containers[0].ID != alive.ContainerID {
+		t.Fatalf("containers = %+v, want only %s", containers, alive.ContainerID)
+	}
+	if !ss.portManager.ReservePort(expired.Ports[0].HostPort) {
+		t.Fatalf("port %d of the expired session is still reserved", expired.Ports[0].HostPort)
+	}
+
+	if _, err := ss.ExtendSession(expired.ID, 60); !errors.Is(err, util.ErrOperationNotValid) {
+		t.Fatalf("ExtendSession of an expired session = %v, want ErrOperationNotValid", err)
+	}
+}
+
+func TestReapSkipsBusySessions(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1, TTLSeconds: 60})
+
+	past := time.Now().Add(-time.Second)
+	ss.mu.Lock()
+	ss.sessions[session.ID].ExpiresAt = &past
+	ss.transitions[session.ID] = OperationRestart
+	ss.mu.Unlock()
+
+	// A session in the middle of an operation is left for a later tick
+	ss.reapExpiredSessions()
+	if got, _ := ss.GetSession(session.ID); got.Status == model.SessionStatusExpired {
+		t.Fatal("busy session was reaped")
+	}
+
+	ss.mu.Lock()
+	delete(ss.transitions, session.ID)
+	ss.mu.Unlock()
+	ss.reapExpiredSessions()
+	if got, _ := ss.GetSession(session.ID); got.Status != model.SessionStatusExpired {
+		t.Fatalf("status = %s, want expired", got.Status)
+	}
+}
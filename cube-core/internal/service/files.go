@@ -0,0 +1,326 @@
+package service
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// FileDownload is a file, or a tar archive of a path, read from a session's container
+type FileDownload struct {
+	Name    string
+	Size    int64 // -1 for archives, whose size is not known up front
+	Archive bool
+	Body    io.ReadCloser
+}
+
+// UploadFiles copies content into a session's container. A tar archive is extracted into the
+// directory at containerPath; anything else is written as a single file at containerPath.
+func (ss *SessionService) UploadFiles(ctx context.Context, sessionID, containerPath string, content io.Reader, archive bool) error {
+	containerID, err := ss.fileContainer(sessionID)
+	if err != nil {
+		return err
+	}
+	containerPath, err = validateContainerPath(containerPath)
+	if err != nil {
+		return err
+	}
+
+	if archive {
+		ss.logger.Info("Extracting archive into %s in session %s", containerPath, sessionID)
+		return ss.copyArchive(ctx, containerID, containerPath, content)
+	}
+
+	if containerPath == "/" {
+		return util.WrapError(util.ErrInvalidRequest, "path must name a file")
+	}
+	stat, err := ss.dockerManager.StatContainerPath(ctx, containerID, containerPath)
+	if err == nil && stat.Mode.IsDir() {
+		return util.WrapError(util.ErrInvalidRequest, "%s is a directory; upload a tar archive or name a file", containerPath)
+	}
+	if err != nil && !docker.IsNotFound(err) {
+		return util.WrapError(err, "failed to check %s", containerPath)
+	}
+
+	// A tar header needs the file size, so spool the upload first
+	spool, err := os.CreateTemp("", "cube-upload-*")
+	if err != nil {
+		return util.WrapError(err, "failed to buffer upload")
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, err := io.Copy(spool, ss.limitUpload(content))
+	if err != nil {
+		return err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return util.WrapError(err, "failed to buffer upload")
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path.Base(containerPath),
+			Mode:     0644,
+			Size:     size,
+			ModTime:  time.Now(),
+		})
+		if err == nil {
+			_, err = io.Copy(tw, spool)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	ss.logger.Info("Writing %d bytes to %s in session %s", size, containerPath, sessionID)
+	if err := ss.dockerManager.CopyToContainer(ctx, containerID, path.Dir(containerPath), reader); err != nil {
+		reader.CloseWithError(err)
+		return ss.fileTransferError(containerPath, err)
+	}
+	return nil
+}
+
+// copyArchive validates a tar archive entry by entry while streaming it into a container directory
+func (ss *SessionService) copyArchive(ctx context.Context, containerID, dir string, content io.Reader) error {
+	reader, writer := io.Pipe()
+	sanitized := make(chan error, 1)
+	go func() {
+		err := sanitizeArchive(ss.limitUpload(content), writer, ss.cfg.MaxUploadBytes)
+		sanitized <- err
+		writer.CloseWithError(err)
+	}()
+
+	copyErr := ss.dockerManager.CopyToContainer(ctx, containerID, dir, reader)
+	reader.CloseWithError(errors.New("copy ended"))
+
+	// A rejected archive explains a failed copy better than Docker does
+	if err := <-sanitized; err != nil {
+		return err
+	}
+	if copyErr != nil {
+		return ss.fileTransferError(dir, copyErr)
+	}
+	return nil
+}
+
+// sanitizeArchive copies a tar archive, rejecting entries that are not plain files,
+// directories or links, names that leave the destination, and more than limit bytes of
+// file content
+func sanitizeArchive(src io.Reader, dst io.Writer, limit int64) error {
+	tr := tar.NewReader(src)
+	tw := tar.NewWriter(dst)
+
+	var total int64
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if util.IsTooLargeError(err) {
+				return err
+			}
+			return util.WrapError(util.ErrInvalidRequest, "invalid tar archive: %v", err)
+		}
+
+		if !archivePathInside(header.Name) {
+			return util.WrapError(util.ErrInvalidRequest, "archive entry %q leaves the destination", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeDir, tar.TypeSymlink:
+		case tar.TypeLink:
+			if !archivePathInside(header.Linkname) {
+				return util.WrapError(util.ErrInvalidRequest, "archive entry %q links outside the destination", header.Name)
+			}
+		default:
+			return util.WrapError(util.ErrInvalidRequest, "archive entry %q has an unsupported type", header.Name)
+		}
+
+		total += header.Size
+		if limit > 0 && total > limit {
+			return util.WrapError(util.ErrTooLarge, "archive exceeds the upload limit of %d bytes", limit)
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			if util.IsTooLargeError(err) {
+				return err
+			}
+			return util.WrapError(util.ErrInvalidRequest, "invalid tar archive: %v", err)
+		}
+	}
+
+	return tw.Close()
+}
+
+// archivePathInside reports whether a relative archive path stays inside its destination
+func archivePathInside(name string) bool {
+	if name == "" || path.IsAbs(name) {
+		return false
+	}
+	cleaned := path.Clean(name)
+	return cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
+
+// DownloadFiles reads a path from a session's container. Regular files are returned as is
+// unless archive is set; directories are always returned as a tar archive.
+func (ss *SessionService) DownloadFiles(ctx context.Context, sessionID, containerPath string, archive bool) (*FileDownload, error) {
+	containerID, err := ss.fileContainer(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	containerPath, err = validateContainerPath(containerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, stat, err := ss.dockerManager.CopyFromContainer(ctx, containerID, containerPath)
+	if err != nil {
+		return nil, ss.fileTransferError(containerPath, err)
+	}
+
+	// Follow a symlink to the file it points to
+	if stat.LinkTarget != "" && !archive {
+		content.Close()
+		content, stat, err = ss.dockerManager.CopyFromContainer(ctx, containerID, stat.LinkTarget)
+		if err != nil {
+			return nil, ss.fileTransferError(containerPath, err)
+		}
+	}
+
+	limit := ss.cfg.MaxDownloadBytes
+	if archive || stat.Mode.IsDir() {
+		name := stat.Name
+		if name == "/" || name == "" {
+			name = "root"
+		}
+		return &FileDownload{
+			Name:    name + ".tar",
+			Size:    -1,
+			Archive: true,
+			Body:    limitedReadCloser(content, limit),
+		}, nil
+	}
+
+	if !stat.Mode.IsRegular() {
+		content.Close()
+		return nil, util.WrapError(util.ErrInvalidRequest, "%s is not a regular file", containerPath)
+	}
+	if limit > 0 && stat.Size > limit {
+		content.Close()
+		return nil, util.WrapError(util.ErrTooLarge, "%s exceeds the download limit of %d bytes", containerPath, limit)
+	}
+
+	// Docker always sends a tar archive; unwrap the single file in it
+	tr := tar.NewReader(content)
+	if _, err := tr.Next(); err != nil {
+		content.Close()
+		return nil, util.WrapError(err, "failed to read %s", containerPath)
+	}
+
+	return &FileDownload{
+		Name: stat.Name,
+		Size: stat.Size,
+		Body: readCloser{Reader: tr, Closer: content},
+	}, nil
+}
+
+// fileContainer returns the container of a session that files can be copied to and from
+func (ss *SessionService) fileContainer(sessionID string) (string, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		return "", util.ErrNotFound
+	}
+	if session.ContainerID == "" || session.Status == model.SessionStatusExpired {
+		return "", util.WrapError(util.ErrOperationNotValid, "session %s has no container", sessionID)
+	}
+	return session.ContainerID, nil
+}
+
+// validateContainerPath checks that a path inside a container is absolute and returns it cleaned
+func validateContainerPath(containerPath string) (string, error) {
+	if containerPath == "" {
+		return "", util.WrapError(util.ErrInvalidRequest, "path is required")
+	}
+	if !path.IsAbs(containerPath) || strings.ContainsRune(containerPath, 0) {
+		return "", util.WrapError(util.ErrInvalidRequest, "path %q must be an absolute path", containerPath)
+	}
+	return path.Clean(containerPath), nil
+}
+
+// fileTransferError maps a Docker copy error, reporting missing paths as not found
+func (ss *SessionService) fileTransferError(containerPath string, err error) error {
+	if docker.IsNotFound(err) {
+		return util.WrapError(util.ErrNotFound, "%s not found", containerPath)
+	}
+	ss.logger.Error("Failed to copy %s: %v", containerPath, err)
+	return util.WrapError(err, "failed to copy %s", containerPath)
+}
+
+// limitUpload caps an upload at the configured maximum
+func (ss *SessionService) limitUpload(content io.Reader) io.Reader {
+	if ss.cfg.MaxUploadBytes <= 0 {
+		return content
+	}
+	return &limitedReader{r: content, remaining: ss.cfg.MaxUploadBytes, limit: ss.cfg.MaxUploadBytes, what: "upload"}
+}
+
+// limitedReadCloser caps a download at limit bytes; zero means no limit
+func limitedReadCloser(rc io.ReadCloser, limit int64) io.ReadCloser {
+	if limit <= 0 {
+		return rc
+	}
+	return readCloser{
+		Reader: &limitedReader{r: rc, remaining: limit, limit: limit, what: "download"},
+		Closer: rc,
+	}
+}
+
+// limitedReader fails with util.ErrTooLarge once more than limit bytes have been read
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+	what      string
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, util.WrapError(util.ErrTooLarge, "%s exceeds the limit of %d bytes", l.what, l.limit)
+	}
+	// Read one byte past the limit to tell an exact fit from an overflow
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), util.WrapError(util.ErrTooLarge, "%s exceeds the limit of %d bytes", l.what, l.limit)
+	}
+	return n, err
+}
+
+// readCloser combines a reader with the closer of the stream it reads from
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// tarEntry is an entry of a tar archive built by a test
+type tarEntry struct {
+	typeflag byte
+	name     string
+	linkname string
+	content  string
+}
+
+// buildTar returns a tar archive of entries
+func buildTar(t *testing.T, entries ...tarEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Typeflag: e.typeflag, Name: e.name, Linkname: e.linkname, Mode: 0644}
+		if e.typeflag == tar.TypeReg {
+			header.Size = int64(len(e.content))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("write header %s: %v", e.name, err)
+		}
+		if _, err := io.WriteString(tw, e.content); err != nil {
+			t.Fatalf("write %s: %v", e.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchivePathInside(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "file.txt", want: true},
+		{name: "dir/", want: true},
+		{name: "dir/file.txt", want: true},
+		{name: "./file.txt", want: true},
+		{name: "dir/../file.txt", want: true},
+		{name: "..file", want: true},
+		{name: "", want: false},
+		{name: "/etc/passwd", want: false},
+		{name: "..", want: false},
+		{name: "../file.txt", want: false},
+		{name: "dir/../../file.txt", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := archivePathInside(tt.name); got != tt.want {
+				t.Fatalf("archivePathInside(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		limit   int64
+		wantErr error
+	}{
+		{
+			name: "files and directories",
+			entries: []tarEntry{
+				{typeflag: tar.TypeDir, name: "fixtures/"},
+				{typeflag: tar.TypeReg, name: "fixtures/users.json", content: `[{"id": 1}]`},
+			},
+		},
+		{
+			name: "links inside",
+			entries: []tarEntry{
+				{typeflag: tar.TypeReg, name: "data.txt", content: "data"},
+				{typeflag: tar.TypeSymlink, name: "latest", linkname: "data.txt"},
+				{typeflag: tar.TypeLink, name: "copy.txt", linkname: "data.txt"},
+			},
+		},
+		{name: "exactly at the limit", entries: []tarEntry{{typeflag: tar.TypeReg, name: "a", content: "12345"}}, limit: 5},
+		{
+			name:    "over the limit across files",
+			entries: []tarEntry{{typeflag: tar.TypeReg, name: "a", content: "123"}, {typeflag: tar.TypeReg, name: "b", content: "456"}},
+			limit:   5,
+			wantErr: util.ErrTooLarge,
+		},
+		{name: "absolute name", entries: []tarEntry{{typeflag: tar.TypeReg, name: "/etc/passwd", content: "root"}}, wantErr: util.ErrInvalidRequest},
+		{name: "name leaving the destination", entries: []tarEntry{{typeflag: tar.TypeReg, name: "../../etc/passwd", content: "root"}}, wantErr: util.ErrInvalidRequest},
+		{name: "hard link outside", entries: []tarEntry{{typeflag: tar.TypeLink, name: "passwd", linkname: "../etc/passwd"}}, wantErr: util.ErrInvalidRequest},
+		{name: "device", entries: []tarEntry{{typeflag: tar.TypeChar, name: "null"}}, wantErr: util.ErrInvalidRequest},
+		{name: "fifo", entries: []tarEntry{{typeflag: tar.TypeFifo, name: "pipe"}}, wantErr: util.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := sanitizeArchive(bytes.NewReader(buildTar(t, tt.entries...)), &out, tt.limit)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("sanitizeArchive = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			// The copy holds the same entries
+			tr := tar.NewReader(&out)
+			for _, e := range tt.entries {
+				header, err := tr.Next()
+				if err != nil {
+					t.Fatalf("read copy: %v", err)
+				}
+				content, _ := io.ReadAll(tr)
+				if header.Name != e.name || string(content) != e.content {
+					t.Fatalf("copied %s (%q), want %s (%q)", header.Name, content, e.name, e.content)
+				}
+			}
+		})
+	}
+
+	if err := sanitizeArchive(strings.NewReader("not a tar archive, but long enough to hold a header block"), io.Discard, 0); !errors.Is(err, util.ErrInvalidRequest) {
+		t.Fatalf("sanitizeArchive of garbage = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestValidateContainerPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "/app/data.json", want: "/app/data.json"},
+		{path: "/app/", want: "/app"},
+		{path: "/app/../etc", want: "/etc"},
+		{path: "/", want: "/"},
+		{path: "", wantErr: true},
+		{path: "app/data.json", wantErr: true},
+		{path: "/app/\x00", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := validateContainerPath(tt.path)
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrInvalidRequest) {
+					t.Fatalf("validateContainerPath = %q, %v; want ErrInvalidRequest", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("validateContainerPath = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "under the limit", content: "1234", limit: 5},
+		{name: "exact fit", content: "12345", limit: 5},
+		{name: "one byte over", content: "123456", limit: 5, wantErr: true},
+		{name: "far over", content: strings.Repeat("x", 100000), limit: 5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &limitedReader{r: strings.NewReader(tt.content), remaining: tt.limit, limit: tt.limit, what: "upload"}
+			data, err := io.ReadAll(r)
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrTooLarge) {
+					t.Fatalf("ReadAll = %v, want ErrTooLarge", err)
+				}
+				if int64(len(data)) > tt.limit {
+					t.Fatalf("read %d bytes past a limit of %d", len(data), tt.limit)
+				}
+				return
+			}
+			if err != nil || string(data) != tt.content {
+				t.Fatalf("ReadAll = %q, %v; want %q", data, err, tt.content)
+			}
+		})
+	}
+}
+
+func TestUploadFiles(t *testing.T) {
+	fake, ss := newTestService(t, func(cfg *config.Config) {
+		cfg.MaxUploadBytes = 4096
+	})
+	fake.AddImage("node:20")
+	session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "node:20"})
+	fake.SetContainerFile(session.ContainerID, "/app/package.json", "{}")
+	ss.sessions["provisioning"] = &model.Session{ID: "provisioning", Status: model.SessionStatusProvisioning}
+
+	fixtures := buildTar(t,
+		tarEntry{typeflag: tar.TypeDir, name: "fixtures/"},
+		tarEntry{typeflag: tar.TypeReg, name: "fixtures/users.json", content: "[]"},
+	)
+
+	tests := []struct {
+		name      string
+		sessionID string
+		path      string
+		content   []byte
+		archive   bool
+		wantErr   error
+		// wantFile is a file expected in the container afterwards, holding wantContent
+		wantFile    string
+		wantContent string
+	}{
+		{name: "file", path: "/app/seed.sql", content: []byte("insert"), wantFile: "/app/seed.sql", wantContent: "insert"},
+		{name: "replace file", path: "/app/package.json", content: []byte(`{"name": "app"}`), wantFile: "/app/package.json", wantContent: `{"name": "app"}`},
+		{name: "archive", path: "/app", content: fixtures, archive: true, wantFile: "/app/fixtures/users.json", wantContent: "[]"},
+		{name: "file over a directory", path: "/app", content: []byte("x"), wantErr: util.ErrInvalidRequest},
+		{name: "file at the root", path: "/", content: []byte("x"), wantErr: util.ErrInvalidRequest},
+		{name: "relative path", path: "app/seed.sql", content: []byte("x"), wantErr: util.ErrInvalidRequest},
+		{name: "missing directory", path: "/missing/seed.sql", content: []byte("x"), wantErr: util.ErrNotFound},
+		{name: "archive into missing directory", path: "/missing", content: fixtures, archive: true, wantErr: util.ErrNotFound},
+		{name: "unsafe archive", path: "/app", content: buildTar(t, tarEntry{typeflag: tar.TypeReg, name: "../etc/passwd", content: "x"}), archive: true, wantErr: util.ErrInvalidRequest},
+		{name: "file too large", path: "/app/big.bin", content: bytes.Repeat([]byte("x"), 4097), wantErr: util.ErrTooLarge},
+		{name: "unknown session", sessionID: "missing", path: "/app/seed.sql", content: []byte("x"), wantErr: util.ErrNotFound},
+		{name: "session without container", sessionID: "provisioning", path: "/app/seed.sql", content: []byte("x"), wantErr: util.ErrOperationNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionID := tt.sessionID
+			if sessionID == "" {
+				sessionID = session.ID
+			}
+			err := ss.UploadFiles(context.Background(), sessionID, tt.path, bytes.NewReader(tt.content), tt.archive)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UploadFiles = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantFile == "" {
+				return
+			}
+			if content, exists := fake.ContainerFile(session.ContainerID, tt.wantFile); !exists || content != tt.wantContent {
+				t.Fatalf("%s holds %q (exists %v), want %q", tt.wantFile, content, exists, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestDownloadFiles(t *testing.T) {
+	fake, ss := newTestService(t, func(cfg *config.Config) {
+		cfg.MaxDownloadBytes = 4096
+	})
+	fake.AddImage("node:20")
+	session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "node:20"})
+	fake.SetContainerFile(session.ContainerID, "/app/report.txt", "all tests passed")
+	fake.SetContainerFile(session.ContainerID, "/logs/big.log", strings.Repeat("x", 4097))
+
+	tests := []struct {
+		name        string
+		path        string
+		archive     bool
+		wantErr     error
+		wantReadErr error
+		wantName    string
+		wantArchive bool
+		// wantContent is the file content, or the first file of an archive
+		wantContent string
+	}{
+		{name: "file", path: "/app/report.txt", wantName: "report.txt", wantContent: "all tests passed"},
+		{name: "file as archive", path: "/app/report.txt", archive: true, wantName: "report.txt.tar", wantArchive: true, wantContent: "all tests passed"},
+		{name: "directory", path: "/app/", wantName: "app.tar", wantArchive: true, wantContent: "all tests passed"},
+		{name: "directory over the limit", path: "/logs", wantName: "logs.tar", wantArchive: true, wantReadErr: util.ErrTooLarge},
+		{name: "missing", path: "/app/missing.txt", wantErr: util.ErrNotFound},
+		{name: "relative path", path: "app/report.txt", wantErr: util.ErrInvalidRequest},
+		{name: "file too large", path: "/logs/big.log", wantErr: util.ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			download, err := ss.DownloadFiles(context.Background(), session.ID, tt.path, tt.archive)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DownloadFiles = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer download.Body.Close()
+
+			if download.Name != tt.wantName || download.Archive != tt.wantArchive {
+				t.Fatalf("download %s (archive %v), want %s (archive %v)", download.Name, download.Archive, tt.wantName, tt.wantArchive)
+			}
+			var body io.Reader = download.Body
+			if download.Archive {
+				tr := tar.NewReader(download.Body)
+				for {
+					header, err := tr.Next()
+					if err != nil {
+						if !errors.Is(err, tt.wantReadErr) {
+							t.Fatalf("read archive: %v, want %v", err, tt.wantReadErr)
+						}
+						return
+					}
+					if header.Typeflag == tar.TypeReg {
+						break
+					}
+				}
+				body = tr
+			}
+			content, err := io.ReadAll(body)
+			if tt.wantReadErr != nil {
+				if !errors.Is(err, tt.wantReadErr) {
+					t.Fatalf("read = %v, want %v", err, tt.wantReadErr)
+				}
+				return
+			}
+			if err != nil || string(content) != tt.wantContent {
+				t.Fatalf("content = %q, %v; want %q", content, err, tt.wantContent)
+			}
+		})
+	}
+}
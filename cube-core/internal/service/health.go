@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+)
+
+// operationRecreate marks a session whose unhealthy container is being recreated
+const operationRecreate = "recreate"
+
+// healthStatusFromDocker converts a container's Docker health to its API representation
+func healthStatusFromDocker(health *docker.HealthState) *model.HealthStatus {
+	if health == nil {
+		return nil
+	}
+
+	result := &model.HealthStatus{
+		Status:        health.Status,
+		FailingStreak: health.FailingStreak,
+		LastOutput:    health.LastOutput,
+		LastExitCode:  health.LastExitCode,
+	}
+	if !health.LastCheckedAt.IsZero() {
+		checkedAt := health.LastCheckedAt
+		result.LastCheckedAt = &checkedAt
+	}
+	return result
+}
+
+// healthChanged reports whether a health update is worth persisting
+func healthChanged(previous, current *model.HealthStatus) bool {
+	if previous == nil || current == nil {
+		return previous != current
+	}
+	return previous.Status != current.Status || previous.FailingStreak != current.FailingStreak
+}
+
+// StartHealthMonitor periodically records the state and Docker health of session containers
+// and recreates unhealthy ones whose restart policy asks for it, until ctx is cancelled
+func (ss *SessionService) StartHealthMonitor(ctx context.Context) {
+	interval := ss.cfg.HealthCheckInterval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ss.checkSessionContainers()
+			}
+		}
+	}()
+}
+
+// checkSessionContainers inspects the container of every session that is meant to be up once
+func (ss *SessionService) checkSessionContainers() {
+	ss.mu.Lock()
+	var sessions []model.Session
+	for _, session := range ss.sessions {
+		if session.ContainerID == "" {
+			continue
+		}
+		switch session.Status {
+		case model.SessionStatusRunning, model.SessionStatusReady, model.SessionStatusStarting, model.SessionStatusFailed,
+			model.SessionStatusExited, model.SessionStatusCrashLoop:
+			sessions = append(sessions, *session)
+		}
+	}
+	ss.mu.Unlock()
+
+	for _, snapshot := range sessions {
+		// Inspect outside the lock; the session may change in the meantime
+		state, err := ss.dockerManager.InspectContainerState(snapshot.ContainerID)
+		if err != nil {
+			ss.logger.Warn("Failed to inspect container %s of session %s: %v", snapshot.ContainerID, snapshot.ID, err)
+			continue
+		}
+		if reason := ss.recordContainerState(snapshot.ID, snapshot.ContainerID, state); reason != "" {
+			ss.recreateSessionContainer(snapshot.ID, snapshot.ContainerID, reason)
+		}
+	}
+}
+
+// recordContainerState stores the latest state and health of a session's container. If the
+// container is unhealthy and the session's restart policy asks for it to be recreated, the
+// session is marked as busy with the recreate and the reason to record is returned.
+func (ss *SessionService) recordContainerState(sessionID, containerID string, state *docker.ContainerState) string {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Sessions in the middle of a lifecycle operation are expected to change state
+	session, exists := ss.sessions[sessionID]
+	if !exists || session.ContainerID != containerID || ss.transitions[sessionID] != "" {
+		return ""
+	}
+
+	stateChanged := ss.reflectContainerState(session, state)
+
+	health := healthStatusFromDocker(state.Health)
+	changed := healthChanged(session.Health, health)
+	session.Health = health
+	if changed && health != nil {
+		ss.logger.Info("Container %s of session %s is %s", containerID, sessionID, health.Status)
+	}
+
+	if stateChanged || changed {
+		if err := ss.persistSession(session); err != nil {
+			ss.logger.Warn("Container state of session %s will not survive a restart: %v", sessionID, err)
+		}
+	}
+
+	// Exited containers are left to their Docker restart policy
+	if health == nil || health.Status != model.HealthStatusUnhealthy || !state.Running {
+		return ""
+	}
+
+	policy := session.RestartPolicy
+	if policy == nil || !policy.RecreateOnUnhealthy {
+		return ""
+	}
+	if policy.MaxRecreates > 0 && session.RecreateCount >= policy.MaxRecreates {
+		if changed {
+			ss.logger.Warn("Session %s is unhealthy but has been recreated %d times already", sessionID, session.RecreateCount)
+		}
+		return ""
+	}
+
+	ss.stopReadinessProbe(sessionID)
+	ss.transitions[sessionID] = operationRecreate
+	return fmt.Sprintf("container recreated after becoming unhealthy: %s", health.LastOutput)
+}
+
+// recreateSessionContainer replaces a session's container with a fresh one on the same host
+// ports and volumes. The session must be marked as busy with the recreate; the Docker work
+// happens without holding ss.mu.
+func (ss *SessionService) recreateSessionContainer(sessionID, oldContainerID, reason string) {
+	ss.logger.Info("Recreating container %s of session %s", oldContainerID, sessionID)
+	containerID, recreateErr := ss.dockerManager.RecreateContainer(oldContainerID)
+	oldGone := false
+	if recreateErr != nil {
+		// The old container may already be gone, leaving only the ports to release on delete
+		exists, err := ss.dockerManager.ContainerExists(oldContainerID)
+		oldGone = err == nil && !exists
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.transitions, sessionID)
+
+	// The session may have been deleted or expired while its container was replaced
+	session, exists := ss.sessions[sessionID]
+	if !exists || session.ContainerID != oldContainerID || session.Status == model.SessionStatusExpired {
+		if recreateErr == nil {
+			ss.logger.Info("Session %s went away while recreating its container, removing %s", sessionID, containerID)
+			if err := ss.dockerManager.RemoveContainer(containerID); err != nil && !docker.IsNotFound(err) {
+				ss.logger.Error("Failed to remove container %s: %v", containerID, err)
+			}
+		}
+		return
+	}
+
+	if recreateErr != nil {
+		ss.logger.Error("Failed to recreate container %s of session %s: %v", oldContainerID, sessionID, recreateErr)
+		if oldGone {
+			session.ContainerID = ""
+		}
+		session.Status = model.SessionStatusError
+		session.StatusReason = fmt.Sprintf("failed to recreate unhealthy container: %v", recreateErr)
+		if err := ss.persistSession(session); err != nil {
+			ss.logger.Warn("Failure of session %s will not survive a restart: %v", sessionID, err)
+		}
+		return
+	}
+
+	now := time.Now()
+	// Copies of the session handed out earlier share the services slice
+	services := make([]model.ServiceContainer, len(session.Services))
+	copy(services, session.Services)
+	for i := range services {
+		if services[i].ContainerID == oldContainerID {
+			services[i].ContainerID = containerID
+		}
+	}
+	if len(services) > 0 {
+		session.Services = services
+	}
+	session.ContainerID = containerID
+	session.RecreateCount++
+	session.Health = nil
+	session.LastActivityAt = &now
+	session.Status = runningStatus(session)
+	session.StatusReason = reason
+	if session.Readiness != nil {
+		ss.startReadinessProbe(session)
+	}
+
+	if err := ss.persistSession(session); err != nil {
+		ss.logger.Warn("New container of session %s will not survive a restart: %v", sessionID, err)
+	}
+	ss.logger.Info("Session %s now runs in container %s", sessionID, containerID)
+}
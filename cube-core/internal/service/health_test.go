@@ -0,0 +1,208 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestHealthStatusFromDocker(t *testing.T) {
+	checkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		health *docker.HealthState
+		want   *model.HealthStatus
+	}{
+		{name: "no healthcheck"},
+		{
+			name:   "starting",
+			health: &docker.HealthState{Status: model.HealthStatusStarting},
+			want:   &model.HealthStatus{Status: model.HealthStatusStarting},
+		},
+		{
+			name: "unhealthy",
+			health: &docker.HealthState{
+				Status:        model.HealthStatusUnhealthy,
+				FailingStreak: 3,
+				LastOutput:    "connection refused",
+				LastExitCode:  1,
+				LastCheckedAt: checkedAt,
+			},
+			want: &model.HealthStatus{
+				Status:        model.HealthStatusUnhealthy,
+				FailingStreak: 3,
+				LastOutput:    "connection refused",
+				LastExitCode:  1,
+				LastCheckedAt: &checkedAt,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := healthStatusFromDocker(tt.health); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("healthStatusFromDocker = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthChanged(t *testing.T) {
+	healthy := &model.HealthStatus{Status: model.HealthStatusHealthy}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		previous *model.HealthStatus
+		current  *model.HealthStatus
+		want     bool
+	}{
+		{name: "no healthcheck", want: false},
+		{name: "first result", current: healthy, want: true},
+		{name: "healthcheck gone", previous: healthy, want: true},
+		{name: "same status", previous: healthy, current: &model.HealthStatus{Status: model.HealthStatusHealthy}, want: false},
+		{name: "new probe only", previous: healthy, current: &model.HealthStatus{Status: model.HealthStatusHealthy, LastCheckedAt: &now, LastOutput: "ok"}, want: false},
+		{name: "turned unhealthy", previous: healthy, current: &model.HealthStatus{Status: model.HealthStatusUnhealthy}, want: true},
+		{
+			name:     "failing streak grew",
+			previous: &model.HealthStatus{Status: model.HealthStatusHealthy, FailingStreak: 1},
+			current:  &model.HealthStatus{Status: model.HealthStatusHealthy, FailingStreak: 2},
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := healthChanged(tt.previous, tt.current); got != tt.want {
+				t.Fatalf("healthChanged = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthMonitorRecordsHealth(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          *model.RestartPolicy
+		health          string
+		containerStatus string
+		wantRecreated   bool
+	}{
+		{name: "healthy", policy: &model.RestartPolicy{RecreateOnUnhealthy: true}, health: model.HealthStatusHealthy},
+		{name: "unhealthy without recreate policy", health: model.HealthStatusUnhealthy},
+		{name: "unhealthy but exited", policy: &model.RestartPolicy{RecreateOnUnhealthy: true}, health: model.HealthStatusUnhealthy, containerStatus: "exited"},
+		{name: "unhealthy with recreate policy", policy: &model.RestartPolicy{RecreateOnUnhealthy: true}, health: model.HealthStatusUnhealthy, wantRecreated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, ss := newTestService(t, nil)
+			fake.AddImage("nginx:latest")
+			session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", RestartPolicy: tt.policy})
+			fake.SetContainerHealth(session.ContainerID, tt.health)
+			if tt.containerStatus != "" {
+				fake.SetContainerStatus(session.ContainerID, tt.containerStatus, 1)
+			}
+
+			ss.checkSessionContainers()
+
+			ss.mu.Lock()
+			checked := *ss.sessions[session.ID]
+			ss.mu.Unlock()
+			if recreated := checked.ContainerID != session.ContainerID; recreated != tt.wantRecreated {
+				t.Fatalf("recreated = %v, want %v", recreated, tt.wantRecreated)
+			}
+			if tt.wantRecreated {
+				return
+			}
+			if checked.Health == nil || checked.Health.Status != tt.health {
+				t.Fatalf("recorded health = %+v, want %s", checked.Health, tt.health)
+			}
+		})
+	}
+}
+
+func TestRecreateUnhealthyContainer(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName:     "nginx:latest",
+		NumPorts:      1,
+		RestartPolicy: &model.RestartPolicy{RecreateOnUnhealthy: true, MaxRecreates: 1},
+	})
+	oldContainerID := session.ContainerID
+	fake.SetContainerHealth(oldContainerID, model.HealthStatusUnhealthy)
+
+	state, err := ss.dockerManager.InspectContainerState(oldContainerID)
+	if err != nil {
+		t.Fatalf("InspectContainerState: %v", err)
+	}
+	reason := ss.recordContainerState(session.ID, oldContainerID, state)
+	if reason == "" {
+		t.Fatal("unhealthy container was not marked for recreation")
+	}
+
+	// Until the recreate is done, the session is busy but the lock is free
+	if _, err := ss.ApplySessionOperation(session.ID, OperationStop); !errors.Is(err, util.ErrOperationNotValid) {
+		t.Fatalf("stop during recreate = %v, want ErrOperationNotValid", err)
+	}
+	if _, err := ss.GetSession(session.ID); err != nil {
+		t.Fatalf("GetSession during recreate: %v", err)
+	}
+
+	ss.recreateSessionContainer(session.ID, oldContainerID, reason)
+
+	recreated, err := ss.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if recreated.ContainerID == oldContainerID || recreated.ContainerID == "" {
+		t.Fatalf("container = %q, want a new container", recreated.ContainerID)
+	}
+	if recreated.RecreateCount != 1 || recreated.Status != model.SessionStatusRunning {
+		t.Fatalf("recreate count %d, status %s; want 1 and running", recreated.RecreateCount, recreated.Status)
+	}
+	if containers := fake.Containers(); len(containers) != 1 || containers[0].ID != recreated.ContainerID {
+		t.Fatalf("containers = %+v, want only %s", containers, recreated.ContainerID)
+	}
+
+	// The policy allows a single recreate
+	fake.SetContainerHealth(recreated.ContainerID, model.HealthStatusUnhealthy)
+	ss.checkSessionContainers()
+	if again, _ := ss.GetSession(session.ID); again.ContainerID != recreated.ContainerID {
+		t.Fatalf("container was recreated beyond max_recreates")
+	}
+}
+
+func TestRecreateAfterSessionDeleted(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName:     "nginx:latest",
+		NumPorts:      1,
+		RestartPolicy: &model.RestartPolicy{RecreateOnUnhealthy: true},
+	})
+	fake.SetContainerHealth(session.ContainerID, model.HealthStatusUnhealthy)
+
+	state, err := ss.dockerManager.InspectContainerState(session.ContainerID)
+	if err != nil {
+		t.Fatalf("InspectContainerState: %v", err)
+	}
+	reason := ss.recordContainerState(session.ID, session.ContainerID, state)
+
+	// Forget the session the way a delete racing with the recreate would
+	ss.mu.Lock()
+	ss.forgetSession(session.ID)
+	ss.mu.Unlock()
+
+	// The container created for a session that is gone is removed again
+	ss.recreateSessionContainer(session.ID, session.ContainerID, reason)
+	if containers := fake.Containers(); len(containers) != 0 {
+		t.Fatalf("containers = %+v, want none", containers)
+	}
+}
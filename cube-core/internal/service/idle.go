@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// IdleMonitor suspends sessions that show no network or CPU activity for the configured idle timeout
+type IdleMonitor struct {
+	cfg            *config.Config
+	sessionService *SessionService
+	metricsService *MetricsService
+	// lastNetworkBytes holds the rx+tx byte counters seen at the previous sample, per session
+	lastNetworkBytes map[string]uint64
+	logger           *util.Logger
+}
+
+// NewIdleMonitor creates a new idle monitor
+func NewIdleMonitor(cfg *config.Config, sessionService *SessionService, metricsService *MetricsService) (*IdleMonitor, error) {
+	switch cfg.IdleAction {
+	case SuspendActionPause, SuspendActionStop:
+	default:
+		return nil, fmt.Errorf("invalid idle action %q, expected %q or %q", cfg.IdleAction, SuspendActionPause, SuspendActionStop)
+	}
+
+	return &IdleMonitor{
+		cfg:              cfg,
+		sessionService:   sessionService,
+		metricsService:   metricsService,
+		lastNetworkBytes: make(map[string]uint64),
+		logger:           util.NewLogger(),
+	}, nil
+}
+
+// Start samples session activity until ctx is cancelled. It does nothing if idle detection is disabled.
+func (im *IdleMonitor) Start(ctx context.Context) {
+	if im.cfg.IdleTimeout <= 0 {
+		im.logger.Info("Idle detection disabled")
+		return
+	}
+
+	interval := im.cfg.IdleCheckInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				im.checkSessions(ctx)
+			}
+		}
+	}()
+}
+
+// checkSessions samples every running session once and suspends those idle for too long
+func (im *IdleMonitor) checkSessions(ctx context.Context) {
+	now := time.Now()
+	seen := make(map[string]bool)
+
+	for _, session := range im.sessionService.runningSessions() {
+		seen[session.ID] = true
+
+		stats, err := im.metricsService.getContainerStats(ctx, session.ContainerID)
+		if err != nil {
+			im.logger.Warn("Failed to sample activity of session %s: %v", session.ID, err)
+			continue
+		}
+
+		networkBytes := stats.Network.RxBytes + stats.Network.TxBytes
+		previous, sampled := im.lastNetworkBytes[session.ID]
+		im.lastNetworkBytes[session.ID] = networkBytes
+
+		// The first sample only establishes a baseline for the network counters
+		if !sampled {
+			continue
+		}
+
+		// Counters going backwards mean the container restarted, which counts as activity
+		active := networkBytes < previous ||
+			networkBytes-previous > im.cfg.IdleNetworkThreshold ||
+			stats.CPU.UsagePercent > im.cfg.IdleCPUThreshold
+		if active {
+			im.sessionService.recordActivity(session.ID, now)
+			continue
+		}
+
+		lastActivity := session.CreatedAt
+		if session.LastActivityAt != nil {
+			lastActivity = *session.LastActivityAt
+		}
+
+		idleFor := now.Sub(lastActivity)
+		if idleFor < im.cfg.IdleTimeout {
+			continue
+		}
+
+		reason := fmt.Sprintf("idle: no activity for %s", idleFor.Round(time.Minute))
+		if _, err := im.sessionService.SuspendSession(session.ID, im.cfg.IdleAction, reason); err != nil {
+			im.logger.Error("Failed to suspend idle session %s: %v", session.ID, err)
+			continue
+		}
+		delete(im.lastNetworkBytes, session.ID)
+	}
+
+	// Forget counters of sessions that are no longer running
+	for id := range im.lastNetworkBytes {
+		if !seen[id] {
+			delete(im.lastNetworkBytes, id)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestNewIdleMonitor(t *testing.T) {
+	tests := []struct {
+		action  string
+		wantErr bool
+	}{
+		{action: SuspendActionPause},
+		{action: SuspendActionStop},
+		{action: "kill", wantErr: true},
+		{action: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.IdleAction = tt.action
+			_, err := NewIdleMonitor(cfg, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewIdleMonitor = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSuspendAndResumeKeepPorts(t *testing.T) {
+	tests := []struct {
+		action          string
+		wantStatus      string
+		containerStatus string
+	}{
+		{action: SuspendActionPause, wantStatus: model.SessionStatusPaused, containerStatus: "paused"},
+		{action: SuspendActionStop, wantStatus: model.SessionStatusStopped, containerStatus: "exited"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			fake, ss := newTestService(t, nil)
+			fake.AddImage("nginx:latest")
+			session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 2})
+			if len(session.Ports) != 2 {
+				t.Fatalf("ports = %+v, want 2", session.Ports)
+			}
+
+			suspended, err := ss.SuspendSession(session.ID, tt.action, "idle: no activity for 30m0s")
+			if err != nil {
+				t.Fatalf("SuspendSession: %v", err)
+			}
+			if suspended.Status != tt.wantStatus || suspended.StatusReason != "idle: no activity for 30m0s" {
+				t.Fatalf("suspended session is %s (%q), want %s with the reason", suspended.Status, suspended.StatusReason, tt.wantStatus)
+			}
+			if status := fake.Containers()[0].Status; status != tt.containerStatus {
+				t.Fatalf("container is %s, want %s", status, tt.containerStatus)
+			}
+
+			// The host ports stay reserved while suspended
+			for _, p := range session.Ports {
+				if ss.portManager.ReservePort(p.HostPort) {
+					t.Fatalf("host port %d was released on suspend", p.HostPort)
+				}
+			}
+
+			resumed, err := ss.ResumeSession(session.ID)
+			if err != nil {
+				t.Fatalf("ResumeSession: %v", err)
+			}
+			if resumed.Status != model.SessionStatusRunning || resumed.StatusReason != "" {
+				t.Fatalf("resumed session is %s (%q), want running", resumed.Status, resumed.StatusReason)
+			}
+			if resumed.LastActivityAt == nil {
+				t.Fatal("resuming did not count as activity")
+			}
+			if len(resumed.Ports) != len(session.Ports) {
+				t.Fatalf("ports = %+v, want %+v", resumed.Ports, session.Ports)
+			}
+			for i, p := range resumed.Ports {
+				if p != session.Ports[i] {
+					t.Fatalf("ports = %+v, want %+v", resumed.Ports, session.Ports)
+				}
+			}
+		})
+	}
+}
+
+func TestSuspendAndResumeRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		call    func(ss *SessionService, id string) error
+		wantErr error
+	}{
+		{
+			name:   "unknown action",
+			status: model.SessionStatusRunning,
+			call: func(ss *SessionService, id string) error {
+				_, err := ss.SuspendSession(id, "kill", "")
+				return err
+			},
+			wantErr: util.ErrInvalidRequest,
+		},
+		{
+			name:   "suspend paused session",
+			status: model.SessionStatusPaused,
+			call: func(ss *SessionService, id string) error {
+				_, err := ss.SuspendSession(id, SuspendActionStop, "")
+				return err
+			},
+			wantErr: util.ErrOperationNotValid,
+		},
+		{
+			name:   "suspend missing session",
+			status: model.SessionStatusRunning,
+			call: func(ss *SessionService, id string) error {
+				_, err := ss.SuspendSession("missing", SuspendActionPause, "")
+				return err
+			},
+			wantErr: util.ErrNotFound,
+		},
+		{
+			name:   "resume running session",
+			status: model.SessionStatusRunning,
+			call: func(ss *SessionService, id string) error {
+				_, err := ss.ResumeSession(id)
+				return err
+			},
+			wantErr: util.ErrOperationNotValid,
+		},
+		{
+			name:   "resume expired session",
+			status: model.SessionStatusExpired,
+			call: func(ss *SessionService, id string) error {
+				_, err := ss.ResumeSession(id)
+				return err
+			},
+			wantErr: util.ErrOperationNotValid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ss := newTestService(t, nil)
+			ss.sessions["s1"] = &model.Session{ID: "s1", Status: tt.status}
+
+			if err := tt.call(ss, "s1"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if status := ss.sessions["s1"].Status; status != tt.status {
+				t.Fatalf("status changed to %s", status)
+			}
+		})
+	}
+}
+
+func TestRecordActivity(t *testing.T) {
+	_, ss := newTestService(t, nil)
+	ss.sessions["s1"] = &model.Session{ID: "s1", Status: model.SessionStatusRunning}
+	start := time.Now()
+
+	tests := []struct {
+		at        time.Time
+		wantSaved time.Time
+	}{
+		{at: start, wantSaved: start},
+		{at: start.Add(time.Minute), wantSaved: start},
+		{at: start.Add(activitySaveInterval - time.Second), wantSaved: start},
+		{at: start.Add(activitySaveInterval), wantSaved: start.Add(activitySaveInterval)},
+	}
+	for _, tt := range tests {
+		ss.recordActivity("s1", tt.at)
+		if got := *ss.sessions["s1"].LastActivityAt; !got.Equal(tt.at) {
+			t.Errorf("activity at %s: last activity %s", tt.at.Sub(start), got.Sub(start))
+		}
+		if saved := ss.activitySaved["s1"]; !saved.Equal(tt.wantSaved) {
+			t.Errorf("activity at %s: saved at %s, want %s", tt.at.Sub(start), saved.Sub(start), tt.wantSaved.Sub(start))
+		}
+	}
+
+	// Activity of unknown sessions is ignored
+	ss.recordActivity("missing", start)
+	if _, exists := ss.sessions["missing"]; exists {
+		t.Fatal("recording activity created a session")
+	}
+}
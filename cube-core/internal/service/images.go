@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// PullImage pulls an image, reporting every progress update to progress
+func (ss *SessionService) PullImage(ctx context.Context, req *model.PullImageRequest, progress func(model.PullProgressEvent)) error {
+	if req.Image == "" {
+		return util.WrapError(util.ErrInvalidRequest, "image is required")
+	}
+
+	ss.logger.Info("Pulling image %s", req.Image)
+	err := ss.dockerManager.PullImage(ctx, req.Image, dockerRegistryAuth(req.RegistryAuth), func(p docker.PullProgress) {
+		if progress != nil {
+			progress(model.PullProgressEvent{
+				ID:       p.ID,
+				Status:   p.Status,
+				Progress: p.Progress,
+				Current:  p.Current,
+				Total:    p.Total,
+			})
+		}
+	})
+	if err != nil {
+		ss.logger.Error("Failed to pull image %s: %v", req.Image, err)
+		return err
+	}
+
+	ss.logger.Info("Pulled image %s", req.Image)
+	return nil
+}
+
+// ensureImage pulls the image for a new session if it is not present locally
+func (ss *SessionService) ensureImage(ctx context.Context, image string, auth *model.RegistryAuth) error {
+	exists, err := ss.dockerManager.ImageExists(image)
+	if err != nil {
+		return util.WrapError(err, "failed to check image %s", image)
+	}
+	if exists {
+		return nil
+	}
+
+	if !ss.cfg.PullMissingImages {
+		return util.WrapError(util.ErrInvalidRequest, "image %s is not available locally", image)
+	}
+
+	ss.logger.Info("Image %s not found locally, pulling it", image)
+	if err := ss.dockerManager.PullImage(ctx, image, dockerRegistryAuth(auth), nil); err != nil {
+		return util.WrapError(err, "failed to pull image %s", image)
+	}
+	return nil
+}
+
+// dockerRegistryAuth converts API registry credentials to Docker registry credentials
+func dockerRegistryAuth(auth *model.RegistryAuth) *docker.RegistryAuth {
+	if auth == nil {
+		return nil
+	}
+
+	return &docker.RegistryAuth{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ServerAddress: auth.ServerAddress,
+		IdentityToken: auth.IdentityToken,
+	}
+}
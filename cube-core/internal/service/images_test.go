@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker/dockertest"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func newImageTestService(t *testing.T, pullMissing bool) (*dockertest.FakeAPI, *SessionService) {
+	t.Helper()
+
+	return newTestService(t, func(cfg *config.Config) {
+		cfg.PullMissingImages = pullMissing
+	})
+}
+
+func TestEnsureImagePullsMissingImage(t *testing.T) {
+	fake, ss := newImageTestService(t, true)
+
+	if err := ss.ensureImage(context.Background(), "alpine:3.19", nil); err != nil {
+		t.Fatalf("ensureImage: %v", err)
+	}
+	if got := fake.Pulls(); !reflect.DeepEqual(got, []string{"alpine:3.19"}) {
+		t.Fatalf("pulls = %v, want [alpine:3.19]", got)
+	}
+
+	// Once pulled, the image is not pulled again
+	if err := ss.ensureImage(context.Background(), "alpine:3.19", nil); err != nil {
+		t.Fatalf("ensureImage: %v", err)
+	}
+	if got := fake.Pulls(); len(got) != 1 {
+		t.Fatalf("pulls = %v, want a single pull", got)
+	}
+}
+
+func TestEnsureImageSkipsPresentImage(t *testing.T) {
+	fake, ss := newImageTestService(t, true)
+	fake.AddImage("nginx:latest")
+
+	if err := ss.ensureImage(context.Background(), "nginx:latest", nil); err != nil {
+		t.Fatalf("ensureImage: %v", err)
+	}
+	if got := fake.Pulls(); len(got) != 0 {
+		t.Fatalf("pulls = %v, want none", got)
+	}
+}
+
+func TestEnsureImageWithoutPulling(t *testing.T) {
+	fake, ss := newImageTestService(t, false)
+
+	err := ss.ensureImage(context.Background(), "alpine:3.19", nil)
+	if !util.IsInvalidRequestError(err) {
+		t.Fatalf("ensureImage error = %v, want an invalid request error", err)
+	}
+	if got := fake.Pulls(); len(got) != 0 {
+		t.Fatalf("pulls = %v, want none", got)
+	}
+}
+
+func TestEnsureImageReportsPullErrors(t *testing.T) {
+	fake, ss := newImageTestService(t, true)
+	fake.SetPullMessages("private/app:1", `{"status":"Pulling from private/app"}`, `{"errorDetail":{"message":"denied"},"error":"denied"}`)
+
+	if err := ss.ensureImage(context.Background(), "private/app:1", nil); err == nil {
+		t.Fatal("ensureImage succeeded, want the pull error")
+	}
+}
+
+func TestPullImageReportsProgress(t *testing.T) {
+	fake, ss := newImageTestService(t, true)
+	fake.SetPullMessages("alpine:3.19",
+		`{"status":"Pulling from library/alpine","id":"3.19"}`,
+		`{"status":"Downloading","id":"abc","progressDetail":{"current":512,"total":1024},"progress":"[=====>     ]"}`,
+		`{"status":"Download complete","id":"abc"}`,
+	)
+
+	var events []model.PullProgressEvent
+	err := ss.PullImage(context.Background(), &model.PullImageRequest{Image: "alpine:3.19"}, func(event model.PullProgressEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("PullImage: %v", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d progress events, want 3: %+v", len(events), events)
+	}
+	if events[1].ID != "abc" || events[1].Current != 512 || events[1].Total != 1024 {
+		t.Fatalf("download event = %+v, want layer abc at 512/1024", events[1])
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+)
+
+// sessionLabelKeys are all the labels that tie a container to a session. Images committed
+// from a session's container must clear every one of them.
+var sessionLabelKeys = []string{
+	docker.LabelManaged,
+	docker.LabelSessionID,
+	docker.LabelImage,
+	docker.LabelPorts,
+	docker.LabelCreatedAt,
+	docker.LabelExpiresAt,
+	docker.LabelService,
+	docker.LabelPrimary,
+	docker.LabelDependsOn,
+	docker.LabelNetwork,
+	docker.LabelInternalNetwork,
+	docker.LabelEgress,
+	docker.LabelEgressProxy,
+	docker.LabelOwnedSnapshot,
+}
+
+// sessionLabels builds the Docker labels that identify a container as belonging to a session
+func sessionLabels(session *model.Session) (map[string]string, error) {
+	// URLs depend on the host the server runs on, so they are rebuilt rather than stored
+	ports := make([]model.Port, len(session.Ports))
+	for i, p := range session.Ports {
+		ports[i] = p
+		ports[i].URL = ""
+	}
+
+	portsJSON, err := json.Marshal(ports)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode port labels: %v", err)
+	}
+
+	labels := map[string]string{
+		docker.LabelManaged:   "true",
+		docker.LabelSessionID: session.ID,
+		docker.LabelImage:     session.ImageName,
+		docker.LabelPorts:     string(portsJSON),
+		docker.LabelCreatedAt: session.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	if session.ExpiresAt != nil {
+		labels[docker.LabelExpiresAt] = session.ExpiresAt.UTC().Format(time.RFC3339Nano)
+	}
+	if session.Network != "" {
+		labels[docker.LabelNetwork] = session.Network
+	}
+	if session.InternalNetwork {
+		labels[docker.LabelInternalNetwork] = "true"
+	}
+	if session.OwnsSnapshot && session.SnapshotID != "" {
+		labels[docker.LabelOwnedSnapshot] = session.SnapshotID
+	}
+	if session.Egress != nil {
+		egressJSON, err := json.Marshal(session.Egress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode egress label: %v", err)
+		}
+		labels[docker.LabelEgress] = string(egressJSON)
+	}
+
+	return labels, nil
+}
+
+// sessionFromLabels rebuilds a session from the labels of a managed container
+func sessionFromLabels(c docker.Container) (*model.Session, error) {
+	sessionID := c.Labels[docker.LabelSessionID]
+	if sessionID == "" {
+		return nil, fmt.Errorf("container %s has no session label", c.ID)
+	}
+
+	var ports []model.Port
+	if raw := c.Labels[docker.LabelPorts]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &ports); err != nil {
+			return nil, fmt.Errorf("failed to decode port labels of container %s: %v", c.ID, err)
+		}
+	}
+
+	createdAt := time.Unix(c.Created, 0)
+	if raw := c.Labels[docker.LabelCreatedAt]; raw != "" {
+		if parsed, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			createdAt = parsed
+		}
+	}
+
+	imageName := c.Labels[docker.LabelImage]
+	if imageName == "" {
+		imageName = c.Image
+	}
+
+	// This is the expiry the session was created with; later extensions live in the session store
+	var expiresAt *time.Time
+	if raw := c.Labels[docker.LabelExpiresAt]; raw != "" {
+		if parsed, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			expiresAt = &parsed
+		}
+	}
+
+	var egressPolicy *model.EgressPolicy
+	if raw := c.Labels[docker.LabelEgress]; raw != "" {
+		egressPolicy = &model.EgressPolicy{}
+		if err := json.Unmarshal([]byte(raw), egressPolicy); err != nil {
+			return nil, fmt.Errorf("failed to decode egress label of container %s: %v", c.ID, err)
+		}
+	}
+
+	status := model.SessionStatusRunning
+	if c.State != "running" {
+		status = model.SessionStatusStopped
+	}
+
+	session := &model.Session{
+		ID:          sessionID,
+		CreatedAt:   createdAt,
+		ImageName:   imageName,
+		ContainerID: c.ID,
+		Ports:       ports,
+		Status:      status,
+		ExpiresAt:   expiresAt,
+
+		Network:         c.Labels[docker.LabelNetwork],
+		InternalNetwork: c.Labels[docker.LabelInternalNetwork] == "true",
+		Egress:          egressPolicy,
+
+		SnapshotID:   c.Labels[docker.LabelOwnedSnapshot],
+		OwnsSnapshot: c.Labels[docker.LabelOwnedSnapshot] != "",
+	}
+
+	// A container of a stack only stands for the session if it runs the primary service
+	if service := c.Labels[docker.LabelService]; service != "" {
+		primary := c.Labels[docker.LabelPrimary] == "true"
+		var dependsOn []string
+		if raw := c.Labels[docker.LabelDependsOn]; raw != "" {
+			dependsOn = strings.Split(raw, ",")
+		}
+		session.Services = []model.ServiceContainer{{
+			Name:        service,
+			ImageName:   c.Image,
+			ContainerID: c.ID,
+			Primary:     primary,
+			DependsOn:   dependsOn,
+		}}
+		session.Network = c.Labels[docker.LabelNetwork]
+		if !primary {
+			session.ContainerID = ""
+			session.Status = model.SessionStatusStopped
+		}
+	}
+
+	return session, nil
+}
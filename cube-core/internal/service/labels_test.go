@@ -0,0 +1,132 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/port"
+)
+
+func TestSessionLabelsRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(time.Hour)
+	session := &model.Session{
+		ID:        "6f1c5a9e-0d6b-4c4e-9a41-2f0f3b8d7c11",
+		CreatedAt: createdAt,
+		ImageName: "nginx:latest",
+		Ports: []model.Port{
+			{HostPort: 32768, ContainerPort: 80, Protocol: "tcp", Description: "HTTP", URL: "http://example.test:32768"},
+		},
+		ExpiresAt:       &expiresAt,
+		Network:         "cube-session-6f1c5a9e-0d6b-4c4e-9a41-2f0f3b8d7c11",
+		InternalNetwork: true,
+		SnapshotID:      "a3f9",
+		OwnsSnapshot:    true,
+		Egress:          &model.EgressPolicy{Mode: model.EgressModeAllowlist, Allow: []model.EgressRule{{CIDR: "10.0.0.0/8", Ports: []int{443}}}},
+	}
+
+	labels, err := sessionLabels(session)
+	if err != nil {
+		t.Fatalf("sessionLabels: %v", err)
+	}
+	rebuilt, err := sessionFromLabels(docker.Container{ID: "c1", Image: "sha256:1", State: "running", Labels: labels})
+	if err != nil {
+		t.Fatalf("sessionFromLabels: %v", err)
+	}
+
+	// URLs are rebuilt from the current settings rather than stored
+	want := *session
+	want.Ports = []model.Port{{HostPort: 32768, ContainerPort: 80, Protocol: "tcp", Description: "HTTP"}}
+	want.ContainerID = "c1"
+	want.Status = model.SessionStatusRunning
+	if !reflect.DeepEqual(rebuilt, &want) {
+		t.Fatalf("rebuilt session = %+v, want %+v", rebuilt, &want)
+	}
+}
+
+func TestSessionFromLabels(t *testing.T) {
+	base := map[string]string{
+		docker.LabelManaged:   "true",
+		docker.LabelSessionID: "s1",
+		docker.LabelPorts:     `[{"host_port":32768,"container_port":80,"protocol":"tcp"}]`,
+	}
+	with := func(key, value string) map[string]string {
+		labels := make(map[string]string, len(base)+1)
+		for k, v := range base {
+			labels[k] = v
+		}
+		labels[key] = value
+		return labels
+	}
+
+	tests := []struct {
+		name       string
+		container  docker.Container
+		wantErr    bool
+		wantStatus string
+		wantImage  string
+	}{
+		{"running", docker.Container{ID: "c1", Image: "nginx", State: "running", Labels: base}, false, model.SessionStatusRunning, "nginx"},
+		{"exited", docker.Container{ID: "c1", Image: "nginx", State: "exited", Labels: base}, false, model.SessionStatusStopped, "nginx"},
+		{"image label wins", docker.Container{ID: "c1", Image: "sha256:1", State: "running", Labels: with(docker.LabelImage, "nginx:1.27")}, false, model.SessionStatusRunning, "nginx:1.27"},
+		{"no session label", docker.Container{ID: "c1", State: "running", Labels: map[string]string{docker.LabelManaged: "true"}}, true, "", ""},
+		{"broken ports", docker.Container{ID: "c1", State: "running", Labels: with(docker.LabelPorts, "{")}, true, "", ""},
+		{"broken egress", docker.Container{ID: "c1", State: "running", Labels: with(docker.LabelEgress, "[")}, true, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := sessionFromLabels(tt.container)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("sessionFromLabels = %+v, want an error", session)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sessionFromLabels: %v", err)
+			}
+			if session.Status != tt.wantStatus || session.ImageName != tt.wantImage {
+				t.Errorf("status %s, image %s; want %s, %s", session.Status, session.ImageName, tt.wantStatus, tt.wantImage)
+			}
+			if len(session.Ports) != 1 || session.Ports[0].HostPort != 32768 {
+				t.Errorf("ports = %+v, want host port 32768", session.Ports)
+			}
+		})
+	}
+}
+
+func TestReconcileSessionsRecoversFromLabels(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1, TTLSeconds: 600})
+
+	// A server without the session store only has the container labels to go on
+	restarted := NewSessionService(ss.cfg, ss.dockerManager, port.NewPortManager(), nil, nil)
+	recovered, err := restarted.ReconcileSessions()
+	if err != nil || recovered != 1 {
+		t.Fatalf("ReconcileSessions = %d, %v; want 1 session", recovered, err)
+	}
+
+	got, err := restarted.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got.ContainerID != session.ContainerID || got.Status != model.SessionStatusRunning {
+		t.Errorf("container %s, status %s; want %s, running", got.ContainerID, got.Status, session.ContainerID)
+	}
+	if !got.ExpiresAt.Equal(*session.ExpiresAt) {
+		t.Errorf("expires at %s, want %s", got.ExpiresAt, session.ExpiresAt)
+	}
+	if restarted.portManager.ReservePort(session.Ports[0].HostPort) {
+		t.Errorf("port %d of the recovered session is not reserved", session.Ports[0].HostPort)
+	}
+
+	// Sessions the server already knows are left alone
+	if recovered, err := restarted.ReconcileSessions(); err != nil || recovered != 0 {
+		t.Fatalf("second ReconcileSessions = %d, %v; want 0 sessions", recovered, err)
+	}
+}
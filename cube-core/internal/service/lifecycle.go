@@ -0,0 +1,291 @@
+package service
+
+import (
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// Session lifecycle operations
+const (
+	OperationStop    = "stop"
+	OperationStart   = "start"
+	OperationPause   = "pause"
+	OperationUnpause = "unpause"
+	OperationRestart = "restart"
+)
+
+// Suspend actions
+const (
+	SuspendActionPause = OperationPause
+	SuspendActionStop  = OperationStop
+)
+
+// sessionTransition describes which statuses an operation may be applied from and the status it leads to
+type sessionTransition struct {
+	from []string
+	to   string
+}
+
+// sessionTransitions is the session state machine. Host port reservations are kept in every
+// state reachable through it; only deletion and expiry release them. Ready is treated like
+// running, and sessions with readiness checks that (re)start their container go through
+// starting again.
+var sessionTransitions = map[string]sessionTransition{
+	OperationStop: {
+		from: []string{model.SessionStatusRunning, model.SessionStatusReady, model.SessionStatusStarting,
+			model.SessionStatusFailed, model.SessionStatusPaused, model.SessionStatusExited, model.SessionStatusCrashLoop},
+		to: model.SessionStatusStopped,
+	},
+	OperationStart: {
+		from: []string{model.SessionStatusStopped, model.SessionStatusExited},
+		to:   model.SessionStatusRunning,
+	},
+	OperationPause: {
+		from: []string{model.SessionStatusRunning, model.SessionStatusReady},
+		to:   model.SessionStatusPaused,
+	},
+	OperationUnpause: {
+		from: []string{model.SessionStatusPaused},
+		to:   model.SessionStatusRunning,
+	},
+	OperationRestart: {
+		from: []string{model.SessionStatusRunning, model.SessionStatusReady, model.SessionStatusStarting,
+			model.SessionStatusFailed, model.SessionStatusStopped, model.SessionStatusExited, model.SessionStatusCrashLoop},
+		to: model.SessionStatusRunning,
+	},
+}
+
+// ApplySessionOperation runs a lifecycle operation on a session, rejecting transitions the
+// state machine does not allow with util.ErrOperationNotValid
+func (ss *SessionService) ApplySessionOperation(sessionID, op string) (*model.Session, error) {
+	return ss.transitionSession(sessionID, op, "")
+}
+
+// SuspendSession pauses or stops a running session's container while keeping its host
+// ports reserved, recording reason in the session status
+func (ss *SessionService) SuspendSession(sessionID, action, reason string) (*model.Session, error) {
+	if action != SuspendActionPause && action != SuspendActionStop {
+		return nil, util.WrapError(util.ErrInvalidRequest, "unknown suspend action %q", action)
+	}
+
+	ss.mu.Lock()
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		ss.mu.Unlock()
+		return nil, util.ErrNotFound
+	}
+
+	// Only running sessions are suspended; a paused session is already suspended
+	if !isRunningStatus(session.Status) {
+		status := session.Status
+		ss.mu.Unlock()
+		return nil, util.WrapError(util.ErrOperationNotValid, "cannot suspend session in status %s", status)
+	}
+	ss.mu.Unlock()
+
+	session, err := ss.transitionSession(sessionID, action, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	ss.logger.Info("Suspended session %s: %s", sessionID, reason)
+	return session, nil
+}
+
+// ResumeSession brings a paused or stopped session back to running. Docker keeps the
+// container's port bindings and the ports stay reserved while suspended, so the session
+// comes back on the same host ports.
+func (ss *SessionService) ResumeSession(sessionID string) (*model.Session, error) {
+	ss.mu.Lock()
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		ss.mu.Unlock()
+		return nil, util.ErrNotFound
+	}
+	status := session.Status
+	ss.mu.Unlock()
+
+	switch status {
+	case model.SessionStatusPaused:
+		return ss.transitionSession(sessionID, OperationUnpause, "")
+	case model.SessionStatusStopped:
+		return ss.transitionSession(sessionID, OperationStart, "")
+	default:
+		return nil, util.WrapError(util.ErrOperationNotValid, "cannot resume session in status %s", status)
+	}
+}
+
+// transitionSession validates and performs a lifecycle operation. The session is marked as
+// in transition while its containers are operated on without holding ss.mu, so stopping a
+// large stack does not hold up every other request; a second operation meanwhile is
+// rejected with util.ErrOperationNotValid.
+func (ss *SessionService) transitionSession(sessionID, op, reason string) (*model.Session, error) {
+	transition, ok := sessionTransitions[op]
+	if !ok {
+		return nil, util.WrapError(util.ErrInvalidRequest, "unknown session operation %q", op)
+	}
+
+	ss.mu.Lock()
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		ss.mu.Unlock()
+		return nil, util.ErrNotFound
+	}
+	if current := ss.transitions[sessionID]; current != "" {
+		ss.mu.Unlock()
+		return nil, util.WrapError(util.ErrOperationNotValid, "session %s is busy with %s", sessionID, current)
+	}
+	if !contains(transition.from, session.Status) {
+		status := session.Status
+		ss.mu.Unlock()
+		return nil, util.WrapError(util.ErrOperationNotValid, "cannot %s session in status %s", op, status)
+	}
+
+	// Whatever happens to the container, an ongoing readiness probe no longer applies
+	ss.stopReadinessProbe(sessionID)
+	ss.transitions[sessionID] = op
+	snapshot := *session
+	ss.mu.Unlock()
+
+	opErr := ss.applyContainerOperation(&snapshot, op)
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.transitions, sessionID)
+
+	// The session may have been deleted while its containers were busy
+	session, exists = ss.sessions[sessionID]
+	if !exists {
+		if opErr != nil {
+			return nil, util.WrapError(opErr, "failed to %s container", op)
+		}
+		return nil, util.WrapError(util.ErrNotFound, "session %s was deleted during %s", sessionID, op)
+	}
+
+	if opErr != nil {
+		if session.Status == model.SessionStatusStarting {
+			ss.startReadinessProbe(session)
+		}
+		return nil, util.WrapError(opErr, "failed to %s container", op)
+	}
+
+	session.Status = transition.to
+	session.StatusReason = reason
+	if transition.to == model.SessionStatusRunning {
+		// Coming back counts as activity so the idle monitor does not suspend it straight away
+		now := time.Now()
+		session.LastActivityAt = &now
+
+		// Unpaused processes pick up where they were; (re)started ones have to become ready again
+		session.Status = runningStatus(session)
+		if session.Readiness != nil && op != OperationUnpause {
+			ss.startReadinessProbe(session)
+		}
+	}
+
+	if err := ss.persistSession(session); err != nil {
+		ss.logger.Warn("Status of session %s will not survive a restart: %v", sessionID, err)
+	}
+
+	ss.logger.Info("Session %s is now %s after %s", sessionID, session.Status, op)
+	result := *session
+	return &result, nil
+}
+
+// applyContainerOperation performs a lifecycle operation on a session's containers. The
+// containers of a stack are started in start order and stopped in reverse. It only reads the
+// session, so callers may pass a copy and need not hold ss.mu.
+func (ss *SessionService) applyContainerOperation(session *model.Session, op string) error {
+	containerIDs := sessionContainerIDs(session)
+
+	// Restarting containers one at a time would bring dependencies back after their dependents
+	if op == OperationRestart && len(containerIDs) > 1 {
+		if err := ss.applyContainerOperation(session, OperationStop); err != nil {
+			return err
+		}
+		return ss.applyContainerOperation(session, OperationStart)
+	}
+
+	if op == OperationStop || op == OperationPause {
+		reversed := make([]string, len(containerIDs))
+		for i, containerID := range containerIDs {
+			reversed[len(containerIDs)-1-i] = containerID
+		}
+		containerIDs = reversed
+	}
+
+	for _, containerID := range containerIDs {
+		var err error
+		switch op {
+		case OperationStop:
+			// Paused containers have to be unpaused before they can be stopped
+			if session.Status == model.SessionStatusPaused {
+				if err = ss.dockerManager.UnpauseContainer(containerID); err != nil {
+					break
+				}
+			}
+			ss.logger.Info("Stopping container %s for session %s", containerID, session.ID)
+			err = ss.dockerManager.StopContainer(containerID)
+		case OperationStart:
+			ss.logger.Info("Starting container %s for session %s", containerID, session.ID)
+			err = ss.dockerManager.StartContainer(containerID)
+		case OperationPause:
+			ss.logger.Info("Pausing container %s for session %s", containerID, session.ID)
+			err = ss.dockerManager.PauseContainer(containerID)
+		case OperationUnpause:
+			ss.logger.Info("Unpausing container %s for session %s", containerID, session.ID)
+			err = ss.dockerManager.UnpauseContainer(containerID)
+		case OperationRestart:
+			ss.logger.Info("Restarting container %s for session %s", containerID, session.ID)
+			err = ss.dockerManager.RestartContainer(containerID)
+		}
+		if err != nil {
+			ss.logger.Error("Failed to %s container %s: %v", op, containerID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// runningSessions returns a snapshot of the sessions currently running
+func (ss *SessionService) runningSessions() []model.Session {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	sessions := make([]model.Session, 0, len(ss.sessions))
+	for _, session := range ss.sessions {
+		if isRunningStatus(session.Status) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions
+}
+
+// activitySaveInterval is how often the activity of a busy session is written to the
+// session store. Status changes save it too; after a restart the idle timer of a session
+// may start up to this much early.
+const activitySaveInterval = 5 * time.Minute
+
+// recordActivity updates the time activity was last seen in a session. It is kept in memory
+// and only saved every activitySaveInterval, since saving rewrites the whole session store.
+func (ss *SessionService) recordActivity(sessionID string, at time.Time) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		return
+	}
+
+	session.LastActivityAt = &at
+	if saved, ok := ss.activitySaved[sessionID]; ok && at.Sub(saved) < activitySaveInterval {
+		return
+	}
+
+	ss.activitySaved[sessionID] = at
+	if err := ss.persistSession(session); err != nil {
+		ss.logger.Warn("Activity of session %s will not survive a restart: %v", sessionID, err)
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestSessionTransitions(t *testing.T) {
+	tests := []struct {
+		op   string
+		from string
+		// want is the status after the operation; empty means it is rejected
+		want string
+	}{
+		{op: OperationStop, from: model.SessionStatusRunning, want: model.SessionStatusStopped},
+		{op: OperationStop, from: model.SessionStatusReady, want: model.SessionStatusStopped},
+		{op: OperationStop, from: model.SessionStatusPaused, want: model.SessionStatusStopped},
+		{op: OperationStop, from: model.SessionStatusCrashLoop, want: model.SessionStatusStopped},
+		{op: OperationStop, from: model.SessionStatusStopped},
+		{op: OperationStop, from: model.SessionStatusProvisioning},
+		{op: OperationStop, from: model.SessionStatusExpired},
+		{op: OperationStart, from: model.SessionStatusStopped, want: model.SessionStatusRunning},
+		{op: OperationStart, from: model.SessionStatusExited, want: model.SessionStatusRunning},
+		{op: OperationStart, from: model.SessionStatusRunning},
+		{op: OperationStart, from: model.SessionStatusPaused},
+		{op: OperationPause, from: model.SessionStatusRunning, want: model.SessionStatusPaused},
+		{op: OperationPause, from: model.SessionStatusReady, want: model.SessionStatusPaused},
+		{op: OperationPause, from: model.SessionStatusStarting},
+		{op: OperationPause, from: model.SessionStatusStopped},
+		{op: OperationUnpause, from: model.SessionStatusPaused, want: model.SessionStatusRunning},
+		{op: OperationUnpause, from: model.SessionStatusRunning},
+		{op: OperationRestart, from: model.SessionStatusRunning, want: model.SessionStatusRunning},
+		{op: OperationRestart, from: model.SessionStatusFailed, want: model.SessionStatusRunning},
+		{op: OperationRestart, from: model.SessionStatusStopped, want: model.SessionStatusRunning},
+		{op: OperationRestart, from: model.SessionStatusPaused},
+		{op: OperationRestart, from: model.SessionStatusError},
+		{op: OperationRestart, from: model.SessionStatusExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op+" from "+tt.from, func(t *testing.T) {
+			_, ss := newTestService(t, nil)
+			// Without containers only the state machine is exercised
+			ss.sessions["s1"] = &model.Session{ID: "s1", Status: tt.from, StatusReason: "before"}
+
+			session, err := ss.ApplySessionOperation("s1", tt.op)
+			if tt.want == "" {
+				if !errors.Is(err, util.ErrOperationNotValid) {
+					t.Fatalf("ApplySessionOperation = %v, want ErrOperationNotValid", err)
+				}
+				if status := ss.sessions["s1"].Status; status != tt.from {
+					t.Fatalf("rejected operation changed the status to %s", status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplySessionOperation: %v", err)
+			}
+			if session.Status != tt.want || session.StatusReason != "" {
+				t.Fatalf("session is %s (%q), want %s", session.Status, session.StatusReason, tt.want)
+			}
+			if stored := ss.sessions["s1"]; stored.Status != tt.want {
+				t.Fatalf("stored session is %s, want %s", stored.Status, tt.want)
+			}
+			if _, busy := ss.transitions["s1"]; busy {
+				t.Fatal("session is still marked in transition")
+			}
+		})
+	}
+}
+
+func TestTransitionSessionRejections(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID string
+		op        string
+		busyWith  string
+		wantErr   error
+	}{
+		{name: "unknown operation", sessionID: "s1", op: "freeze", wantErr: util.ErrInvalidRequest},
+		{name: "missing session", sessionID: "missing", op: OperationStop, wantErr: util.ErrNotFound},
+		{name: "busy with another operation", sessionID: "s1", op: OperationStop, busyWith: OperationRestart, wantErr: util.ErrOperationNotValid},
+		{name: "busy with expiry", sessionID: "s1", op: OperationPause, busyWith: operationExpire, wantErr: util.ErrOperationNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ss := newTestService(t, nil)
+			ss.sessions["s1"] = &model.Session{ID: "s1", Status: model.SessionStatusRunning}
+			if tt.busyWith != "" {
+				ss.transitions["s1"] = tt.busyWith
+			}
+
+			if _, err := ss.ApplySessionOperation(tt.sessionID, tt.op); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ApplySessionOperation = %v, want %v", err, tt.wantErr)
+			}
+			if status := ss.sessions["s1"].Status; status != model.SessionStatusRunning {
+				t.Fatalf("status changed to %s", status)
+			}
+			if ss.transitions["s1"] != tt.busyWith {
+				t.Fatalf("transition = %q, want %q", ss.transitions["s1"], tt.busyWith)
+			}
+		})
+	}
+}
+
+func TestApplySessionOperationToContainer(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest"})
+
+	steps := []struct {
+		op              string
+		wantStatus      string
+		containerStatus string
+	}{
+		{op: OperationPause, wantStatus: model.SessionStatusPaused, containerStatus: "paused"},
+		// Stopping a paused session unpauses its container first
+		{op: OperationStop, wantStatus: model.SessionStatusStopped, containerStatus: "exited"},
+		{op: OperationStart, wantStatus: model.SessionStatusRunning, containerStatus: "running"},
+		{op: OperationRestart, wantStatus: model.SessionStatusRunning, containerStatus: "running"},
+	}
+	for _, step := range steps {
+		updated, err := ss.ApplySessionOperation(session.ID, step.op)
+		if err != nil {
+			t.Fatalf("%s: %v", step.op, err)
+		}
+		if updated.Status != step.wantStatus {
+			t.Fatalf("after %s session is %s, want %s", step.op, updated.Status, step.wantStatus)
+		}
+		if status := fake.Containers()[0].Status; status != step.containerStatus {
+			t.Fatalf("after %s container is %s, want %s", step.op, status, step.containerStatus)
+		}
+	}
+
+	// A failing container operation leaves the session as it was
+	fake.SetContainerStatus(session.ContainerID, "paused", 0)
+	if _, err := ss.ApplySessionOperation(session.ID, OperationPause); err == nil {
+		t.Fatal("pausing a paused container succeeded")
+	}
+	if status := ss.sessions[session.ID].Status; status != model.SessionStatusRunning {
+		t.Fatalf("failed pause left the session %s, want running", status)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	timetypes "github.com/docker/docker/api/types/time"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// SessionLogs opens the log stream of a session's container
+func (ss *SessionService) SessionLogs(ctx context.Context, sessionID string, opts model.LogOptions) (*docker.LogStream, error) {
+	ss.mu.Lock()
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		ss.mu.Unlock()
+		return nil, util.ErrNotFound
+	}
+	containerID := session.ContainerID
+	ss.mu.Unlock()
+
+	if containerID == "" {
+		return nil, util.WrapError(util.ErrOperationNotValid, "session %s has no container", sessionID)
+	}
+
+	return ss.ContainerLogs(ctx, containerID, opts)
+}
+
+// ContainerLogs opens the log stream of any container
+func (ss *SessionService) ContainerLogs(ctx context.Context, containerID string, opts model.LogOptions) (*docker.LogStream, error) {
+	if err := validateLogOptions(opts); err != nil {
+		return nil, err
+	}
+
+	stream, err := ss.dockerManager.ContainerLogs(ctx, containerID, docker.LogOptions{
+		Follow:     opts.Follow,
+		Since:      opts.Since,
+		Tail:       opts.Tail,
+		Timestamps: opts.Timestamps,
+		Stdout:     opts.Stdout,
+		Stderr:     opts.Stderr,
+	})
+	if err != nil {
+		if docker.IsNotFound(err) {
+			return nil, util.WrapError(util.ErrNotFound, "container %s not found", containerID)
+		}
+		ss.logger.Error("Failed to open logs of container %s: %v", containerID, err)
+		return nil, err
+	}
+	return stream, nil
+}
+
+// validateLogOptions checks the tail, since and stream selection of a logs request
+func validateLogOptions(opts model.LogOptions) error {
+	if opts.Tail != "" && opts.Tail != "all" {
+		if n, err := strconv.Atoi(opts.Tail); err != nil || n < 0 {
+			return util.WrapError(util.ErrInvalidRequest, "tail must be a non-negative number or \"all\"")
+		}
+	}
+	if opts.Since != "" {
+		if _, err := timetypes.GetTimestamp(opts.Since, time.Now()); err != nil {
+			return util.WrapError(util.ErrInvalidRequest, "invalid since %q", opts.Since)
+		}
+	}
+	if !opts.Stdout && !opts.Stderr {
+		return util.WrapError(util.ErrInvalidRequest, "at least one of stdout and stderr must be selected")
+	}
+	return nil
+}
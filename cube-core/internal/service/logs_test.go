@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestValidateLogOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    model.LogOptions
+		wantErr bool
+	}{
+		{name: "both streams", opts: model.LogOptions{Stdout: true, Stderr: true}},
+		{name: "stderr only", opts: model.LogOptions{Stderr: true}},
+		{name: "tail all", opts: model.LogOptions{Stdout: true, Tail: "all"}},
+		{name: "tail count", opts: model.LogOptions{Stdout: true, Tail: "100"}},
+		{name: "tail zero", opts: model.LogOptions{Stdout: true, Tail: "0"}},
+		{name: "since duration", opts: model.LogOptions{Stdout: true, Since: "10m"}},
+		{name: "since timestamp", opts: model.LogOptions{Stdout: true, Since: "2024-05-01T12:00:00Z"}},
+		{name: "since unix time", opts: model.LogOptions{Stdout: true, Since: "1714564800"}},
+		{name: "no streams", opts: model.LogOptions{}, wantErr: true},
+		{name: "negative tail", opts: model.LogOptions{Stdout: true, Tail: "-5"}, wantErr: true},
+		{name: "tail word", opts: model.LogOptions{Stdout: true, Tail: "some"}, wantErr: true},
+		{name: "bad since", opts: model.LogOptions{Stdout: true, Since: "yesterday"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLogOptions(tt.opts)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateLogOptions = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("validateLogOptions = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestSessionLogs(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest"})
+	fake.SetContainerLogs(session.ContainerID, "listening on :80\n", "warning: no config\n")
+
+	tests := []struct {
+		name       string
+		opts       model.LogOptions
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "both streams", opts: model.LogOptions{Stdout: true, Stderr: true}, wantStdout: "listening on :80\n", wantStderr: "warning: no config\n"},
+		{name: "stdout only", opts: model.LogOptions{Stdout: true}, wantStdout: "listening on :80\n"},
+		{name: "stderr only", opts: model.LogOptions{Stderr: true}, wantStderr: "warning: no config\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := ss.SessionLogs(context.Background(), session.ID, tt.opts)
+			if err != nil {
+				t.Fatalf("SessionLogs: %v", err)
+			}
+			defer stream.Close()
+
+			var stdout, stderr bytes.Buffer
+			if err := stream.Copy(&stdout, &stderr); err != nil {
+				t.Fatalf("Copy: %v", err)
+			}
+			if stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr {
+				t.Fatalf("stdout %q, stderr %q; want %q, %q", stdout.String(), stderr.String(), tt.wantStdout, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestSessionLogsErrors(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	gone := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest"})
+	if err := ss.dockerManager.RemoveContainer(gone.ContainerID); err != nil {
+		t.Fatalf("RemoveContainer: %v", err)
+	}
+	ss.sessions["provisioning"] = &model.Session{ID: "provisioning", Status: model.SessionStatusProvisioning}
+	both := model.LogOptions{Stdout: true, Stderr: true}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		opts      model.LogOptions
+		wantErr   error
+	}{
+		{name: "unknown session", sessionID: "missing", opts: both, wantErr: util.ErrNotFound},
+		{name: "no container yet", sessionID: "provisioning", opts: both, wantErr: util.ErrOperationNotValid},
+		{name: "container gone", sessionID: gone.ID, opts: both, wantErr: util.ErrNotFound},
+		{name: "invalid options", sessionID: gone.ID, opts: model.LogOptions{Stdout: true, Tail: "-1"}, wantErr: util.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := ss.SessionLogs(context.Background(), tt.sessionID, tt.opts)
+			if err == nil {
+				stream.Close()
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SessionLogs = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -157,6 +157,7 @@ func (ms *MetricsService) GetContainerMetrics(ctx context.Context) ([]model.Cont
 			BlockIO:       stats.BlockIO,
 			RestartCount:  inspect.RestartCount,
 			Status:        inspect.State.Status,
+			Health:        healthStatusFromDocker(docker.ContainerHealth(inspect.State.Health)),
 			Uptime:        uptime,
 			UptimeDisplay: uptimeDisplay,
 			Timestamp:     time.Now().Unix(),
@@ -174,12 +175,14 @@ func (ms *MetricsService) GetContainerMetricsForSession(ctx context.Context, ses
 
 	// Find session
 	sessions := ms.sessionService.ListSessions()
+	var session *model.Session
 	var containerID string
 	var containerName string
 
-	for _, session := range sessions {
-		if session.ID == sessionID {
-			containerID = session.ContainerID
+	for _, s := range sessions {
+		if s.ID == sessionID {
+			session = s
+			containerID = s.ContainerID
 			break
 		}
 	}
@@ -237,11 +240,22 @@ func (ms *MetricsService) GetContainerMetricsForSession(ctx context.Context, ses
 		BlockIO:       stats.BlockIO,
 		RestartCount:  inspect.RestartCount,
 		Status:        containerStatus,
+		Health:        healthStatusFromDocker(docker.ContainerHealth(inspect.State.Health)),
 		Uptime:        uptime,
 		UptimeDisplay: uptimeDisplay,
 		Timestamp:     time.Now().Unix(),
 	}
 
+	// Policy violations are counted by the session's egress proxy
+	if session.EgressProxyID != "" {
+		egressMetrics, err := ms.sessionService.egressMetrics(ctxWithTimeout, session)
+		if err != nil {
+			ms.logger.Warn("Failed to get egress metrics for session %s: %v", sessionID, err)
+		} else {
+			metrics.Egress = egressMetrics
+		}
+	}
+
 	return metrics, nil
 }
 
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// sessionVolumePrefix starts the names of the volumes created for sessions
+const sessionVolumePrefix = "cube-session-"
+
+// sessionVolumeName returns the name of the named volume created for a session
+func sessionVolumeName(sessionID string) string {
+	return sessionVolumePrefix + sessionID
+}
+
+// validateMounts checks mount specifications, restricting bind mounts to the configured allowlist
+func (ss *SessionService) validateMounts(mounts []model.Mount, sessionVolume *model.SessionVolume) error {
+	targets := make(map[string]bool, len(mounts)+1)
+	checkTarget := func(target string) error {
+		if !filepath.IsAbs(target) {
+			return util.WrapError(util.ErrInvalidRequest, "mount target %q must be an absolute path", target)
+		}
+		target = filepath.Clean(target)
+		if target == "/" {
+			return util.WrapError(util.ErrInvalidRequest, "mount target must not be /")
+		}
+		if targets[target] {
+			return util.WrapError(util.ErrInvalidRequest, "duplicate mount target %q", target)
+		}
+		targets[target] = true
+		return nil
+	}
+
+	for _, m := range mounts {
+		if err := checkTarget(m.Target); err != nil {
+			return err
+		}
+
+		switch m.Type {
+		case model.MountTypeVolume:
+			if m.Source == "" || strings.ContainsAny(m.Source, "/\\") {
+				return util.WrapError(util.ErrInvalidRequest, "volume mount for %q needs a volume name as source", m.Target)
+			}
+			// Session volumes belong to their session; mounting one would expose another session's data
+			if strings.HasPrefix(m.Source, sessionVolumePrefix) {
+				return util.WrapError(util.ErrInvalidRequest, "volume %s is a session volume and cannot be mounted", m.Source)
+			}
+		case model.MountTypeBind:
+			if err := ss.checkBindSource(m.Source); err != nil {
+				return err
+			}
+		case model.MountTypeTmpfs:
+			if m.Source != "" {
+				return util.WrapError(util.ErrInvalidRequest, "tmpfs mount for %q must not have a source", m.Target)
+			}
+			if m.TmpfsSizeBytes < 0 {
+				return util.WrapError(util.ErrInvalidRequest, "tmpfs_size_bytes must not be negative")
+			}
+		default:
+			return util.WrapError(util.ErrInvalidRequest, "unknown mount type %q", m.Type)
+		}
+	}
+
+	if sessionVolume != nil {
+		if err := checkTarget(sessionVolume.Target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkBindSource ensures a bind mount source lies inside one of the allowed host paths
+func (ss *SessionService) checkBindSource(source string) error {
+	if !filepath.IsAbs(source) {
+		return util.WrapError(util.ErrInvalidRequest, "bind mount source %q must be an absolute path", source)
+	}
+
+	// Resolve symlinks where possible so a link cannot point outside the allowlist
+	resolved := filepath.Clean(source)
+	if evaluated, err := filepath.EvalSymlinks(resolved); err == nil {
+		resolved = evaluated
+	} else if !os.IsNotExist(err) {
+		return util.WrapError(util.ErrInvalidRequest, "cannot resolve bind mount source %q", source)
+	}
+
+	for _, allowed := range ss.cfg.AllowedBindPaths {
+		// The allowed paths may be symlinks themselves, e.g. /tmp on macOS
+		allowed = filepath.Clean(allowed)
+		if evaluated, err := filepath.EvalSymlinks(allowed); err == nil {
+			allowed = evaluated
+		}
+		if resolved == allowed || strings.HasPrefix(resolved, allowed+string(filepath.Separator)) {
+			return nil
+		}
+	}
+
+	return util.WrapError(util.ErrInvalidRequest, "bind mount source %q is not in an allowed host path", source)
+}
+
+// dockerMounts converts session mounts, plus the per-session volume if any, to Docker mount specs
+func dockerMounts(mounts []model.Mount, sessionVolume *model.SessionVolume) []docker.MountSpec {
+	var result []docker.MountSpec
+	for _, m := range mounts {
+		source := m.Source
+		if m.Type == model.MountTypeBind {
+			source = filepath.Clean(source)
+		}
+		result = append(result, docker.MountSpec{
+			Type:           m.Type,
+			Source:         source,
+			Target:         filepath.Clean(m.Target),
+			ReadOnly:       m.ReadOnly,
+			TmpfsSizeBytes: m.TmpfsSizeBytes,
+		})
+	}
+
+	if sessionVolume != nil {
+		result = append(result, docker.MountSpec{
+			Type:   model.MountTypeVolume,
+			Source: sessionVolume.Name,
+			Target: filepath.Clean(sessionVolume.Target),
+		})
+	}
+
+	return result
+}
+
+// removeSessionVolume removes the session's named volume unless it should be retained. It
+// only reads the session, so callers need not hold ss.mu.
+func (ss *SessionService) removeSessionVolume(session *model.Session) {
+	if session.SessionVolume == nil || session.SessionVolume.Retain {
+		return
+	}
+
+	ss.logger.Info("Removing volume %s for session %s", session.SessionVolume.Name, session.ID)
+	if err := ss.dockerManager.RemoveVolume(session.SessionVolume.Name); err != nil {
+		ss.logger.Warn("Failed to remove volume %s: %v", session.SessionVolume.Name, err)
+	}
+}
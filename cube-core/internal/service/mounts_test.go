@@ -0,0 +1,157 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestValidateMounts(t *testing.T) {
+	allowed := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		mounts        []model.Mount
+		sessionVolume *model.SessionVolume
+		wantErr       bool
+	}{
+		{name: "none"},
+		{name: "named volume", mounts: []model.Mount{{Type: model.MountTypeVolume, Source: "cache", Target: "/cache"}}},
+		{name: "tmpfs", mounts: []model.Mount{{Type: model.MountTypeTmpfs, Target: "/tmp", TmpfsSizeBytes: 64 << 20}}},
+		{name: "allowed bind", mounts: []model.Mount{{Type: model.MountTypeBind, Source: allowed, Target: "/src", ReadOnly: true}}},
+		{name: "bind below allowed path", mounts: []model.Mount{{Type: model.MountTypeBind, Source: filepath.Join(allowed, "not-yet-created"), Target: "/src"}}},
+		{name: "session volume", sessionVolume: &model.SessionVolume{Target: "/data"}},
+		{name: "relative target", mounts: []model.Mount{{Type: model.MountTypeTmpfs, Target: "tmp"}}, wantErr: true},
+		{name: "root target", mounts: []model.Mount{{Type: model.MountTypeTmpfs, Target: "/"}}, wantErr: true},
+		{
+			name: "duplicate target",
+			mounts: []model.Mount{
+				{Type: model.MountTypeTmpfs, Target: "/data"},
+				{Type: model.MountTypeVolume, Source: "cache", Target: "/data/"},
+			},
+			wantErr: true,
+		},
+		{
+			name:          "session volume on a mount target",
+			mounts:        []model.Mount{{Type: model.MountTypeTmpfs, Target: "/data"}},
+			sessionVolume: &model.SessionVolume{Target: "/data"},
+			wantErr:       true,
+		},
+		{name: "unknown type", mounts: []model.Mount{{Type: "nfs", Source: "x", Target: "/x"}}, wantErr: true},
+		{name: "volume without name", mounts: []model.Mount{{Type: model.MountTypeVolume, Target: "/cache"}}, wantErr: true},
+		{name: "volume name with slash", mounts: []model.Mount{{Type: model.MountTypeVolume, Source: "a/b", Target: "/cache"}}, wantErr: true},
+		{name: "another session's volume", mounts: []model.Mount{{Type: model.MountTypeVolume, Source: sessionVolumeName("other"), Target: "/data"}}, wantErr: true},
+		{name: "tmpfs with source", mounts: []model.Mount{{Type: model.MountTypeTmpfs, Source: "x", Target: "/tmp"}}, wantErr: true},
+		{name: "negative tmpfs size", mounts: []model.Mount{{Type: model.MountTypeTmpfs, Target: "/tmp", TmpfsSizeBytes: -1}}, wantErr: true},
+		{name: "bind outside allowed paths", mounts: []model.Mount{{Type: model.MountTypeBind, Source: outside, Target: "/src"}}, wantErr: true},
+		{name: "bind with relative source", mounts: []model.Mount{{Type: model.MountTypeBind, Source: "src", Target: "/src"}}, wantErr: true},
+		{name: "bind through dot dot", mounts: []model.Mount{{Type: model.MountTypeBind, Source: allowed + "/../", Target: "/src"}}, wantErr: true},
+		{name: "bind through symlink", mounts: []model.Mount{{Type: model.MountTypeBind, Source: filepath.Join(allowed, "escape"), Target: "/src"}}, wantErr: true},
+		{name: "bind sharing a prefix", mounts: []model.Mount{{Type: model.MountTypeBind, Source: allowed + "-other", Target: "/src"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &SessionService{cfg: &config.Config{AllowedBindPaths: []string{allowed}}}
+			err := ss.validateMounts(tt.mounts, tt.sessionVolume)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateMounts = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("validateMounts = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestDockerMounts(t *testing.T) {
+	tests := []struct {
+		name          string
+		mounts        []model.Mount
+		sessionVolume *model.SessionVolume
+		want          []docker.MountSpec
+	}{
+		{name: "none"},
+		{
+			name: "cleaned paths",
+			mounts: []model.Mount{
+				{Type: model.MountTypeBind, Source: "/srv/app/", Target: "/app/", ReadOnly: true},
+				{Type: model.MountTypeTmpfs, Target: "/tmp", TmpfsSizeBytes: 1024},
+			},
+			want: []docker.MountSpec{
+				{Type: model.MountTypeBind, Source: "/srv/app", Target: "/app", ReadOnly: true},
+				{Type: model.MountTypeTmpfs, Target: "/tmp", TmpfsSizeBytes: 1024},
+			},
+		},
+		{
+			name:          "session volume last",
+			mounts:        []model.Mount{{Type: model.MountTypeVolume, Source: "cache", Target: "/cache"}},
+			sessionVolume: &model.SessionVolume{Name: "cube-session-s1", Target: "/data/"},
+			want: []docker.MountSpec{
+				{Type: model.MountTypeVolume, Source: "cache", Target: "/cache"},
+				{Type: model.MountTypeVolume, Source: "cube-session-s1", Target: "/data"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dockerMounts(tt.mounts, tt.sessionVolume); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("dockerMounts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionVolumeLifetime(t *testing.T) {
+	tests := []struct {
+		name       string
+		retain     bool
+		wantVolume bool
+	}{
+		{name: "removed with the session"},
+		{name: "retained", retain: true, wantVolume: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, ss := newTestService(t, nil)
+			fake.AddImage("postgres:16")
+
+			session := createTestSession(t, ss, &model.CreateSessionRequest{
+				ImageName:     "postgres:16",
+				Mounts:        []model.Mount{{Type: model.MountTypeTmpfs, Target: "/run", TmpfsSizeBytes: 1 << 20}},
+				SessionVolume: &model.SessionVolume{Target: "/var/lib/postgresql/data", Retain: tt.retain},
+			})
+			volumeName := sessionVolumeName(session.ID)
+			if session.SessionVolume == nil || session.SessionVolume.Name != volumeName {
+				t.Fatalf("session volume = %+v, want %s", session.SessionVolume, volumeName)
+			}
+			if volumes := fake.Volumes(); !reflect.DeepEqual(volumes, []string{volumeName}) {
+				t.Fatalf("volumes = %v, want %s", volumes, volumeName)
+			}
+
+			mounts := fake.Containers()[0].Mounts
+			if len(mounts) != 2 || mounts[0].Type != mount.TypeTmpfs || mounts[0].TmpfsOptions == nil ||
+				mounts[0].TmpfsOptions.SizeBytes != 1<<20 || mounts[1].Source != volumeName {
+				t.Fatalf("container mounts = %+v", mounts)
+			}
+
+			if err := ss.DeleteSession(session.ID); err != nil {
+				t.Fatalf("DeleteSession: %v", err)
+			}
+			if volumes := fake.Volumes(); (len(volumes) == 1) != tt.wantVolume {
+				t.Fatalf("volumes after delete = %v, want retained %v", volumes, tt.wantVolume)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// sessionNetworkName returns the name of a session's private network
+func sessionNetworkName(sessionID string) string {
+	return "cube-session-" + sessionID
+}
+
+// usesSessionNetwork reports whether a session gets a private network rather than the default bridge
+func (ss *SessionService) usesSessionNetwork(req *model.CreateSessionRequest) bool {
+	return ss.cfg.SessionNetworks || req.Stack != nil || req.InternalNetwork || req.Egress != nil
+}
+
+// validateInternalNetwork rejects what cannot work without published ports
+func validateInternalNetwork(req *model.CreateSessionRequest) error {
+	if !req.InternalNetwork {
+		return nil
+	}
+
+	if req.NumPorts > 0 || len(req.PortMappings) > 0 {
+		return util.WrapError(util.ErrInvalidRequest, "sessions on an internal network cannot publish ports")
+	}
+	if req.Stack != nil {
+		for name, service := range req.Stack.Services {
+			if len(service.Ports) > 0 {
+				return util.WrapError(util.ErrInvalidRequest, "service %s cannot publish ports on an internal network", name)
+			}
+		}
+	}
+	if req.Readiness != nil {
+		for _, check := range req.Readiness.Checks {
+			if check.Type != model.ReadinessCheckExec {
+				return util.WrapError(util.ErrInvalidRequest, "sessions on an internal network only support exec readiness checks")
+			}
+		}
+	}
+	return nil
+}
+
+// createSessionNetwork creates the private network of a session and returns its name. Sessions
+// with an egress policy get an internal network, left only through their egress proxy.
+func (ss *SessionService) createSessionNetwork(session *model.Session) (string, error) {
+	networkName := sessionNetworkName(session.ID)
+	internal := session.InternalNetwork || session.Egress != nil
+	ss.logger.Info("Creating network %s for session %s", networkName, session.ID)
+
+	labels := map[string]string{
+		docker.LabelManaged:   "true",
+		docker.LabelSessionID: session.ID,
+	}
+	if _, err := ss.dockerManager.CreateNetwork(networkName, internal, labels); err != nil {
+		ss.logger.Error("Failed to create network: %v", err)
+		return "", util.WrapError(err, "failed to create session network")
+	}
+	return networkName, nil
+}
+
+// removeSessionNetwork removes the private network of a session, once its containers are gone
+func (ss *SessionService) removeSessionNetwork(session *model.Session) {
+	if session.Network == "" {
+		return
+	}
+
+	ss.logger.Info("Removing network %s for session %s", session.Network, session.ID)
+	if err := ss.dockerManager.RemoveNetwork(session.Network); err != nil && !docker.IsNotFound(err) {
+		ss.logger.Warn("Failed to remove network %s: %v", session.Network, err)
+	}
+}
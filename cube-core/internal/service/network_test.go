@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestUsesSessionNetwork(t *testing.T) {
+	tests := []struct {
+		name            string
+		sessionNetworks bool
+		req             model.CreateSessionRequest
+		want            bool
+	}{
+		{name: "default bridge", req: model.CreateSessionRequest{ImageName: "nginx:latest"}, want: false},
+		{name: "server setting", sessionNetworks: true, req: model.CreateSessionRequest{ImageName: "nginx:latest"}, want: true},
+		{name: "stack", req: model.CreateSessionRequest{Stack: &model.StackSpec{}}, want: true},
+		{name: "internal network", req: model.CreateSessionRequest{ImageName: "nginx:latest", InternalNetwork: true}, want: true},
+		{name: "egress policy", req: model.CreateSessionRequest{ImageName: "nginx:latest", Egress: &model.EgressPolicy{Mode: "none"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &SessionService{cfg: &config.Config{SessionNetworks: tt.sessionNetworks}}
+			if got := ss.usesSessionNetwork(&tt.req); got != tt.want {
+				t.Fatalf("usesSessionNetwork = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateInternalNetwork(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     model.CreateSessionRequest
+		wantErr bool
+	}{
+		{name: "not internal", req: model.CreateSessionRequest{NumPorts: 2}},
+		{name: "internal without ports", req: model.CreateSessionRequest{InternalNetwork: true}},
+		{
+			name: "internal with exec readiness",
+			req: model.CreateSessionRequest{
+				InternalNetwork: true,
+				Readiness:       &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: model.ReadinessCheckExec, Command: []string{"true"}}}},
+			},
+		},
+		{name: "port count", req: model.CreateSessionRequest{InternalNetwork: true, NumPorts: 1}, wantErr: true},
+		{name: "port mappings", req: model.CreateSessionRequest{InternalNetwork: true, PortMappings: []model.PortMapping{{ContainerPort: 80}}}, wantErr: true},
+		{
+			name: "stack service ports",
+			req: model.CreateSessionRequest{
+				InternalNetwork: true,
+				Stack:           &model.StackSpec{Services: map[string]model.StackService{"web": {ImageName: "nginx", Ports: []model.PortMapping{{ContainerPort: 80}}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "tcp readiness",
+			req: model.CreateSessionRequest{
+				InternalNetwork: true,
+				Readiness:       &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: model.ReadinessCheckTCP, Port: 5432}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInternalNetwork(&tt.req)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateInternalNetwork = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("validateInternalNetwork = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestSessionNetworks(t *testing.T) {
+	tests := []struct {
+		name            string
+		sessionNetworks bool
+		req             model.CreateSessionRequest
+		// wantNetwork is whether the session gets its own network, and wantInternal whether
+		// that network is internal
+		wantNetwork  bool
+		wantInternal bool
+	}{
+		{name: "default bridge", req: model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1}},
+		{name: "private network", sessionNetworks: true, req: model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1}, wantNetwork: true},
+		{name: "internal network", req: model.CreateSessionRequest{ImageName: "nginx:latest", InternalNetwork: true}, wantNetwork: true, wantInternal: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, ss := newTestService(t, func(cfg *config.Config) {
+				cfg.SessionNetworks = tt.sessionNetworks
+			})
+			fake.AddImage("nginx:latest")
+
+			session := createTestSession(t, ss, &tt.req)
+			if session.Status != model.SessionStatusRunning {
+				t.Fatalf("session is %s: %s", session.Status, session.StatusReason)
+			}
+
+			wantNetwork := "bridge"
+			if tt.wantNetwork {
+				wantNetwork = sessionNetworkName(session.ID)
+			}
+			if tt.wantNetwork != (session.Network != "") {
+				t.Errorf("session network = %q, want one %v", session.Network, tt.wantNetwork)
+			}
+			internal, exists := fake.NetworkInternal(sessionNetworkName(session.ID))
+			if exists != tt.wantNetwork || internal != tt.wantInternal {
+				t.Errorf("network exists %v, internal %v; want %v, %v", exists, internal, tt.wantNetwork, tt.wantInternal)
+			}
+
+			// The containers listing shows network membership
+			containers, err := ss.ListAllContainers(context.Background())
+			if err != nil {
+				t.Fatalf("ListAllContainers: %v", err)
+			}
+			if len(containers) != 1 || len(containers[0].Networks) != 1 || containers[0].Networks[0] != wantNetwork {
+				t.Fatalf("ListAllContainers = %+v, want one container on %s", containers, wantNetwork)
+			}
+
+			if err := ss.DeleteSession(session.ID); err != nil {
+				t.Fatalf("DeleteSession: %v", err)
+			}
+			if _, exists := fake.NetworkInternal(sessionNetworkName(session.ID)); exists {
+				t.Fatal("session network was kept after delete")
+			}
+		})
+	}
+
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	if _, err := ss.CreateSession(&model.CreateSessionRequest{ImageName: "nginx:latest", InternalNetwork: true, NumPorts: 1}); !errors.Is(err, util.ErrInvalidRequest) {
+		t.Fatalf("CreateSession publishing ports on an internal network = %v, want ErrInvalidRequest", err)
+	}
+}
@@ -0,0 +1,507 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// provisionJob is a session waiting for its container to be created
+type provisionJob struct {
+	ctx       context.Context
+	sessionID string
+	req       model.CreateSessionRequest
+}
+
+// provisionedContainer holds the resources created for a session by a provisioning worker
+type provisionedContainer struct {
+	containerID   string
+	ports         []model.Port
+	network       string
+	egressProxyID string
+	// services are only set for multi-container sessions
+	services []model.ServiceContainer
+}
+
+// startProvisioners starts the worker pool that creates session containers
+func (ss *SessionService) startProvisioners(workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range ss.provisionQueue {
+				ss.provisionSession(job)
+			}
+		}()
+	}
+}
+
+// provisionSession creates and starts a session's container outside the session lock, then
+// records the outcome. If the session was deleted in the meantime, everything created for it
+// is cleaned up again.
+func (ss *SessionService) provisionSession(job provisionJob) {
+	ss.mu.Lock()
+	session, exists := ss.sessions[job.sessionID]
+	if !exists || job.ctx.Err() != nil {
+		ss.mu.Unlock()
+		return
+	}
+	session.StatusReason = "creating container"
+	if err := ss.persistSession(session); err != nil {
+		ss.logger.Warn("Provisioning progress of session %s will not survive a restart: %v", job.sessionID, err)
+	}
+	snapshot := *session
+	ss.mu.Unlock()
+
+	result, provisionErr := ss.provisionContainer(job.ctx, &snapshot, &job.req)
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	delete(ss.provisioning, job.sessionID)
+
+	session, exists = ss.sessions[job.sessionID]
+	if !exists || session.Status != model.SessionStatusProvisioning {
+		// Deleted or expired while provisioning
+		if provisionErr == nil {
+			ss.logger.Info("Session %s went away while provisioning, cleaning up", job.sessionID)
+			ss.releaseProvisioned(&snapshot, result)
+		}
+		return
+	}
+
+	if provisionErr != nil {
+		ss.logger.Error("Failed to provision session %s: %v", job.sessionID, provisionErr)
+		session.Status = model.SessionStatusError
+		session.StatusReason = provisionErr.Error()
+		if err := ss.persistSession(session); err != nil {
+			ss.logger.Warn("Failure of session %s will not survive a restart: %v", job.sessionID, err)
+		}
+		return
+	}
+
+	session.ContainerID = result.containerID
+	session.Ports = result.ports
+	if result.services != nil {
+		session.Services = result.services
+	}
+	session.Network = result.network
+	session.EgressProxyID = result.egressProxyID
+	session.Status = model.SessionStatusRunning
+	session.StatusReason = ""
+	if session.Readiness != nil {
+		ss.startReadinessProbe(session)
+	}
+
+	if err := ss.persistSession(session); err != nil {
+		// Without a durable record the container would be orphaned on restart
+		ss.logger.Error("Rolling back container %s for unpersisted session %s", result.containerID, job.sessionID)
+		ss.stopReadinessProbe(job.sessionID)
+		ss.releaseProvisioned(&snapshot, result)
+		session.ContainerID = ""
+		session.Ports = []model.Port{}
+		session.Services = snapshot.Services
+		session.Network = ""
+		session.EgressProxyID = ""
+		session.Status = model.SessionStatusError
+		session.StatusReason = err.Error()
+		return
+	}
+
+	ss.logger.Info("Created session %s for image %s", job.sessionID, session.ImageName)
+}
+
+// releaseProvisioned removes provisioned containers and frees their ports, volume and network.
+// It also rolls back partially provisioned stacks.
+func (ss *SessionService) releaseProvisioned(session *model.Session, result *provisionedContainer) {
+	provisioned := &model.Session{ContainerID: result.containerID, Services: result.services, EgressProxyID: result.egressProxyID}
+	for _, containerID := range sessionContainerIDs(provisioned) {
+		if err := ss.dockerManager.RemoveContainer(containerID); err != nil && !docker.IsNotFound(err) {
+			ss.logger.Error("Failed to remove container %s: %v", containerID, err)
+		}
+	}
+	for _, p := range result.ports {
+		ss.portManager.ReleasePort(p.HostPort)
+	}
+	ss.removeSessionVolume(session)
+	ss.removeSessionNetwork(&model.Session{ID: session.ID, Network: result.network})
+}
+
+// provisionContainer pulls the image if needed, allocates host ports and creates and starts
+// the session's container. On failure everything it allocated is released again.
+func (ss *SessionService) provisionContainer(ctx context.Context, session *model.Session, req *model.CreateSessionRequest) (*provisionedContainer, error) {
+	if req.Stack != nil {
+		return ss.provisionStack(ctx, session, req)
+	}
+
+	// Make sure the image is available before reserving anything for it
+	if err := ss.ensureImage(ctx, req.ImageName, req.RegistryAuth); err != nil {
+		return nil, err
+	}
+
+	// Docker publishes no ports of containers on an internal network
+	var portConfigs []portConfig
+	if !req.InternalNetwork {
+		var err error
+		portConfigs, err = ss.resolvePortConfigs(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkReadinessPorts(req.Readiness, portConfigs); err != nil {
+		return nil, err
+	}
+
+	// Get available host ports from the port manager
+	hostPorts := make([]int, len(portConfigs))
+	for i := range portConfigs {
+		port, err := ss.portManager.GetAvailablePort()
+		if err != nil {
+			// Release any ports we've already allocated
+			ss.logger.Error("Failed to get available port: %v", err)
+			for j := 0; j < i; j++ {
+				ss.portManager.ReleasePort(hostPorts[j])
+			}
+			return nil, util.WrapError(err, "failed to get available port")
+		}
+		hostPorts[i] = port
+	}
+
+	releasePorts := func() {
+		for _, port := range hostPorts {
+			ss.portManager.ReleasePort(port)
+		}
+	}
+
+	// Create docker port mappings
+	dockerPortMappings := make([]docker.PortMapping, len(portConfigs))
+	for i, config := range portConfigs {
+		dockerPortMappings[i] = docker.PortMapping{
+			HostPort:      hostPorts[i],
+			ContainerPort: config.ContainerPort,
+			Protocol:      config.Protocol,
+		}
+	}
+
+	// Log the ports being allocated
+	ss.logger.Info("Creating container for image %s with %d port mappings", req.ImageName, len(dockerPortMappings))
+	for i, mapping := range dockerPortMappings {
+		ss.logger.Debug("Port %d: %d -> %d/%s", i+1, mapping.HostPort, mapping.ContainerPort, mapping.Protocol)
+	}
+
+	// Create session ports
+	sessionPorts := make([]model.Port, len(portConfigs))
+	for i, config := range portConfigs {
+		proto := config.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+
+		sessionPorts[i] = model.Port{
+			HostPort:      hostPorts[i],
+			ContainerPort: config.ContainerPort,
+			Protocol:      proto,
+			Description:   config.Description,
+			Scheme:        config.Scheme,
+		}
+	}
+	ss.setPortURLs(session.ID, sessionPorts)
+
+	// Label the container so the session can be rebuilt from Docker alone
+	labelled := *session
+	labelled.Ports = sessionPorts
+	if ss.usesSessionNetwork(req) {
+		labelled.Network = sessionNetworkName(session.ID)
+	}
+	labels, err := sessionLabels(&labelled)
+	if err != nil {
+		releasePorts()
+		return nil, err
+	}
+
+	// Create the per-session volume before the container that mounts it
+	if session.SessionVolume != nil {
+		ss.logger.Info("Creating volume %s for session %s", session.SessionVolume.Name, session.ID)
+		volumeLabels := map[string]string{
+			docker.LabelManaged:   "true",
+			docker.LabelSessionID: session.ID,
+		}
+		if err := ss.dockerManager.CreateVolume(session.SessionVolume.Name, volumeLabels); err != nil {
+			ss.logger.Error("Failed to create volume: %v", err)
+			releasePorts()
+			return nil, util.WrapError(err, "failed to create session volume")
+		}
+	}
+
+	// Keep the session off the default bridge, where it could reach other sessions
+	if labelled.Network != "" {
+		if _, err := ss.createSessionNetwork(session); err != nil {
+			releasePorts()
+			ss.removeSessionVolume(session)
+			return nil, err
+		}
+	}
+
+	// With an egress policy the proxy publishes the ports, and the container has none of its own
+	var egressProxyID string
+	var aliases []string
+	if session.Egress != nil {
+		egressProxyID, err = ss.startEgressProxy(ctx, session, sessionPorts)
+		if err != nil {
+			releasePorts()
+			ss.removeSessionVolume(session)
+			ss.removeSessionNetwork(&labelled)
+			return nil, err
+		}
+		dockerPortMappings = nil
+		aliases = []string{egressSessionAlias}
+	}
+
+	restartPolicy, maxRetries := dockerRestartPolicy(session.RestartPolicy)
+
+	// Create container
+	containerID, err := ss.dockerManager.CreateContainer(docker.ContainerOptions{
+		Image:             req.ImageName,
+		PortMappings:      dockerPortMappings,
+		Labels:            labels,
+		Resources:         dockerResources(session.Resources),
+		Env:               dockerEnv(withEgressEnv(session, req.Env)),
+		Cmd:               req.Command,
+		Entrypoint:        req.Entrypoint,
+		WorkingDir:        req.WorkingDir,
+		User:              req.User,
+		Mounts:            dockerMounts(req.Mounts, session.SessionVolume),
+		RestartPolicy:     restartPolicy,
+		MaxRestartRetries: maxRetries,
+		Network:           labelled.Network,
+		NetworkAliases:    aliases,
+	})
+	if err != nil {
+		// Release all allocated ports
+		ss.logger.Error("Failed to create container: %v", err)
+		if egressProxyID != "" {
+			if err := ss.dockerManager.RemoveContainer(egressProxyID); err != nil && !docker.IsNotFound(err) {
+				ss.logger.Error("Failed to remove container %s: %v", egressProxyID, err)
+			}
+		}
+		releasePorts()
+		ss.removeSessionVolume(session)
+		ss.removeSessionNetwork(&labelled)
+		return nil, util.WrapError(err, "failed to create container")
+	}
+
+	return &provisionedContainer{
+		containerID:   containerID,
+		ports:         sessionPorts,
+		network:       labelled.Network,
+		egressProxyID: egressProxyID,
+	}, nil
+}
+
+// portConfig describes a container port to publish
+type portConfig struct {
+	ContainerPort int
+	Protocol      string
+	Description   string
+	Scheme        string
+}
+
+// resolvePortConfigs works out which container ports to publish, from the request or the image
+func (ss *SessionService) resolvePortConfigs(req *model.CreateSessionRequest) ([]portConfig, error) {
+	// First attempt: Use provided port mappings if available
+	if len(req.PortMappings) > 0 {
+		ss.logger.Info("Using explicitly defined port mappings for image %s", req.ImageName)
+		portConfigs := make([]portConfig, len(req.PortMappings))
+		for i, mapping := range req.PortMappings {
+			portConfigs[i] = portConfig{
+				ContainerPort: mapping.ContainerPort,
+				Protocol:      mapping.Protocol,
+				Description:   mapping.Description,
+				Scheme:        mapping.Scheme,
+			}
+		}
+		return portConfigs, nil
+	}
+
+	// Second attempt: Use NumPorts if specified
+	if req.NumPorts > 0 {
+		ss.logger.Info("Using specified number of ports (%d) for image %s", req.NumPorts, req.ImageName)
+		portConfigs := make([]portConfig, req.NumPorts)
+
+		for i := 0; i < req.NumPorts; i++ {
+			portConfigs[i] = portConfig{
+				ContainerPort: 8080 + i, // Generic default starting at 8080
+				Protocol:      "tcp",
+				Description:   fmt.Sprintf("Port %d", i+1),
+			}
+		}
+		return portConfigs, nil
+	}
+
+	// Final attempt: Detect exposed ports from the image
+	ss.logger.Info("Attempting to detect exposed ports for image %s", req.ImageName)
+	// The image is present locally at this point, so inspect it directly
+	exposedPorts, err := ss.dockerManager.ImageExposedPorts(req.ImageName)
+	if err != nil {
+		ss.logger.Error("Failed to inspect image %s: %v", req.ImageName, err)
+		return nil, util.WrapError(err, "failed to inspect image")
+	}
+	ss.logger.Info("Found image %s with %d exposed ports", req.ImageName, len(exposedPorts))
+
+	if len(exposedPorts) == 0 {
+		// If we couldn't detect any ports, use a default configuration
+		ss.logger.Warn("No exposed ports detected for image %s, using default HTTP port", req.ImageName)
+		return []portConfig{
+			{ContainerPort: 80, Protocol: "tcp", Description: "HTTP"},
+		}, nil
+	}
+
+	// Use the detected exposed ports
+	ss.logger.Info("Using %d exposed ports from image %s: %v", len(exposedPorts), req.ImageName, exposedPorts)
+	portConfigs := make([]portConfig, len(exposedPorts))
+
+	for i, port := range exposedPorts {
+		// Use more descriptive names for common ports
+		var description string
+		switch port {
+		case 80, 8080:
+			description = "HTTP"
+		case 443, 8443:
+			description = "HTTPS"
+		case 22:
+			description = "SSH"
+		case 3306:
+			description = "MySQL"
+		case 5432:
+			description = "PostgreSQL"
+		case 27017:
+			description = "MongoDB"
+		case 6379:
+			description = "Redis"
+		default:
+			description = fmt.Sprintf("Port %d", port)
+		}
+
+		portConfigs[i] = portConfig{
+			ContainerPort: port,
+			Protocol:      "tcp",
+			Description:   description,
+		}
+	}
+	return portConfigs, nil
+}
+
+// GetSession returns a copy of a session by ID, enriched with the live state of its container.
+// The session's status is brought in line with the container on the way.
+func (ss *SessionService) GetSession(sessionID string) (*model.Session, error) {
+	ss.mu.Lock()
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		ss.mu.Unlock()
+		return nil, util.ErrNotFound
+	}
+	containerID := session.ContainerID
+	inspect := containerID != "" && session.Status != model.SessionStatusExpired
+	result := *session
+	ss.mu.Unlock()
+
+	if !inspect {
+		return &result, nil
+	}
+
+	// Inspect failures leave the container state empty, since the session record is still valid
+	state, err := ss.dockerManager.InspectContainerState(containerID)
+	if err != nil {
+		if docker.IsNotFound(err) {
+			result.Container = &model.ContainerState{Status: "missing"}
+		} else {
+			ss.logger.Warn("Failed to inspect container %s of session %s: %v", containerID, sessionID, err)
+		}
+		return &result, nil
+	}
+
+	ss.mu.Lock()
+	if session, exists := ss.sessions[sessionID]; exists && session.ContainerID == containerID {
+		if ss.reflectContainerState(session, state) {
+			if err := ss.persistSession(session); err != nil {
+				ss.logger.Warn("Container state of session %s will not survive a restart: %v", sessionID, err)
+			}
+		}
+		result = *session
+	}
+	ss.mu.Unlock()
+
+	result.Container = containerStateFromDocker(state)
+	result.Health = healthStatusFromDocker(state.Health)
+	if len(result.Services) > 0 {
+		result.Services = ss.inspectServices(&result, result.Container)
+	}
+	return &result, nil
+}
+
+// containerStateFromDocker converts Docker container state to its API representation
+func containerStateFromDocker(state *docker.ContainerState) *model.ContainerState {
+	result := &model.ContainerState{
+		Status:       state.Status,
+		Running:      state.Running,
+		Paused:       state.Paused,
+		Restarting:   state.Restarting,
+		OOMKilled:    state.OOMKilled,
+		ExitCode:     state.ExitCode,
+		Error:        state.Error,
+		RestartCount: state.RestartCount,
+	}
+	if !state.StartedAt.IsZero() {
+		startedAt := state.StartedAt
+		result.StartedAt = &startedAt
+	}
+	if !state.FinishedAt.IsZero() {
+		finishedAt := state.FinishedAt
+		result.FinishedAt = &finishedAt
+	}
+	return result
+}
+
+// WaitForSession blocks until done reports true for the session, the session is deleted or
+// ctx ends, and returns the latest copy of the session
+func (ss *SessionService) WaitForSession(ctx context.Context, sessionID string, done func(*model.Session) bool) (*model.Session, error) {
+	for {
+		ss.mu.Lock()
+		session, exists := ss.sessions[sessionID]
+		if !exists {
+			ss.mu.Unlock()
+			return nil, util.ErrNotFound
+		}
+		result := *session
+		changed := ss.changed
+		ss.mu.Unlock()
+
+		if done(&result) {
+			return &result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return &result, nil
+		case <-changed:
+		}
+	}
+}
+
+// IsSessionSettled reports whether a session has finished provisioning and, if it has
+// readiness checks, finished starting, successfully or not
+func IsSessionSettled(session *model.Session) bool {
+	return session.Status != model.SessionStatusProvisioning && session.Status != model.SessionStatusStarting
+}
+
+// notifyChange wakes up everyone waiting for a session change. Callers must hold ss.mu.
+func (ss *SessionService) notifyChange() {
+	close(ss.changed)
+	ss.changed = make(chan struct{})
+}
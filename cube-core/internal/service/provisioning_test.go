@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// waitProvisioning waits until a provisioning worker has picked up a session
+func waitProvisioning(t *testing.T, ss *SessionService, sessionID string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	session, err := ss.WaitForSession(ctx, sessionID, func(s *model.Session) bool {
+		return s.StatusReason == "creating container"
+	})
+	if err != nil {
+		t.Fatalf("WaitForSession: %v", err)
+	}
+	if session.StatusReason != "creating container" {
+		t.Fatalf("session %s was not picked up: %s (%q)", sessionID, session.Status, session.StatusReason)
+	}
+}
+
+func TestCreateSessionProvisionsAsynchronously(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	release := fake.HoldPulls()
+	defer release()
+
+	session, err := ss.CreateSession(&model.CreateSessionRequest{ImageName: "redis:7", NumPorts: 1})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if session.Status != model.SessionStatusProvisioning || session.ContainerID != "" {
+		t.Fatalf("accepted session is %s with container %q, want provisioning without a container", session.Status, session.ContainerID)
+	}
+
+	// Waiting times out with the session as it is while the image is being pulled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	current, err := ss.WaitForSession(ctx, session.ID, IsSessionSettled)
+	if err != nil || current.Status != model.SessionStatusProvisioning {
+		t.Fatalf("WaitForSession = %+v, %v; want the provisioning session", current, err)
+	}
+
+	release()
+	settled := waitSettled(t, ss, session.ID)
+	if settled.Status != model.SessionStatusRunning || settled.StatusReason != "" {
+		t.Fatalf("session is %s (%q), want running", settled.Status, settled.StatusReason)
+	}
+	if settled.ContainerID == "" || len(settled.Ports) != 1 {
+		t.Fatalf("session has container %q and ports %+v", settled.ContainerID, settled.Ports)
+	}
+	if containers := fake.Containers(); len(containers) != 1 || containers[0].Status != "running" {
+		t.Fatalf("containers = %+v, want one running", containers)
+	}
+}
+
+func TestProvisioningFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		configure  func(cfg *config.Config)
+		req        model.CreateSessionRequest
+		wantReason string
+	}{
+		{
+			name:       "image missing without pulls",
+			configure:  func(cfg *config.Config) { cfg.PullMissingImages = false },
+			req:        model.CreateSessionRequest{ImageName: "app:1"},
+			wantReason: "app:1",
+		},
+		{
+			name:       "pull denied",
+			req:        model.CreateSessionRequest{ImageName: "private/app:1"},
+			wantReason: "denied",
+		},
+		{
+			name:       "readiness port not published",
+			req:        model.CreateSessionRequest{ImageName: "nginx:latest", Readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: "tcp", Port: 9999}}}},
+			wantReason: "9999",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, ss := newTestService(t, tt.configure)
+			fake.AddImage("nginx:latest")
+			fake.SetPullMessages("private/app:1", `{"errorDetail":{"message":"denied"},"error":"denied"}`)
+
+			session := createTestSession(t, ss, &tt.req)
+			if session.Status != model.SessionStatusError || !strings.Contains(session.StatusReason, tt.wantReason) {
+				t.Fatalf("session is %s (%q), want error mentioning %q", session.Status, session.StatusReason, tt.wantReason)
+			}
+			if containers := fake.Containers(); len(containers) != 0 {
+				t.Fatalf("containers = %+v, want none", containers)
+			}
+		})
+	}
+}
+
+func TestCreateSessionRejectsWhenQueueIsFull(t *testing.T) {
+	fake, ss := newTestService(t, func(cfg *config.Config) {
+		cfg.ProvisionWorkers = 1
+		cfg.ProvisionQueueSize = 1
+	})
+	release := fake.HoldPulls()
+	defer release()
+
+	// The only worker is busy pulling the first image and the second session fills the queue
+	first, err := ss.CreateSession(&model.CreateSessionRequest{ImageName: "redis:7"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	waitProvisioning(t, ss, first.ID)
+	second, err := ss.CreateSession(&model.CreateSessionRequest{ImageName: "redis:7"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	if _, err := ss.CreateSession(&model.CreateSessionRequest{ImageName: "redis:7"}); !errors.Is(err, util.ErrResourceBusy) {
+		t.Fatalf("CreateSession with a full queue = %v, want ErrResourceBusy", err)
+	}
+	if sessions := ss.ListSessions(); len(sessions) != 2 {
+		t.Fatalf("%d sessions, want the rejected one forgotten", len(sessions))
+	}
+
+	release()
+	for _, id := range []string{first.ID, second.ID} {
+		if session := waitSettled(t, ss, id); session.Status != model.SessionStatusRunning {
+			t.Fatalf("session %s is %s (%q), want running", id, session.Status, session.StatusReason)
+		}
+	}
+}
+
+func TestDeleteSessionWhileProvisioning(t *testing.T) {
+	fake, ss := newTestService(t, func(cfg *config.Config) {
+		cfg.ProvisionWorkers = 1
+	})
+	release := fake.HoldPulls()
+	defer release()
+
+	session, err := ss.CreateSession(&model.CreateSessionRequest{ImageName: "redis:7", NumPorts: 1})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	waitProvisioning(t, ss, session.ID)
+
+	if err := ss.DeleteSession(session.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := ss.GetSession(session.ID); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("GetSession after delete = %v, want ErrNotFound", err)
+	}
+	release()
+
+	// The single worker is done with the deleted session once it picks up the next one
+	next := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "redis:7"})
+	containers := fake.Containers()
+	if len(containers) != 1 || containers[0].ID != next.ContainerID {
+		t.Fatalf("containers = %+v, want only the next session's", containers)
+	}
+}
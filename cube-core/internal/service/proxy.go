@@ -0,0 +1,149 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// maxPortSlugLength keeps "<session-id>-<slug>" within the 63 characters of a DNS label
+const maxPortSlugLength = 63 - 36 - 1
+
+// ResolveProxyTarget returns the address the reverse proxy forwards requests for a session
+// port to. The port is named by its slug or its container port number.
+func (ss *SessionService) ResolveProxyTarget(sessionID, portKey string) (*url.URL, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		return nil, util.ErrNotFound
+	}
+
+	index := sessionPortIndex(session.Ports, portKey)
+	if index < 0 {
+		return nil, util.WrapError(util.ErrNotFound, "session %s has no port %s", sessionID, portKey)
+	}
+
+	// Sessions still becoming ready, or failing their checks, may well answer already
+	switch session.Status {
+	case model.SessionStatusRunning, model.SessionStatusReady, model.SessionStatusStarting, model.SessionStatusFailed:
+	default:
+		return nil, util.WrapError(util.ErrUnavailable, "session %s is %s", sessionID, session.Status)
+	}
+
+	return ss.portTarget(session.Ports[index]), nil
+}
+
+// portTarget returns the address the server itself reaches a published port at. Port URLs
+// are meant for clients and may point somewhere the server cannot reach.
+func (ss *SessionService) portTarget(p model.Port) *url.URL {
+	return &url.URL{
+		Scheme: portScheme(p),
+		Host:   net.JoinHostPort(ss.cfg.ProxyTargetHost, strconv.Itoa(p.HostPort)),
+	}
+}
+
+// proxyPortURL returns the URL of a session port on the reverse proxy
+func (ss *SessionService) proxyPortURL(sessionID, slug string) string {
+	public := ss.proxyPublicURL()
+	u := *public
+	if ss.cfg.ProxyBaseDomain != "" {
+		u.Host = fmt.Sprintf("%s-%s.%s", sessionID, slug, ss.cfg.ProxyBaseDomain)
+		if port := public.Port(); port != "" {
+			u.Host = net.JoinHostPort(u.Host, port)
+		}
+		u.Path = "/"
+	} else {
+		u.Path = strings.TrimSuffix(public.Path, "/") + fmt.Sprintf("/s/%s/%s/", sessionID, slug)
+	}
+	return u.String()
+}
+
+// proxyPublicURL returns the URL clients reach the reverse proxy at
+func (ss *SessionService) proxyPublicURL() *url.URL {
+	if ss.cfg.ProxyPublicURL != "" {
+		if u, err := url.Parse(ss.cfg.ProxyPublicURL); err == nil && u.Host != "" {
+			return u
+		}
+		ss.logger.Warn("Ignoring invalid proxy public URL %q", ss.cfg.ProxyPublicURL)
+	}
+	return &url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(ss.publicHost(), strconv.Itoa(ss.cfg.ProxyPort)),
+	}
+}
+
+// portSlugs names the ports of a session for proxy routes, after their description, or
+// their service in a stack. Names that would be ambiguous get the container port appended.
+func portSlugs(ports []model.Port) []string {
+	slugs := make([]string, len(ports))
+	counts := make(map[string]int, len(ports))
+	for i, p := range ports {
+		slug := slugify(p.Description)
+		if slug == "" {
+			slug = slugify(p.Service)
+		}
+		if slug == "" {
+			slug = "port"
+		}
+		slugs[i] = slug
+		counts[slug]++
+	}
+
+	for i, p := range ports {
+		if counts[slugs[i]] > 1 {
+			suffix := "-" + strconv.Itoa(p.ContainerPort)
+			slugs[i] = strings.TrimSuffix(truncate(slugs[i], maxPortSlugLength-len(suffix)), "-") + suffix
+		}
+	}
+	return slugs
+}
+
+// sessionPortIndex finds a session port by slug or container port number, or returns -1
+func sessionPortIndex(ports []model.Port, key string) int {
+	key = strings.ToLower(key)
+	for i, slug := range portSlugs(ports) {
+		if slug == key && ports[i].Protocol == "tcp" {
+			return i
+		}
+	}
+
+	if containerPort, err := strconv.Atoi(key); err == nil {
+		for i, p := range ports {
+			if p.ContainerPort == containerPort && p.Protocol == "tcp" {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// slugify lowercases a name and replaces everything but letters and digits with dashes
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.Trim(truncate(b.String(), maxPortSlugLength), "-")
+}
+
+// truncate shortens an ASCII string to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "", want: ""},
+		{name: "web", want: "web"},
+		{name: "Web UI", want: "web-ui"},
+		{name: "  Admin -- Panel!  ", want: "admin-panel"},
+		{name: "Grafana (v10)", want: "grafana-v10"},
+		{name: "Café", want: "caf"},
+		{name: "---", want: ""},
+		{name: strings.Repeat("a", 40), want: strings.Repeat("a", maxPortSlugLength)},
+		{name: strings.Repeat("a", maxPortSlugLength-1) + " b", want: strings.Repeat("a", maxPortSlugLength-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slugify(tt.name); got != tt.want {
+				t.Fatalf("slugify(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortSlugs(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports []model.Port
+		want  []string
+	}{
+		{name: "no ports", ports: nil, want: []string{}},
+		{
+			name:  "descriptions",
+			ports: []model.Port{{ContainerPort: 80, Description: "Web UI"}, {ContainerPort: 5432, Description: "Postgres"}},
+			want:  []string{"web-ui", "postgres"},
+		},
+		{
+			name:  "services",
+			ports: []model.Port{{ContainerPort: 80, Service: "web"}, {ContainerPort: 9090, Description: "Metrics", Service: "web"}},
+			want:  []string{"web", "metrics"},
+		},
+		{
+			name:  "unnamed",
+			ports: []model.Port{{ContainerPort: 8080}},
+			want:  []string{"port"},
+		},
+		{
+			name:  "ambiguous",
+			ports: []model.Port{{ContainerPort: 80}, {ContainerPort: 443}, {ContainerPort: 5432, Description: "db"}},
+			want:  []string{"port-80", "port-443", "db"},
+		},
+		{
+			name: "ambiguous long names",
+			ports: []model.Port{
+				{ContainerPort: 8080, Description: strings.Repeat("a", 30)},
+				{ContainerPort: 9090, Description: strings.Repeat("a", 30)},
+			},
+			want: []string{strings.Repeat("a", maxPortSlugLength-5) + "-8080", strings.Repeat("a", maxPortSlugLength-5) + "-9090"},
+		},
+		{
+			name: "no dash before the suffix",
+			ports: []model.Port{
+				{ContainerPort: 1, Description: strings.Repeat("a", maxPortSlugLength-3) + " b"},
+				{ContainerPort: 2, Description: strings.Repeat("a", maxPortSlugLength-3) + " b"},
+			},
+			want: []string{strings.Repeat("a", maxPortSlugLength-3) + "-1", strings.Repeat("a", maxPortSlugLength-3) + "-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := portSlugs(tt.ports)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("portSlugs = %q, want %q", got, tt.want)
+			}
+			for _, slug := range got {
+				if len(slug) > maxPortSlugLength {
+					t.Fatalf("slug %q is longer than %d", slug, maxPortSlugLength)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionPortIndex(t *testing.T) {
+	ports := []model.Port{
+		{ContainerPort: 80, Protocol: "tcp", Description: "Web UI"},
+		{ContainerPort: 53, Protocol: "udp", Description: "DNS"},
+		{ContainerPort: 8080, Protocol: "tcp"},
+		{ContainerPort: 5432, Protocol: "tcp", Service: "db"},
+		{ContainerPort: 80, Protocol: "tcp", Description: "8080"},
+	}
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{key: "web-ui", want: 0},
+		{key: "Web-UI", want: 0},
+		{key: "80", want: 0},
+		{key: "port", want: 2},
+		{key: "db", want: 3},
+		{key: "5432", want: 3},
+		// A slug wins over a container port number
+		{key: "8080", want: 4},
+		// UDP ports cannot be proxied
+		{key: "dns", want: -1},
+		{key: "53", want: -1},
+		{key: "web", want: -1},
+		{key: "", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := sessionPortIndex(ports, tt.key); got != tt.want {
+				t.Fatalf("sessionPortIndex(%q) = %d, want %d", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveProxyTarget(t *testing.T) {
+	_, ss := newTestService(t, func(cfg *config.Config) {
+		cfg.ProxyTargetHost = "10.0.0.5"
+	})
+	ports := []model.Port{
+		{HostPort: 32768, ContainerPort: 80, Protocol: "tcp", Description: "web"},
+		{HostPort: 32769, ContainerPort: 443, Protocol: "tcp"},
+	}
+	for _, status := range []string{
+		model.SessionStatusRunning, model.SessionStatusReady, model.SessionStatusStarting, model.SessionStatusFailed,
+		model.SessionStatusProvisioning, model.SessionStatusStopped, model.SessionStatusPaused,
+	} {
+		ss.sessions[status] = &model.Session{ID: status, Status: status, Ports: ports}
+	}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		portKey   string
+		want      string
+		wantErr   error
+	}{
+		{name: "by slug", sessionID: model.SessionStatusRunning, portKey: "web", want: "http://10.0.0.5:32768"},
+		{name: "by container port", sessionID: model.SessionStatusRunning, portKey: "80", want: "http://10.0.0.5:32768"},
+		{name: "https port", sessionID: model.SessionStatusRunning, portKey: "443", want: "https://10.0.0.5:32769"},
+		{name: "ready", sessionID: model.SessionStatusReady, portKey: "web", want: "http://10.0.0.5:32768"},
+		{name: "starting", sessionID: model.SessionStatusStarting, portKey: "web", want: "http://10.0.0.5:32768"},
+		{name: "failed", sessionID: model.SessionStatusFailed, portKey: "web", want: "http://10.0.0.5:32768"},
+		{name: "provisioning", sessionID: model.SessionStatusProvisioning, portKey: "web", wantErr: util.ErrUnavailable},
+		{name: "stopped", sessionID: model.SessionStatusStopped, portKey: "web", wantErr: util.ErrUnavailable},
+		{name: "paused", sessionID: model.SessionStatusPaused, portKey: "web", wantErr: util.ErrUnavailable},
+		{name: "unknown port", sessionID: model.SessionStatusRunning, portKey: "8080", wantErr: util.ErrNotFound},
+		{name: "unknown session", sessionID: "missing", portKey: "web", wantErr: util.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := ss.ResolveProxyTarget(tt.sessionID, tt.portKey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResolveProxyTarget = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && target.String() != tt.want {
+				t.Fatalf("ResolveProxyTarget = %s, want %s", target, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyPortURL(t *testing.T) {
+	const sessionID = "0b7ff3b4-5f6a-4a53-9d3c-4d6b6c0e1a2f"
+	tests := []struct {
+		name       string
+		baseDomain string
+		publicURL  string
+		want       string
+	}{
+		{name: "path route", want: "http://cube.example.com:8081/s/" + sessionID + "/web/"},
+		{name: "public url", publicURL: "https://apps.example.com/cube/", want: "https://apps.example.com/cube/s/" + sessionID + "/web/"},
+		{name: "invalid public url", publicURL: "apps.example.com", want: "http://cube.example.com:8081/s/" + sessionID + "/web/"},
+		{name: "base domain", baseDomain: "apps.example.com", want: "http://" + sessionID + "-web.apps.example.com:8081/"},
+		{
+			name:       "base domain behind a load balancer",
+			baseDomain: "apps.example.com",
+			publicURL:  "https://apps.example.com",
+			want:       "https://" + sessionID + "-web.apps.example.com/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ss := newTestService(t, func(cfg *config.Config) {
+				cfg.PublicHost = "cube.example.com"
+				cfg.ProxyPort = 8081
+				cfg.ProxyBaseDomain = tt.baseDomain
+				cfg.ProxyPublicURL = tt.publicURL
+			})
+			if got := ss.proxyPortURL(sessionID, "web"); got != tt.want {
+				t.Fatalf("proxyPortURL = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
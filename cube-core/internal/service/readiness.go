@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// Readiness defaults for requests that leave them out
+const (
+	defaultReadinessTimeout  = 2 * time.Minute
+	defaultReadinessInterval = 2 * time.Second
+)
+
+// validateReadiness checks the readiness configuration of a create request
+func validateReadiness(readiness *model.ReadinessConfig) error {
+	if readiness == nil {
+		return nil
+	}
+
+	if len(readiness.Checks) == 0 {
+		return util.WrapError(util.ErrInvalidRequest, "readiness needs at least one check")
+	}
+	if readiness.TimeoutSeconds < 0 || readiness.IntervalSeconds < 0 {
+		return util.WrapError(util.ErrInvalidRequest, "readiness timeout and interval must not be negative")
+	}
+	timeout, interval := readinessTimings(readiness)
+	if interval > timeout {
+		return util.WrapError(util.ErrInvalidRequest, "readiness interval must not exceed the timeout")
+	}
+
+	for i, check := range readiness.Checks {
+		switch check.Type {
+		case model.ReadinessCheckTCP:
+			if check.Port <= 0 {
+				return util.WrapError(util.ErrInvalidRequest, "readiness check %d needs a port", i+1)
+			}
+		case model.ReadinessCheckHTTP:
+			if check.Port <= 0 {
+				return util.WrapError(util.ErrInvalidRequest, "readiness check %d needs a port", i+1)
+			}
+			if check.Path != "" && !strings.HasPrefix(check.Path, "/") {
+				return util.WrapError(util.ErrInvalidRequest, "readiness check %d path must start with /", i+1)
+			}
+			if check.ExpectedStatus != 0 && (check.ExpectedStatus < 100 || check.ExpectedStatus > 599) {
+				return util.WrapError(util.ErrInvalidRequest, "readiness check %d has an invalid expected status", i+1)
+			}
+		case model.ReadinessCheckExec:
+			if len(check.Command) == 0 {
+				return util.WrapError(util.ErrInvalidRequest, "readiness check %d needs a command", i+1)
+			}
+		default:
+			return util.WrapError(util.ErrInvalidRequest, "unknown readiness check type %q", check.Type)
+		}
+	}
+
+	return nil
+}
+
+// checkReadinessPorts ensures every port probed by a readiness check is published
+func checkReadinessPorts(readiness *model.ReadinessConfig, portConfigs []portConfig) error {
+	if readiness == nil {
+		return nil
+	}
+
+	for _, check := range readiness.Checks {
+		if check.Type == model.ReadinessCheckExec {
+			continue
+		}
+
+		published := false
+		for _, config := range portConfigs {
+			if config.ContainerPort == check.Port && (config.Protocol == "" || config.Protocol == "tcp") {
+				published = true
+				break
+			}
+		}
+		if !published {
+			return util.WrapError(util.ErrInvalidRequest, "readiness check port %d is not a published tcp port", check.Port)
+		}
+	}
+
+	return nil
+}
+
+// readinessTimings returns the overall timeout and the interval between attempts
+func readinessTimings(readiness *model.ReadinessConfig) (time.Duration, time.Duration) {
+	timeout := defaultReadinessTimeout
+	if readiness.TimeoutSeconds > 0 {
+		timeout = time.Duration(readiness.TimeoutSeconds) * time.Second
+	}
+	interval := defaultReadinessInterval
+	if readiness.IntervalSeconds > 0 {
+		interval = time.Duration(readiness.IntervalSeconds) * time.Second
+	}
+	return timeout, interval
+}
+
+// runningStatus returns the status a session with a started container is reported in
+func runningStatus(session *model.Session) string {
+	if session.Readiness != nil {
+		return model.SessionStatusReady
+	}
+	return model.SessionStatusRunning
+}
+
+// isRunningStatus reports whether a status means the session's container is up
+func isRunningStatus(status string) bool {
+	return status == model.SessionStatusRunning || status == model.SessionStatusReady
+}
+
+// startReadinessProbe puts a session in the starting state and probes it in the background
+// until it is ready, fails or the probe is stopped. Callers must hold ss.mu.
+func (ss *SessionService) startReadinessProbe(session *model.Session) {
+	ss.stopReadinessProbe(session.ID)
+
+	session.Status = model.SessionStatusStarting
+	session.StatusReason = "waiting for readiness checks"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ss.readinessProbes[session.ID] = cancel
+
+	snapshot := *session
+	go ss.awaitReadiness(ctx, snapshot)
+}
+
+// stopReadinessProbe cancels a session's readiness probe, if any. Callers must hold ss.mu.
+func (ss *SessionService) stopReadinessProbe(sessionID string) {
+	if cancel, probing := ss.readinessProbes[sessionID]; probing {
+		cancel()
+		delete(ss.readinessProbes, sessionID)
+	}
+}
+
+// awaitReadiness runs a session's readiness checks every interval until they all pass in
+// one attempt or the timeout passes, then records the outcome
+func (ss *SessionService) awaitReadiness(ctx context.Context, session model.Session) {
+	timeout, interval := readinessTimings(session.Readiness)
+	deadline, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		attempt, cancelAttempt := context.WithTimeout(deadline, interval)
+		lastErr = ss.runReadinessChecks(attempt, &session)
+		cancelAttempt()
+		if lastErr == nil {
+			break
+		}
+		ss.logger.Debug("Session %s is not ready yet: %v", session.ID, lastErr)
+
+		select {
+		case <-deadline.Done():
+		case <-ticker.C:
+			continue
+		}
+		break
+	}
+
+	// Stopped probes leave the session to whoever stopped them
+	if ctx.Err() != nil {
+		return
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	delete(ss.readinessProbes, session.ID)
+
+	current, exists := ss.sessions[session.ID]
+	if !exists || current.Status != model.SessionStatusStarting || current.ContainerID != session.ContainerID {
+		return
+	}
+
+	if lastErr == nil {
+		ss.logger.Info("Session %s is ready", session.ID)
+		current.Status = model.SessionStatusReady
+		current.StatusReason = ""
+		now := time.Now()
+		current.LastActivityAt = &now
+	} else {
+		ss.logger.Warn("Session %s did not become ready within %s: %v", session.ID, timeout, lastErr)
+		current.Status = model.SessionStatusFailed
+		current.StatusReason = fmt.Sprintf("not ready after %s: %v", timeout, lastErr)
+	}
+
+	if err := ss.persistSession(current); err != nil {
+		ss.logger.Warn("Readiness of session %s will not survive a restart: %v", session.ID, err)
+	}
+}
+
+// runReadinessChecks runs every readiness check of a session once, returning the first failure
+func (ss *SessionService) runReadinessChecks(ctx context.Context, session *model.Session) error {
+	for _, check := range session.Readiness.Checks {
+		var err error
+		switch check.Type {
+		case model.ReadinessCheckTCP:
+			err = ss.probeTCP(ctx, session, check)
+		case model.ReadinessCheckHTTP:
+			err = ss.probeHTTP(ctx, session, check)
+		case model.ReadinessCheckExec:
+			err = ss.probeExec(ctx, session, check)
+		default:
+			err = fmt.Errorf("unknown readiness check type %q", check.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// probedPort returns the session port mapped to the container port a check probes. In a stack
+// only the primary service's ports are probed.
+func probedPort(session *model.Session, check model.ReadinessCheck) (model.Port, error) {
+	primary := ""
+	for _, service := range session.Services {
+		if service.Primary {
+			primary = service.Name
+		}
+	}
+
+	for _, p := range session.Ports {
+		if p.ContainerPort == check.Port && p.Protocol == "tcp" && p.Service == primary {
+			return p, nil
+		}
+	}
+	return model.Port{}, fmt.Errorf("container port %d is not published", check.Port)
+}
+
+// probeTCP connects to the host port mapped to the checked container port
+func (ss *SessionService) probeTCP(ctx context.Context, session *model.Session, check model.ReadinessCheck) error {
+	p, err := probedPort(session, check)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", ss.portTarget(p).Host)
+	if err != nil {
+		return fmt.Errorf("tcp check on port %d failed: %v", check.Port, err)
+	}
+	conn.Close()
+	return nil
+}
+
+// readinessClient is used for http checks. Session containers commonly serve self-signed
+// certificates, and the check only cares whether the service answers.
+var readinessClient = &http.Client{
+	Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// probeHTTP requests the checked path on the published port and compares the response status
+func (ss *SessionService) probeHTTP(ctx context.Context, session *model.Session, check model.ReadinessCheck) error {
+	p, err := probedPort(session, check)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ss.portTarget(p).String()+check.Path, nil)
+	if err != nil {
+		return fmt.Errorf("http check on port %d failed: %v", check.Port, err)
+	}
+
+	resp, err := readinessClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http check on port %d failed: %v", check.Port, err)
+	}
+	resp.Body.Close()
+
+	if check.ExpectedStatus != 0 {
+		if resp.StatusCode != check.ExpectedStatus {
+			return fmt.Errorf("http check on port %d returned %d, expected %d", check.Port, resp.StatusCode, check.ExpectedStatus)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("http check on port %d returned %d", check.Port, resp.StatusCode)
+	}
+	return nil
+}
+
+// probeExec runs the check command inside the session's container
+func (ss *SessionService) probeExec(ctx context.Context, session *model.Session, check model.ReadinessCheck) error {
+	result, err := ss.dockerManager.RunCommand(ctx, session.ContainerID, docker.ExecOptions{Cmd: check.Command})
+	if err != nil {
+		return fmt.Errorf("exec check %q failed: %v", strings.Join(check.Command, " "), err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("exec check %q exited with status %d", strings.Join(check.Command, " "), result.ExitCode)
+	}
+	return nil
+}
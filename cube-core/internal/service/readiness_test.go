@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestValidateReadiness(t *testing.T) {
+	tcp := model.ReadinessCheck{Type: model.ReadinessCheckTCP, Port: 5432}
+
+	tests := []struct {
+		name      string
+		readiness *model.ReadinessConfig
+		wantErr   bool
+	}{
+		{name: "none"},
+		{name: "tcp", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{tcp}}},
+		{
+			name: "all check types",
+			readiness: &model.ReadinessConfig{
+				Checks: []model.ReadinessCheck{
+					tcp,
+					{Type: model.ReadinessCheckHTTP, Port: 80, Path: "/healthz", ExpectedStatus: 204},
+					{Type: model.ReadinessCheckExec, Command: []string{"pg_isready"}},
+				},
+				TimeoutSeconds:  30,
+				IntervalSeconds: 5,
+			},
+		},
+		{name: "no checks", readiness: &model.ReadinessConfig{}, wantErr: true},
+		{name: "negative timeout", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{tcp}, TimeoutSeconds: -1}, wantErr: true},
+		{name: "negative interval", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{tcp}, IntervalSeconds: -1}, wantErr: true},
+		{name: "interval over timeout", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{tcp}, TimeoutSeconds: 5, IntervalSeconds: 10}, wantErr: true},
+		{name: "interval over default timeout", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{tcp}, IntervalSeconds: 600}, wantErr: true},
+		{name: "tcp without port", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: model.ReadinessCheckTCP}}}, wantErr: true},
+		{name: "http without port", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: model.ReadinessCheckHTTP, Path: "/"}}}, wantErr: true},
+		{name: "relative http path", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: model.ReadinessCheckHTTP, Port: 80, Path: "healthz"}}}, wantErr: true},
+		{name: "invalid expected status", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: model.ReadinessCheckHTTP, Port: 80, ExpectedStatus: 600}}}, wantErr: true},
+		{name: "exec without command", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: model.ReadinessCheckExec}}}, wantErr: true},
+		{name: "unknown type", readiness: &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: "grpc", Port: 50051}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReadiness(tt.readiness)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateReadiness = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("validateReadiness = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestCheckReadinessPorts(t *testing.T) {
+	ports := []portConfig{{ContainerPort: 80}, {ContainerPort: 5432, Protocol: "tcp"}, {ContainerPort: 53, Protocol: "udp"}}
+
+	tests := []struct {
+		name    string
+		checks  []model.ReadinessCheck
+		wantErr bool
+	}{
+		{name: "default protocol", checks: []model.ReadinessCheck{{Type: model.ReadinessCheckHTTP, Port: 80}}},
+		{name: "tcp", checks: []model.ReadinessCheck{{Type: model.ReadinessCheckTCP, Port: 5432}}},
+		{name: "exec needs no port", checks: []model.ReadinessCheck{{Type: model.ReadinessCheckExec, Command: []string{"true"}}}},
+		{name: "unpublished port", checks: []model.ReadinessCheck{{Type: model.ReadinessCheckTCP, Port: 6379}}, wantErr: true},
+		{name: "udp port", checks: []model.ReadinessCheck{{Type: model.ReadinessCheckTCP, Port: 53}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReadinessPorts(&model.ReadinessConfig{Checks: tt.checks}, ports)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("checkReadinessPorts = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := checkReadinessPorts(nil, nil); err != nil {
+		t.Fatalf("checkReadinessPorts without readiness = %v", err)
+	}
+}
+
+func TestReadinessTimings(t *testing.T) {
+	tests := []struct {
+		name         string
+		readiness    model.ReadinessConfig
+		wantTimeout  time.Duration
+		wantInterval time.Duration
+	}{
+		{name: "defaults", wantTimeout: defaultReadinessTimeout, wantInterval: defaultReadinessInterval},
+		{name: "timeout only", readiness: model.ReadinessConfig{TimeoutSeconds: 10}, wantTimeout: 10 * time.Second, wantInterval: defaultReadinessInterval},
+		{name: "both", readiness: model.ReadinessConfig{TimeoutSeconds: 60, IntervalSeconds: 5}, wantTimeout: time.Minute, wantInterval: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout, interval := readinessTimings(&tt.readiness)
+			if timeout != tt.wantTimeout || interval != tt.wantInterval {
+				t.Fatalf("readinessTimings = %s, %s; want %s, %s", timeout, interval, tt.wantTimeout, tt.wantInterval)
+			}
+		})
+	}
+}
+
+// probeSession returns a session whose container port 80 is published on the port of addr
+func probeSession(t *testing.T, addr string) *model.Session {
+	t.Helper()
+
+	_, portString, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("split %s: %v", addr, err)
+	}
+	hostPort, err := strconv.Atoi(portString)
+	if err != nil {
+		t.Fatalf("parse port %s: %v", portString, err)
+	}
+	return &model.Session{ID: "s1", Ports: []model.Port{{HostPort: hostPort, ContainerPort: 80, Protocol: "tcp"}}}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusNoContent)
+		case "/login":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ss := &SessionService{cfg: &config.Config{ProxyTargetHost: "127.0.0.1"}}
+	session := probeSession(t, server.Listener.Addr().String())
+
+	tests := []struct {
+		name    string
+		check   model.ReadinessCheck
+		wantErr string
+	}{
+		{name: "any 2xx", check: model.ReadinessCheck{Port: 80, Path: "/healthz"}},
+		{name: "expected status", check: model.ReadinessCheck{Port: 80, Path: "/healthz", ExpectedStatus: 204}},
+		{name: "not found", check: model.ReadinessCheck{Port: 80, Path: "/missing"}, wantErr: "returned 404"},
+		{name: "expected not found", check: model.ReadinessCheck{Port: 80, Path: "/missing", ExpectedStatus: 404}},
+		{name: "other status than expected", check: model.ReadinessCheck{Port: 80, Path: "/healthz", ExpectedStatus: 200}, wantErr: "returned 204, expected 200"},
+		{name: "redirect is not followed", check: model.ReadinessCheck{Port: 80, Path: "/login"}, wantErr: "returned 302"},
+		{name: "expected redirect", check: model.ReadinessCheck{Port: 80, Path: "/login", ExpectedStatus: 302}},
+		{name: "unpublished port", check: model.ReadinessCheck{Port: 8080, Path: "/healthz"}, wantErr: "not published"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check.Type = model.ReadinessCheckHTTP
+			err := ss.probeHTTP(context.Background(), session, tt.check)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("probeHTTP: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("probeHTTP = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ss := &SessionService{cfg: &config.Config{ProxyTargetHost: "127.0.0.1"}}
+	session := probeSession(t, listener.Addr().String())
+	check := model.ReadinessCheck{Type: model.ReadinessCheckTCP, Port: 80}
+
+	if err := ss.probeTCP(context.Background(), session, check); err != nil {
+		t.Fatalf("probeTCP on a listening port: %v", err)
+	}
+
+	listener.Close()
+	if err := ss.probeTCP(context.Background(), session, check); err == nil {
+		t.Fatal("probeTCP on a closed port succeeded")
+	}
+}
+
+func TestSessionReadiness(t *testing.T) {
+	tests := []struct {
+		name string
+		// listen makes the probed port accept connections
+		listen     bool
+		timeout    int
+		wantStatus string
+	}{
+		{name: "ready", listen: true, timeout: 5, wantStatus: model.SessionStatusReady},
+		{name: "failed", timeout: 1, wantStatus: model.SessionStatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, ss := newTestService(t, nil)
+			fake.AddImage("postgres:16")
+
+			session, err := ss.CreateSession(&model.CreateSessionRequest{
+				ImageName:    "postgres:16",
+				PortMappings: []model.PortMapping{{ContainerPort: 5432}},
+				Readiness: &model.ReadinessConfig{
+					Checks:          []model.ReadinessCheck{{Type: model.ReadinessCheckTCP, Port: 5432}},
+					TimeoutSeconds:  tt.timeout,
+					IntervalSeconds: 1,
+				},
+			})
+			if err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+
+			// The fake runs nothing, so the test stands in for the container's server
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			starting, err := ss.WaitForSession(ctx, session.ID, func(s *model.Session) bool {
+				return s.Status != model.SessionStatusProvisioning
+			})
+			if err != nil {
+				t.Fatalf("WaitForSession: %v", err)
+			}
+			if tt.listen {
+				listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(starting.Ports[0].HostPort)))
+				if err != nil {
+					t.Fatalf("listen: %v", err)
+				}
+				defer listener.Close()
+			}
+
+			settled := waitSettled(t, ss, session.ID)
+			if settled.Status != tt.wantStatus {
+				t.Fatalf("session is %s (%q), want %s", settled.Status, settled.StatusReason, tt.wantStatus)
+			}
+			if tt.wantStatus == model.SessionStatusFailed && !strings.Contains(settled.StatusReason, "not ready after 1s") {
+				t.Fatalf("failure reason = %q", settled.StatusReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// resolveResources applies server defaults to the requested resource limits and validates
+// them against the server-wide maximums
+func (ss *SessionService) resolveResources(requested *model.ResourceLimits) (*model.ResourceLimits, error) {
+	limits := model.ResourceLimits{}
+	if requested != nil {
+		limits = *requested
+	}
+
+	if limits.CPUs < 0 || limits.MemoryBytes < 0 || limits.PidsLimit < 0 || limits.MemorySwapBytes < -1 {
+		return nil, util.WrapError(util.ErrInvalidRequest, "resource limits must not be negative")
+	}
+
+	// Fall back to the server default, then to the maximum so no session is left unbounded
+	if limits.CPUs == 0 {
+		limits.CPUs = firstPositiveFloat(ss.cfg.DefaultCPUs, ss.cfg.MaxCPUs)
+	}
+	if limits.MemoryBytes == 0 {
+		limits.MemoryBytes = firstPositiveInt(ss.cfg.DefaultMemoryBytes, ss.cfg.MaxMemoryBytes)
+	}
+	if limits.PidsLimit == 0 {
+		limits.PidsLimit = firstPositiveInt(ss.cfg.DefaultPidsLimit, ss.cfg.MaxPidsLimit)
+	}
+
+	if ss.cfg.MaxCPUs > 0 && limits.CPUs > ss.cfg.MaxCPUs {
+		return nil, util.WrapError(util.ErrInvalidRequest, "cpus %.2f exceeds the maximum of %.2f", limits.CPUs, ss.cfg.MaxCPUs)
+	}
+	if ss.cfg.MaxMemoryBytes > 0 && limits.MemoryBytes > ss.cfg.MaxMemoryBytes {
+		return nil, util.WrapError(util.ErrInvalidRequest, "memory_bytes %d exceeds the maximum of %d", limits.MemoryBytes, ss.cfg.MaxMemoryBytes)
+	}
+	if ss.cfg.MaxPidsLimit > 0 && limits.PidsLimit > ss.cfg.MaxPidsLimit {
+		return nil, util.WrapError(util.ErrInvalidRequest, "pids_limit %d exceeds the maximum of %d", limits.PidsLimit, ss.cfg.MaxPidsLimit)
+	}
+
+	if limits.MemorySwapBytes != 0 {
+		if limits.MemoryBytes == 0 {
+			return nil, util.WrapError(util.ErrInvalidRequest, "memory_swap_bytes requires memory_bytes")
+		}
+		if limits.MemorySwapBytes != -1 && limits.MemorySwapBytes < limits.MemoryBytes {
+			return nil, util.WrapError(util.ErrInvalidRequest, "memory_swap_bytes must be at least memory_bytes")
+		}
+	}
+
+	if limits.BlkioWeight != 0 && (limits.BlkioWeight < 10 || limits.BlkioWeight > 1000) {
+		return nil, util.WrapError(util.ErrInvalidRequest, "blkio_weight must be between 10 and 1000")
+	}
+
+	if limits == (model.ResourceLimits{}) {
+		return nil, nil
+	}
+	return &limits, nil
+}
+
+// dockerResources converts session resource limits to Docker resource limits
+func dockerResources(limits *model.ResourceLimits) docker.ResourceLimits {
+	if limits == nil {
+		return docker.ResourceLimits{}
+	}
+
+	return docker.ResourceLimits{
+		NanoCPUs:    int64(limits.CPUs * 1e9),
+		Memory:      limits.MemoryBytes,
+		MemorySwap:  limits.MemorySwapBytes,
+		PidsLimit:   limits.PidsLimit,
+		BlkioWeight: limits.BlkioWeight,
+	}
+}
+
+// firstPositiveFloat returns the first positive value, or zero if there is none
+func firstPositiveFloat(values ...float64) float64 {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// firstPositiveInt returns the first positive value, or zero if there is none
+func firstPositiveInt(values ...int64) int64 {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestResolveResources(t *testing.T) {
+	const gib = 1 << 30
+
+	limitedConfig := config.Config{
+		DefaultCPUs:        1,
+		DefaultMemoryBytes: gib,
+		MaxCPUs:            2,
+		MaxMemoryBytes:     4 * gib,
+		MaxPidsLimit:       512,
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		requested *model.ResourceLimits
+		want      *model.ResourceLimits
+		wantErr   bool
+	}{
+		{name: "unlimited server", want: nil},
+		{name: "unlimited server keeps request", requested: &model.ResourceLimits{CPUs: 8}, want: &model.ResourceLimits{CPUs: 8}},
+		{
+			name: "server defaults and maximums",
+			cfg:  limitedConfig,
+			want: &model.ResourceLimits{CPUs: 1, MemoryBytes: gib, PidsLimit: 512},
+		},
+		{
+			name:      "request within maximums",
+			cfg:       limitedConfig,
+			requested: &model.ResourceLimits{CPUs: 0.5, MemoryBytes: 2 * gib, MemorySwapBytes: 3 * gib, PidsLimit: 100, BlkioWeight: 500},
+			want:      &model.ResourceLimits{CPUs: 0.5, MemoryBytes: 2 * gib, MemorySwapBytes: 3 * gib, PidsLimit: 100, BlkioWeight: 500},
+		},
+		{
+			name:      "unlimited swap",
+			cfg:       limitedConfig,
+			requested: &model.ResourceLimits{MemorySwapBytes: -1},
+			want:      &model.ResourceLimits{CPUs: 1, MemoryBytes: gib, MemorySwapBytes: -1, PidsLimit: 512},
+		},
+		{name: "cpus over maximum", cfg: limitedConfig, requested: &model.ResourceLimits{CPUs: 2.5}, wantErr: true},
+		{name: "memory over maximum", cfg: limitedConfig, requested: &model.ResourceLimits{MemoryBytes: 5 * gib}, wantErr: true},
+		{name: "pids over maximum", cfg: limitedConfig, requested: &model.ResourceLimits{PidsLimit: 1024}, wantErr: true},
+		{name: "default over maximum", cfg: config.Config{DefaultCPUs: 4, MaxCPUs: 2}, wantErr: true},
+		{name: "negative cpus", requested: &model.ResourceLimits{CPUs: -1}, wantErr: true},
+		{name: "negative memory", requested: &model.ResourceLimits{MemoryBytes: -1}, wantErr: true},
+		{name: "negative swap", requested: &model.ResourceLimits{MemoryBytes: gib, MemorySwapBytes: -2}, wantErr: true},
+		{name: "swap without memory", requested: &model.ResourceLimits{MemorySwapBytes: gib}, wantErr: true},
+		{name: "swap below memory", requested: &model.ResourceLimits{MemoryBytes: 2 * gib, MemorySwapBytes: gib}, wantErr: true},
+		{name: "blkio weight too low", requested: &model.ResourceLimits{BlkioWeight: 5}, wantErr: true},
+		{name: "blkio weight too high", requested: &model.ResourceLimits{BlkioWeight: 1001}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &SessionService{cfg: &tt.cfg}
+			limits, err := ss.resolveResources(tt.requested)
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrInvalidRequest) {
+					t.Fatalf("resolveResources = %+v, %v; want ErrInvalidRequest", limits, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveResources: %v", err)
+			}
+			if (limits == nil) != (tt.want == nil) || (limits != nil && *limits != *tt.want) {
+				t.Fatalf("resolveResources = %+v, want %+v", limits, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerResources(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits *model.ResourceLimits
+		want   docker.ResourceLimits
+	}{
+		{name: "none", want: docker.ResourceLimits{}},
+		{name: "fractional cpus", limits: &model.ResourceLimits{CPUs: 0.5}, want: docker.ResourceLimits{NanoCPUs: 500000000}},
+		{
+			name:   "all limits",
+			limits: &model.ResourceLimits{CPUs: 2, MemoryBytes: 1 << 30, MemorySwapBytes: -1, PidsLimit: 256, BlkioWeight: 300},
+			want:   docker.ResourceLimits{NanoCPUs: 2000000000, Memory: 1 << 30, MemorySwap: -1, PidsLimit: 256, BlkioWeight: 300},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dockerResources(tt.limits); got != tt.want {
+				t.Fatalf("dockerResources = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateSessionAppliesResourceLimits(t *testing.T) {
+	fake, ss := newTestService(t, func(cfg *config.Config) {
+		cfg.DefaultMemoryBytes = 256 << 20
+		cfg.MaxPidsLimit = 128
+	})
+	fake.AddImage("nginx:latest")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName: "nginx:latest",
+		Resources: &model.ResourceLimits{CPUs: 1.5, BlkioWeight: 200},
+	})
+	want := model.ResourceLimits{CPUs: 1.5, MemoryBytes: 256 << 20, PidsLimit: 128, BlkioWeight: 200}
+	if session.Resources == nil || *session.Resources != want {
+		t.Fatalf("session resources = %+v, want %+v", session.Resources, want)
+	}
+
+	resources := fake.Containers()[0].Resources
+	if resources.NanoCPUs != 1500000000 || resources.Memory != 256<<20 || resources.BlkioWeight != 200 ||
+		resources.PidsLimit == nil || *resources.PidsLimit != 128 {
+		t.Fatalf("container resources = %+v", resources)
+	}
+
+	// Requests over the maximums are rejected before anything is created
+	_, err := ss.CreateSession(&model.CreateSessionRequest{
+		ImageName: "nginx:latest",
+		Resources: &model.ResourceLimits{PidsLimit: 1000},
+	})
+	if !errors.Is(err, util.ErrInvalidRequest) {
+		t.Fatalf("CreateSession over the pids maximum = %v, want ErrInvalidRequest", err)
+	}
+	if containers := fake.Containers(); len(containers) != 1 {
+		t.Fatalf("%d containers, want 1", len(containers))
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// resolveRestartPolicy validates the restart policy of a create request and normalizes the
+// "on-failure:N" shorthand into a name and retry count
+func resolveRestartPolicy(requested *model.RestartPolicy) (*model.RestartPolicy, error) {
+	if requested == nil {
+		return nil, nil
+	}
+
+	policy := *requested
+	if name, retries, found := strings.Cut(policy.Name, ":"); found {
+		if name != model.RestartPolicyOnFailure {
+			return nil, util.WrapError(util.ErrInvalidRequest, "only the on-failure restart policy takes a retry count")
+		}
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return nil, util.WrapError(util.ErrInvalidRequest, "invalid retry count in restart policy %q", policy.Name)
+		}
+		if policy.MaxRetries != 0 && policy.MaxRetries != n {
+			return nil, util.WrapError(util.ErrInvalidRequest, "restart policy %q conflicts with max_retries %d", policy.Name, policy.MaxRetries)
+		}
+		policy.Name = name
+		policy.MaxRetries = n
+	}
+
+	switch policy.Name {
+	case "", model.RestartPolicyNo, model.RestartPolicyAlways, model.RestartPolicyUnlessStopped:
+		if policy.MaxRetries != 0 {
+			return nil, util.WrapError(util.ErrInvalidRequest, "max_retries only applies to the on-failure restart policy")
+		}
+	case model.RestartPolicyOnFailure:
+		if policy.MaxRetries < 0 {
+			return nil, util.WrapError(util.ErrInvalidRequest, "max_retries must not be negative")
+		}
+	default:
+		return nil, util.WrapError(util.ErrInvalidRequest, "unknown restart policy %q", policy.Name)
+	}
+
+	if policy.MaxRecreates < 0 {
+		return nil, util.WrapError(util.ErrInvalidRequest, "max_recreates must not be negative")
+	}
+
+	return &policy, nil
+}
+
+// dockerRestartPolicy returns the Docker restart policy name and retry count of a session
+func dockerRestartPolicy(policy *model.RestartPolicy) (string, int) {
+	if policy == nil || policy.Name == "" {
+		return model.RestartPolicyNo, 0
+	}
+	return policy.Name, policy.MaxRetries
+}
+
+// reflectContainerState brings a session's status, exit code and restart count in line with
+// the live state of its container, returning whether anything changed. Sessions that were
+// deliberately stopped or paused keep their status, and sessions in the middle of a lifecycle
+// operation are left to it. Callers must hold ss.mu.
+func (ss *SessionService) reflectContainerState(session *model.Session, state *docker.ContainerState) bool {
+	if ss.transitions[session.ID] != "" {
+		return false
+	}
+
+	changed := session.RestartCount != state.RestartCount
+	session.RestartCount = state.RestartCount
+
+	up := isRunningStatus(session.Status) || session.Status == model.SessionStatusStarting ||
+		session.Status == model.SessionStatusFailed
+	down := session.Status == model.SessionStatusExited || session.Status == model.SessionStatusCrashLoop
+
+	switch {
+	case state.Restarting && (up || session.Status == model.SessionStatusExited):
+		// Docker is restarting a main process that keeps exiting
+		ss.stopReadinessProbe(session.ID)
+		exitCode := state.ExitCode
+		session.ExitCode = &exitCode
+		session.Status = model.SessionStatusCrashLoop
+		session.StatusReason = fmt.Sprintf("main process keeps exiting (last status %d), restarted %d times", state.ExitCode, state.RestartCount)
+		ss.logger.Warn("Session %s is crash looping: %s", session.ID, session.StatusReason)
+		return true
+
+	case (state.Status == "exited" || state.Status == "dead") && (up || session.Status == model.SessionStatusCrashLoop):
+		ss.stopReadinessProbe(session.ID)
+		exitCode := state.ExitCode
+		session.ExitCode = &exitCode
+		session.Status = model.SessionStatusExited
+		session.StatusReason = fmt.Sprintf("main process exited with status %d", state.ExitCode)
+		if state.OOMKilled {
+			session.StatusReason += " after running out of memory"
+		}
+		ss.logger.Warn("Session %s %s", session.ID, session.StatusReason)
+		return true
+
+	case state.Running && !state.Paused && !state.Restarting && down:
+		// Docker brought the container back under its restart policy
+		session.Status = runningStatus(session)
+		session.StatusReason = "restarted by Docker after the main process exited"
+		if session.Readiness != nil {
+			ss.startReadinessProbe(session)
+		}
+		ss.logger.Info("Session %s is back after %d restarts", session.ID, state.RestartCount)
+		return true
+	}
+
+	return changed
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestResolveRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested *model.RestartPolicy
+		want      *model.RestartPolicy
+		wantErr   bool
+	}{
+		{name: "none"},
+		{name: "no", requested: &model.RestartPolicy{Name: "no"}, want: &model.RestartPolicy{Name: "no"}},
+		{name: "unless-stopped", requested: &model.RestartPolicy{Name: "unless-stopped"}, want: &model.RestartPolicy{Name: "unless-stopped"}},
+		{name: "on-failure", requested: &model.RestartPolicy{Name: "on-failure", MaxRetries: 5}, want: &model.RestartPolicy{Name: "on-failure", MaxRetries: 5}},
+		{name: "on-failure shorthand", requested: &model.RestartPolicy{Name: "on-failure:3"}, want: &model.RestartPolicy{Name: "on-failure", MaxRetries: 3}},
+		{name: "shorthand agreeing with max_retries", requested: &model.RestartPolicy{Name: "on-failure:3", MaxRetries: 3}, want: &model.RestartPolicy{Name: "on-failure", MaxRetries: 3}},
+		{
+			name:      "recreate only",
+			requested: &model.RestartPolicy{RecreateOnUnhealthy: true, MaxRecreates: 2},
+			want:      &model.RestartPolicy{RecreateOnUnhealthy: true, MaxRecreates: 2},
+		},
+		{name: "unknown", requested: &model.RestartPolicy{Name: "sometimes"}, wantErr: true},
+		{name: "count on always", requested: &model.RestartPolicy{Name: "always:3"}, wantErr: true},
+		{name: "max_retries on unless-stopped", requested: &model.RestartPolicy{Name: "unless-stopped", MaxRetries: 3}, wantErr: true},
+		{name: "bad count", requested: &model.RestartPolicy{Name: "on-failure:many"}, wantErr: true},
+		{name: "negative count", requested: &model.RestartPolicy{Name: "on-failure:-1"}, wantErr: true},
+		{name: "negative max_retries", requested: &model.RestartPolicy{Name: "on-failure", MaxRetries: -1}, wantErr: true},
+		{name: "conflicting counts", requested: &model.RestartPolicy{Name: "on-failure:3", MaxRetries: 5}, wantErr: true},
+		{name: "negative max_recreates", requested: &model.RestartPolicy{RecreateOnUnhealthy: true, MaxRecreates: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := resolveRestartPolicy(tt.requested)
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrInvalidRequest) {
+					t.Fatalf("resolveRestartPolicy = %+v, %v; want ErrInvalidRequest", policy, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveRestartPolicy: %v", err)
+			}
+			if (policy == nil) != (tt.want == nil) || (policy != nil && *policy != *tt.want) {
+				t.Fatalf("resolveRestartPolicy = %+v, want %+v", policy, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      *model.RestartPolicy
+		wantName    string
+		wantRetries int
+	}{
+		{name: "none", wantName: "no"},
+		{name: "recreate only", policy: &model.RestartPolicy{RecreateOnUnhealthy: true}, wantName: "no"},
+		{name: "always", policy: &model.RestartPolicy{Name: "always"}, wantName: "always"},
+		{name: "on-failure", policy: &model.RestartPolicy{Name: "on-failure", MaxRetries: 3}, wantName: "on-failure", wantRetries: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, retries := dockerRestartPolicy(tt.policy)
+			if name != tt.wantName || retries != tt.wantRetries {
+				t.Fatalf("dockerRestartPolicy = %s, %d; want %s, %d", name, retries, tt.wantName, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestReflectContainerState(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		busyWith   string
+		state      docker.ContainerState
+		want       string
+		wantReason string
+		// wantExitCode is the recorded exit code; -1 means none
+		wantExitCode int
+		wantChanged  bool
+	}{
+		{
+			name:         "main process exited",
+			status:       model.SessionStatusRunning,
+			state:        docker.ContainerState{Status: "exited", ExitCode: 1},
+			want:         model.SessionStatusExited,
+			wantReason:   "main process exited with status 1",
+			wantExitCode: 1,
+			wantChanged:  true,
+		},
+		{
+			name:         "out of memory",
+			status:       model.SessionStatusReady,
+			state:        docker.ContainerState{Status: "exited", ExitCode: 137, OOMKilled: true},
+			want:         model.SessionStatusExited,
+			wantReason:   "after running out of memory",
+			wantExitCode: 137,
+			wantChanged:  true,
+		},
+		{
+			name:         "crash loop",
+			status:       model.SessionStatusRunning,
+			state:        docker.ContainerState{Status: "restarting", Running: true, Restarting: true, ExitCode: 2, RestartCount: 3},
+			want:         model.SessionStatusCrashLoop,
+			wantReason:   "restarted 3 times",
+			wantExitCode: 2,
+			wantChanged:  true,
+		},
+		{
+			name:         "crash loop gave up",
+			status:       model.SessionStatusCrashLoop,
+			state:        docker.ContainerState{Status: "exited", ExitCode: 2},
+			want:         model.SessionStatusExited,
+			wantReason:   "main process exited with status 2",
+			wantExitCode: 2,
+			wantChanged:  true,
+		},
+		{
+			name:         "restarted by Docker",
+			status:       model.SessionStatusExited,
+			state:        docker.ContainerState{Status: "running", Running: true, RestartCount: 1},
+			want:         model.SessionStatusRunning,
+			wantReason:   "restarted by Docker",
+			wantExitCode: -1,
+			wantChanged:  true,
+		},
+		{
+			name:         "restart count only",
+			status:       model.SessionStatusRunning,
+			state:        docker.ContainerState{Status: "running", Running: true, RestartCount: 2},
+			want:         model.SessionStatusRunning,
+			wantExitCode: -1,
+			wantChanged:  true,
+		},
+		{
+			name:         "still running",
+			status:       model.SessionStatusRunning,
+			state:        docker.ContainerState{Status: "running", Running: true},
+			want:         model.SessionStatusRunning,
+			wantExitCode: -1,
+		},
+		{
+			name:         "stopped on purpose",
+			status:       model.SessionStatusStopped,
+			state:        docker.ContainerState{Status: "exited", ExitCode: 0},
+			want:         model.SessionStatusStopped,
+			wantExitCode: -1,
+		},
+		{
+			name:         "paused on purpose",
+			status:       model.SessionStatusPaused,
+			state:        docker.ContainerState{Status: "paused", Running: true, Paused: true},
+			want:         model.SessionStatusPaused,
+			wantExitCode: -1,
+		},
+		{
+			name:         "busy with an operation",
+			status:       model.SessionStatusRunning,
+			busyWith:     OperationStop,
+			state:        docker.ContainerState{Status: "exited", ExitCode: 0, RestartCount: 1},
+			want:         model.SessionStatusRunning,
+			wantExitCode: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ss := newTestService(t, nil)
+			session := &model.Session{ID: "s1", Status: tt.status}
+			ss.sessions["s1"] = session
+			if tt.busyWith != "" {
+				ss.transitions["s1"] = tt.busyWith
+			}
+
+			ss.mu.Lock()
+			changed := ss.reflectContainerState(session, &tt.state)
+			ss.mu.Unlock()
+
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if session.Status != tt.want {
+				t.Errorf("status = %s, want %s", session.Status, tt.want)
+			}
+			if !strings.Contains(session.StatusReason, tt.wantReason) {
+				t.Errorf("reason = %q, want it to contain %q", session.StatusReason, tt.wantReason)
+			}
+			exitCode := -1
+			if session.ExitCode != nil {
+				exitCode = *session.ExitCode
+			}
+			if exitCode != tt.wantExitCode {
+				t.Errorf("exit code = %d, want %d", exitCode, tt.wantExitCode)
+			}
+		})
+	}
+}
+
+func TestCreateSessionAppliesRestartPolicy(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("node:20")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName:     "node:20",
+		RestartPolicy: &model.RestartPolicy{Name: "on-failure:3"},
+	})
+	if want := (model.RestartPolicy{Name: "on-failure", MaxRetries: 3}); session.RestartPolicy == nil || *session.RestartPolicy != want {
+		t.Fatalf("session restart policy = %+v, want %+v", session.RestartPolicy, want)
+	}
+	if policy := fake.Containers()[0].RestartPolicy; policy != (container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}) {
+		t.Fatalf("container restart policy = %+v", policy)
+	}
+
+	// The main process exiting shows up in the session, with its exit code
+	fake.SetContainerStatus(session.ContainerID, "exited", 1)
+	ss.checkSessionContainers()
+	exited, err := ss.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if exited.Status != model.SessionStatusExited || exited.ExitCode == nil || *exited.ExitCode != 1 {
+		t.Fatalf("session is %s with exit code %v, want exited with 1", exited.Status, exited.ExitCode)
+	}
+}
@@ -3,13 +3,14 @@ package service
 import (
 	"context"
 	"fmt"
-	"net"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/session-manager/internal/config"
 	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/internal/store"
 	"github.com/yourusername/session-manager/pkg/docker"
 	"github.com/yourusername/session-manager/pkg/port"
 	"github.com/yourusername/session-manager/pkg/util"
@@ -17,221 +18,378 @@ import (
 
 // SessionService manages container sessions
 type SessionService struct {
+	cfg           *config.Config
 	dockerManager *docker.DockerManager
 	portManager   *port.PortManager
+	sessionStore  store.SessionStore
 	sessions      map[string]*model.Session
 	mu            sync.Mutex
 	logger        *util.Logger
+
+	// provisionQueue feeds sessions waiting for their container to the worker pool
+	provisionQueue chan provisionJob
+	// provisioning holds the cancel functions of sessions still being provisioned
+	provisioning map[string]context.CancelFunc
+	// readinessProbes holds the cancel functions of sessions waiting to become ready
+	readinessProbes map[string]context.CancelFunc
+	// activitySaved holds the last activity time written to the session store per session
+	activitySaved map[string]time.Time
+	// transitions holds the lifecycle operation of sessions whose containers are being
+	// stopped, started, paused, restarted, recreated or torn down on expiry
+	transitions map[string]string
+	// changed is closed and replaced whenever a session changes
+	changed chan struct{}
+	// snapshotMu serializes snapshot creation and deletion so the snapshot quota holds
+	snapshotMu sync.Mutex
+
+	// templates holds every version of every template, oldest first, guarded by templatesMu
+	templateStore store.TemplateStore
+	templates     map[string][]*model.SessionTemplate
+	templatesMu   sync.Mutex
 }
 
 // NewSessionService creates a new session service
-func NewSessionService(dockerManager *docker.DockerManager, portManager *port.PortManager) *SessionService {
-	return &SessionService{
-		dockerManager: dockerManager,
-		portManager:   portManager,
-		sessions:      make(map[string]*model.Session),
-		logger:        util.NewLogger(),
+func NewSessionService(cfg *config.Config, dockerManager *docker.DockerManager, portManager *port.PortManager, sessionStore store.SessionStore, templateStore store.TemplateStore) *SessionService {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	if sessionStore == nil {
+		sessionStore = store.NewMemoryStore()
+	}
+	if templateStore == nil {
+		templateStore = store.NewMemoryTemplateStore()
+	}
+
+	ss := &SessionService{
+		cfg:            cfg,
+		dockerManager:  dockerManager,
+		portManager:    portManager,
+		sessionStore:   sessionStore,
+		sessions:       make(map[string]*model.Session),
+		logger:         util.NewLogger(),
+		provisionQueue: make(chan provisionJob, cfg.ProvisionQueueSize),
+		provisioning:   make(map[string]context.CancelFunc),
+		changed:        make(chan struct{}),
+
+		readinessProbes: make(map[string]context.CancelFunc),
+		transitions:     make(map[string]string),
+		activitySaved:   make(map[string]time.Time),
+		templateStore:   templateStore,
+		templates:       make(map[string][]*model.SessionTemplate),
 	}
+	ss.startProvisioners(cfg.ProvisionWorkers)
+
+	return ss
 }
 
-// CreateSession creates a new container session for the specified Docker image
-func (ss *SessionService) CreateSession(req *model.CreateSessionRequest) (*model.Session, error) {
+// RestoreSessions loads persisted sessions from the session store, re-reserves their
+// host ports and drops any session whose container no longer exists
+func (ss *SessionService) RestoreSessions() (int, error) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	// Validate request
-	if req.ImageName == "" {
-		return nil, util.ErrInvalidRequest
+	sessions, err := ss.sessionStore.Load()
+	if err != nil {
+		return 0, util.WrapError(err, "failed to load sessions")
 	}
 
-	// Internal struct to handle port mappings consistently
-	type portConfig struct {
-		ContainerPort int
-		Protocol      string
-		Description   string
-	}
+	restored := 0
+	for _, session := range sessions {
+		// URLs follow the current proxy settings rather than those the session was created under
+		ss.setPortURLs(session.ID, session.Ports)
+
+		// Expired sessions no longer own a container or ports
+		if session.Status == model.SessionStatusExpired {
+			ss.sessions[session.ID] = session
+			restored++
+			continue
+		}
 
-	// Create a slice to hold our port configurations
-	var portConfigs []portConfig
+		// Provisioning cannot be resumed because the request (and its secrets) is not stored
+		if session.Status == model.SessionStatusProvisioning {
+			session.Status = model.SessionStatusError
+			session.StatusReason = "provisioning was interrupted by a server restart"
+			if err := ss.persistSession(session); err != nil {
+				ss.logger.Warn("Failure of session %s will not survive a restart: %v", session.ID, err)
+			}
+		}
 
-	// First attempt: Use provided port mappings if available
-	if len(req.PortMappings) > 0 {
-		ss.logger.Info("Using explicitly defined port mappings for image %s", req.ImageName)
-		portConfigs = make([]portConfig, len(req.PortMappings))
-		for i, mapping := range req.PortMappings {
-			portConfigs[i] = portConfig{
-				ContainerPort: mapping.ContainerPort,
-				Protocol:      mapping.Protocol,
-				Description:   mapping.Description,
+		// Sessions without a container only keep their record
+		if session.ContainerID == "" {
+			ss.sessions[session.ID] = session
+			restored++
+			continue
+		}
+
+		exists, err := ss.dockerManager.ContainerExists(session.ContainerID)
+		if err != nil {
+			ss.logger.Warn("Failed to check if container %s exists: %v", session.ContainerID, err)
+		} else if !exists {
+			ss.logger.Info("Container %s for persisted session %s no longer exists, dropping session",
+				session.ContainerID, session.ID)
+			if err := ss.sessionStore.Delete(session.ID); err != nil {
+				ss.logger.Error("Failed to delete session %s from store: %v", session.ID, err)
 			}
+			continue
 		}
-	} else if req.NumPorts > 0 {
-		// Second attempt: Use NumPorts if specified
-		ss.logger.Info("Using specified number of ports (%d) for image %s", req.NumPorts, req.ImageName)
-		portConfigs = make([]portConfig, req.NumPorts)
 
-		for i := 0; i < req.NumPorts; i++ {
-			portConfigs[i] = portConfig{
-				ContainerPort: 8080 + i, // Generic default starting at 8080
-				Protocol:      "tcp",
-				Description:   fmt.Sprintf("Port %d", i+1),
+		for _, p := range session.Ports {
+			if !ss.portManager.ReservePort(p.HostPort) {
+				ss.logger.Warn("Port %d for session %s is already reserved", p.HostPort, session.ID)
 			}
 		}
-	} else {
-		// Final attempt: Detect exposed ports from the image
-		ss.logger.Info("Attempting to detect exposed ports for image %s", req.ImageName)
-		images, err := ss.dockerManager.ListImages()
+
+		ss.sessions[session.ID] = session
+		restored++
+
+		// Probing starts over for sessions that were still becoming ready
+		if session.Status == model.SessionStatusStarting && session.Readiness != nil {
+			ss.startReadinessProbe(session)
+		}
+	}
+
+	ss.logger.Info("Restored %d sessions from session store", restored)
+	return restored, nil
+}
+
+// ReconcileSessions rebuilds sessions and port reservations from the labels of managed
+// containers, recovering sessions that are missing from the session store
+func (ss *SessionService) ReconcileSessions() (int, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	containers, err := ss.dockerManager.ListManagedContainers()
+	if err != nil {
+		return 0, util.WrapError(err, "failed to list managed containers")
+	}
+
+	// The containers of a stack add up to one session, and egress proxies belong to one
+	var recoveredSessions []*model.Session
+	stacks := make(map[string]*model.Session)
+	egressProxies := make(map[string]string)
+	for _, c := range containers {
+		if c.Labels[docker.LabelEgressProxy] == "true" {
+			egressProxies[c.Labels[docker.LabelSessionID]] = c.ID
+			continue
+		}
+
+		session, err := sessionFromLabels(c)
 		if err != nil {
-			ss.logger.Error("Failed to list images: %v", err)
-			return nil, util.WrapError(err, "failed to list images")
+			ss.logger.Warn("Skipping managed container %s: %v", c.ID, err)
+			continue
 		}
 
-		var exposedPorts []int
+		if _, exists := ss.sessions[session.ID]; exists {
+			continue
+		}
 
-		// Find matching image and get its exposed ports
-		for _, img := range images {
-			imgNameWithTag := img.Repository
-			if img.Tag != "" {
-				imgNameWithTag += ":" + img.Tag
-			}
+		if stack, exists := stacks[session.ID]; exists {
+			mergeStackContainer(stack, session)
+			continue
+		}
+		if len(session.Services) > 0 {
+			stacks[session.ID] = session
+		}
+		recoveredSessions = append(recoveredSessions, session)
+	}
 
-			// Check for match by full name:tag or just name
-			if imgNameWithTag == req.ImageName || img.Repository == req.ImageName {
-				exposedPorts = img.ExposedPorts
-				ss.logger.Info("Found matching image: %s with %d exposed ports", imgNameWithTag, len(exposedPorts))
-				break
+	recovered := 0
+	for _, session := range recoveredSessions {
+		session.EgressProxyID = egressProxies[session.ID]
+		ss.setPortURLs(session.ID, session.Ports)
+		for i := range session.Ports {
+			if !ss.portManager.ReservePort(session.Ports[i].HostPort) {
+				ss.logger.Warn("Port %d for session %s is already reserved", session.Ports[i].HostPort, session.ID)
 			}
 		}
 
-		if len(exposedPorts) == 0 {
-			// If we couldn't detect any ports, use a default configuration
-			ss.logger.Warn("No exposed ports detected for image %s, using default HTTP port", req.ImageName)
-			portConfigs = []portConfig{
-				{ContainerPort: 80, Protocol: "tcp", Description: "HTTP"},
-			}
-		} else {
-			// Use the detected exposed ports
-			ss.logger.Info("Using %d exposed ports from image %s: %v", len(exposedPorts), req.ImageName, exposedPorts)
-			portConfigs = make([]portConfig, len(exposedPorts))
-
-			for i, port := range exposedPorts {
-				// Use more descriptive names for common ports
-				var description string
-				switch port {
-				case 80, 8080:
-					description = "HTTP"
-				case 443, 8443:
-					description = "HTTPS"
-				case 22:
-					description = "SSH"
-				case 3306:
-					description = "MySQL"
-				case 5432:
-					description = "PostgreSQL"
-				case 27017:
-					description = "MongoDB"
-				case 6379:
-					description = "Redis"
-				default:
-					description = fmt.Sprintf("Port %d", port)
-				}
-
-				portConfigs[i] = portConfig{
-					ContainerPort: port,
-					Protocol:      "tcp",
-					Description:   description,
-				}
-			}
+		if err := ss.persistSession(session); err != nil {
+			ss.logger.Warn("Recovered session %s will not be persisted: %v", session.ID, err)
 		}
+
+		ss.sessions[session.ID] = session
+		recovered++
+		ss.logger.Info("Recovered session %s from %d containers", session.ID, max(len(session.Services), 1))
+	}
+
+	ss.logger.Info("Reconciled %d managed containers, recovered %d sessions", len(containers), recovered)
+	return recovered, nil
+}
+
+// persistSession writes the session to the session store. Callers must hold ss.mu.
+func (ss *SessionService) persistSession(session *model.Session) error {
+	if err := ss.sessionStore.Save(session); err != nil {
+		ss.logger.Error("Failed to persist session %s: %v", session.ID, err)
+		return util.WrapError(err, "failed to persist session")
+	}
+	ss.notifyChange()
+	return nil
+}
+
+// forgetSession removes the session from memory and from the session store. Callers must hold ss.mu.
+func (ss *SessionService) forgetSession(sessionID string) {
+	ss.stopReadinessProbe(sessionID)
+	delete(ss.sessions, sessionID)
+	delete(ss.activitySaved, sessionID)
+	if err := ss.sessionStore.Delete(sessionID); err != nil {
+		ss.logger.Error("Failed to delete session %s from store: %v", sessionID, err)
+	}
+	ss.notifyChange()
+}
+
+// CreateSession validates a request and registers a new session in the provisioning state.
+// The image pull and container creation happen asynchronously in a provisioning worker;
+// clients follow progress through GetSession or WaitForSession.
+func (ss *SessionService) CreateSession(req *model.CreateSessionRequest) (*model.Session, error) {
+	// Expand templates first so the result is validated like any other request
+	req, err := ss.resolveTemplate(req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get available host ports from the port manager
-	hostPorts := make([]int, len(portConfigs))
-	for i := range portConfigs {
-		port, err := ss.portManager.GetAvailablePort()
+	// Validate request
+	var services []model.ServiceContainer
+	if req.Stack != nil {
+		req, services, err = ss.resolveStack(req)
 		if err != nil {
-			// Release any ports we've already allocated
-			ss.logger.Error("Failed to get available port: %v", err)
-			for j := 0; j < i; j++ {
-				ss.portManager.ReleasePort(hostPorts[j])
-			}
-			return nil, util.WrapError(err, "failed to get available port")
+			return nil, err
 		}
-		hostPorts[i] = port
 	}
-
-	// Create docker port mappings
-	dockerPortMappings := make([]docker.PortMapping, len(portConfigs))
-	for i, config := range portConfigs {
-		dockerPortMappings[i] = docker.PortMapping{
-			HostPort:      hostPorts[i],
-			ContainerPort: config.ContainerPort,
-			Protocol:      config.Protocol,
+	if req.SnapshotID != "" {
+		if req.ImageName != "" {
+			return nil, util.WrapError(util.ErrInvalidRequest, "image_name and snapshot_id are mutually exclusive")
+		}
+		snapshot, err := ss.GetSnapshot(req.SnapshotID)
+		if err != nil {
+			if util.IsNotFoundError(err) {
+				return nil, util.WrapError(util.ErrInvalidRequest, "snapshot %s not found", req.SnapshotID)
+			}
+			return nil, err
 		}
+		resolved := *req
+		resolved.ImageName = snapshot.Image
+		req = &resolved
+	}
+	if req.ImageName == "" {
+		return nil, util.ErrInvalidRequest
 	}
 
-	// Log the ports being allocated
-	ss.logger.Info("Creating container for image %s with %d port mappings", req.ImageName, len(dockerPortMappings))
-	for i, mapping := range dockerPortMappings {
-		ss.logger.Debug("Port %d: %d -> %d/%s", i+1, mapping.HostPort, mapping.ContainerPort, mapping.Protocol)
+	expiresAt, err := ss.resolveExpiry(req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create container
-	containerID, err := ss.dockerManager.CreateContainer(req.ImageName, dockerPortMappings)
+	resources, err := ss.resolveResources(req.Resources)
 	if err != nil {
-		// Release all allocated ports
-		ss.logger.Error("Failed to create container: %v", err)
-		for _, port := range hostPorts {
-			ss.portManager.ReleasePort(port)
-		}
-		return nil, util.WrapError(err, "failed to create container")
+		return nil, err
 	}
 
-	// Create session ports
-	sessionPorts := make([]model.Port, len(portConfigs))
-	hostname, _ := getLocalIP()
-	if hostname == "" {
-		hostname = "localhost"
+	if err := validateEnv(req.Env); err != nil {
+		return nil, err
 	}
 
-	for i, config := range portConfigs {
-		proto := config.Protocol
-		if proto == "" {
-			proto = "tcp"
-		}
+	if err := ss.validateMounts(req.Mounts, req.SessionVolume); err != nil {
+		return nil, err
+	}
+
+	if err := validateReadiness(req.Readiness); err != nil {
+		return nil, err
+	}
+
+	if err := validateInternalNetwork(req); err != nil {
+		return nil, err
+	}
 
-		portURL := ""
-		if proto == "tcp" {
-			scheme := "http"
-			if config.ContainerPort == 443 || config.ContainerPort == 8443 {
-				scheme = "https"
+	if err := validatePortSchemes(req.PortMappings); err != nil {
+		return nil, err
+	}
+	if req.Stack != nil {
+		for _, service := range req.Stack.Services {
+			if err := validatePortSchemes(service.Ports); err != nil {
+				return nil, err
 			}
-			portURL = fmt.Sprintf("%s://%s:%d", scheme, hostname, hostPorts[i])
 		}
+	}
 
-		sessionPorts[i] = model.Port{
-			HostPort:      hostPorts[i],
-			ContainerPort: config.ContainerPort,
-			Protocol:      proto,
-			Description:   config.Description,
-			URL:           portURL,
-		}
+	egressPolicy, err := resolveEgress(req)
+	if err != nil {
+		return nil, err
+	}
+	if egressPolicy != req.Egress {
+		resolved := *req
+		resolved.Egress = egressPolicy
+		req = &resolved
+	}
+
+	restartPolicy, err := resolveRestartPolicy(req.RestartPolicy)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create session
 	sessionID := uuid.New().String()
 	session := &model.Session{
-		ID:          sessionID,
-		CreatedAt:   time.Now(),
-		ImageName:   req.ImageName,
-		ContainerID: containerID,
-		Ports:       sessionPorts,
-		Status:      "running",
+		ID:           sessionID,
+		CreatedAt:    time.Now(),
+		ImageName:    req.ImageName,
+		Ports:        []model.Port{},
+		Status:       model.SessionStatusProvisioning,
+		StatusReason: "waiting for a provisioning worker",
+		ExpiresAt:    expiresAt,
+		Resources:    resources,
+		// Secret values only ever reach the container, never the session record
+		Env:           redactEnv(req.Env),
+		Command:       req.Command,
+		Entrypoint:    req.Entrypoint,
+		WorkingDir:    req.WorkingDir,
+		User:          req.User,
+		Mounts:        req.Mounts,
+		Readiness:     req.Readiness,
+		RestartPolicy: restartPolicy,
+		SnapshotID:    req.SnapshotID,
+		OwnsSnapshot:  req.SnapshotID != "" && req.OwnsSnapshot,
+
+		Template:        req.Template,
+		TemplateVersion: req.TemplateVersion,
+		Services:        services,
+		InternalNetwork: req.InternalNetwork,
+		Egress:          req.Egress,
+	}
+
+	if req.SessionVolume != nil {
+		session.SessionVolume = &model.SessionVolume{
+			Name:   sessionVolumeName(sessionID),
+			Target: req.SessionVolume.Target,
+			Retain: req.SessionVolume.Retain,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if err := ss.persistSession(session); err != nil {
+		cancel()
+		return nil, err
+	}
+	ss.sessions[sessionID] = session
+	ss.provisioning[sessionID] = cancel
+
+	// Hand the Docker work to the worker pool without blocking the caller
+	select {
+	case ss.provisionQueue <- provisionJob{ctx: ctx, sessionID: sessionID, req: *req}:
+	default:
+		cancel()
+		delete(ss.provisioning, sessionID)
+		ss.forgetSession(sessionID)
+		ss.logger.Warn("Provisioning queue is full, rejecting session for image %s", req.ImageName)
+		return nil, util.WrapError(util.ErrResourceBusy, "too many sessions are being provisioned")
 	}
 
-	ss.sessions[session.ID] = session
-	ss.logger.Info("Created session %s for image %s", session.ID, req.ImageName)
-	return session, nil
+	ss.logger.Info("Accepted session %s for image %s%s", sessionID, req.ImageName, templateLabel(session))
+	result := *session
+	return &result, nil
 }
 
 // DeleteSession deletes an existing session by ID
@@ -245,32 +403,102 @@ func (ss *SessionService) DeleteSession(sessionID string) error {
 		return util.ErrNotFound
 	}
 
-	// Stop and remove container
-	ss.logger.Info("Stopping container %s for session %s", session.ContainerID, sessionID)
-	if err := ss.dockerManager.StopContainer(session.ContainerID); err != nil {
-		ss.logger.Error("Failed to stop container %s: %v", session.ContainerID, err)
-		return util.WrapError(err, "failed to stop container")
+	// A provisioning worker cleans up after sessions deleted while being provisioned
+	if cancel, provisioning := ss.provisioning[sessionID]; provisioning {
+		ss.logger.Info("Cancelling provisioning of session %s", sessionID)
+		cancel()
+		delete(ss.provisioning, sessionID)
+		ss.forgetSession(sessionID)
+		return nil
+	}
+
+	// Expired sessions have already been torn down
+	if session.Status != model.SessionStatusExpired {
+		if err := ss.teardownSession(session); err != nil {
+			return err
+		}
+	}
+
+	// Remove session
+	ss.forgetSession(sessionID)
+	ss.logger.Info("Deleted session %s", sessionID)
+	return nil
+}
+
+// teardownSession stops and removes the session's container and releases its ports.
+// Callers must hold ss.mu.
+func (ss *SessionService) teardownSession(session *model.Session) error {
+	ss.stopReadinessProbe(session.ID)
+	release := ss.releaseCopy(session)
+	return ss.releaseSession(&release)
+}
+
+// releaseCopy returns a copy of a session to hand to releaseSession. A snapshot the session
+// owns is kept while other sessions still use it, and removed with the last of them.
+// Callers must hold ss.mu.
+func (ss *SessionService) releaseCopy(session *model.Session) model.Session {
+	release := *session
+	if release.OwnsSnapshot && ss.snapshotShared(session) {
+		release.OwnsSnapshot = false
+	}
+	return release
+}
+
+// releaseSession stops and removes the session's containers and releases its ports, volume,
+// network and owned snapshot. It only reads the session, so callers may pass a copy and need
+// not hold ss.mu, but must have stopped its readiness probe.
+func (ss *SessionService) releaseSession(session *model.Session) error {
+	// Sessions that failed before getting a container have nothing to stop
+	containerIDs := sessionContainerIDs(session)
+	if len(containerIDs) == 0 {
+		for _, p := range session.Ports {
+			ss.portManager.ReleasePort(p.HostPort)
+		}
+		ss.removeSessionVolume(session)
+		ss.removeSessionNetwork(session)
+		ss.removeOwnedSnapshot(session)
+		return nil
 	}
 
-	ss.logger.Info("Removing container %s for session %s", session.ContainerID, sessionID)
-	if err := ss.dockerManager.RemoveContainer(session.ContainerID); err != nil {
-		ss.logger.Error("Failed to remove container %s: %v", session.ContainerID, err)
-		return util.WrapError(err, "failed to remove container")
+	// Stacks are taken down in reverse start order, dependents first
+	for i := len(containerIDs) - 1; i >= 0; i-- {
+		containerID := containerIDs[i]
+
+		// Paused containers have to be unpaused before they can be stopped
+		if session.Status == model.SessionStatusPaused {
+			if err := ss.dockerManager.UnpauseContainer(containerID); err != nil && !docker.IsNotFound(err) {
+				ss.logger.Warn("Failed to unpause container %s: %v", containerID, err)
+			}
+		}
+
+		// A container that is already gone only needs its ports released
+		ss.logger.Info("Stopping container %s for session %s", containerID, session.ID)
+		if err := ss.dockerManager.StopContainer(containerID); err != nil && !docker.IsNotFound(err) {
+			ss.logger.Error("Failed to stop container %s: %v", containerID, err)
+			return util.WrapError(err, "failed to stop container")
+		}
+
+		ss.logger.Info("Removing container %s for session %s", containerID, session.ID)
+		if err := ss.dockerManager.RemoveContainer(containerID); err != nil && !docker.IsNotFound(err) {
+			ss.logger.Error("Failed to remove container %s: %v", containerID, err)
+			return util.WrapError(err, "failed to remove container")
+		}
 	}
 
 	// Release all ports
-	ss.logger.Info("Releasing ports for session %s", sessionID)
+	ss.logger.Info("Releasing ports for session %s", session.ID)
 	for _, p := range session.Ports {
 		ss.portManager.ReleasePort(p.HostPort)
 	}
 
-	// Remove session
-	delete(ss.sessions, sessionID)
-	ss.logger.Info("Deleted session %s", sessionID)
+	ss.removeSessionVolume(session)
+	ss.removeSessionNetwork(session)
+	ss.removeOwnedSnapshot(session)
 	return nil
 }
 
-// DeleteAllSessions deletes all existing sessions
+// DeleteAllSessions deletes all existing sessions. Sessions whose containers cannot be
+// removed are kept, so deleting them can be retried.
 func (ss *SessionService) DeleteAllSessions() (int, error) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
@@ -297,34 +525,25 @@ func (ss *SessionService) DeleteAllSessions() (int, error) {
 			continue
 		}
 
-		// Stop and remove container
-		ss.logger.Info("Stopping container %s for session %s", session.ContainerID, id)
-		stopErr := ss.dockerManager.StopContainer(session.ContainerID)
-
-		ss.logger.Info("Removing container %s for session %s", session.ContainerID, id)
-		removeErr := ss.dockerManager.RemoveContainer(session.ContainerID)
-
-		// We attempt to remove even if stop fails
-		if stopErr != nil {
-			errMsg := fmt.Sprintf("failed to stop container %s", session.ContainerID)
-			ss.logger.Error("%s: %v", errMsg, stopErr)
-			errors = append(errors, util.WrapError(stopErr, errMsg))
-		}
-
-		if removeErr != nil {
-			errMsg := fmt.Sprintf("failed to remove container %s", session.ContainerID)
-			ss.logger.Error("%s: %v", errMsg, removeErr)
-			errors = append(errors, util.WrapError(removeErr, errMsg))
+		if cancel, provisioning := ss.provisioning[id]; provisioning {
+			cancel()
+			delete(ss.provisioning, id)
+			ss.forgetSession(id)
+			count++
+			continue
 		}
 
-		// Release all ports
-		ss.logger.Info("Releasing ports for session %s", id)
-		for _, p := range session.Ports {
-			ss.portManager.ReleasePort(p.HostPort)
+		// Expired sessions have already been torn down
+		if session.Status != model.SessionStatusExpired {
+			if err := ss.teardownSession(session); err != nil {
+				ss.logger.Error("Failed to delete session %s: %v", id, err)
+				errors = append(errors, util.WrapError(err, "failed to delete session %s", id))
+				continue
+			}
 		}
 
-		// Remove session from map
-		delete(ss.sessions, id)
+		// Remove session from map and store
+		ss.forgetSession(id)
 		count++
 	}
 
@@ -338,7 +557,9 @@ func (ss *SessionService) DeleteAllSessions() (int, error) {
 	return count, nil
 }
 
-// ListSessions returns a list of all sessions
+// ListSessions returns copies of all sessions, with the state of their containers as listed
+// by Docker. Sessions are not changed on the way; the health monitor brings their status in
+// line with their containers.
 func (ss *SessionService) ListSessions() []*model.Session {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
@@ -346,28 +567,36 @@ func (ss *SessionService) ListSessions() []*model.Session {
 	sessions := make([]*model.Session, 0, len(ss.sessions))
 	sessionsToRemove := []string{}
 
+	// Check every session against a single container listing rather than one Docker call each
+	containerStates, listErr := ss.containerStates()
+	if listErr != nil {
+		ss.logger.Warn("Failed to list containers: %v", listErr)
+	}
+
 	for id, session := range ss.sessions {
-		// Verify if the container still exists
-		exists, err := ss.dockerManager.ContainerExists(session.ContainerID)
-		if err != nil {
-			ss.logger.Warn("Failed to check if container %s exists: %v", session.ContainerID, err)
-			// Keep the session but mark it as potentially problematic
-			session.Status = "unknown"
-		} else if !exists {
+		// Return copies so callers can read them without holding the lock
+		result := *session
+
+		// Expired sessions are reported as-is until the reaper forgets them, and sessions
+		// without a container yet have nothing to check
+		if session.Status == model.SessionStatusExpired || session.ContainerID == "" {
+			sessions = append(sessions, &result)
+			continue
+		}
+
+		if listErr != nil {
+			// Report the session as potentially problematic, without touching its record
+			result.Status = model.SessionStatusUnknown
+		} else if state, exists := containerStates[session.ContainerID]; !exists {
 			ss.logger.Info("Container %s for session %s no longer exists, marking as removed",
 				session.ContainerID, session.ID)
-
-			// Option 1: Auto-remove the session
 			sessionsToRemove = append(sessionsToRemove, id)
-
-			// Option 2: Keep the session but mark it as stopped/removed
-			// session.Status = "removed"
+			continue
+		} else {
+			result.Container = listedContainerState(state)
 		}
 
-		// Add to results list if not marked for removal
-		if len(sessionsToRemove) == 0 || !contains(sessionsToRemove, id) {
-			sessions = append(sessions, session)
-		}
+		sessions = append(sessions, &result)
 	}
 
 	// Clean up any sessions with containers that no longer exist
@@ -375,13 +604,26 @@ func (ss *SessionService) ListSessions() []*model.Session {
 		ss.logger.Info("Auto-cleaning session %s with missing container", id)
 		session := ss.sessions[id]
 
-		// Release allocated ports
-		for _, p := range session.Ports {
-			ss.portManager.ReleasePort(p.HostPort)
+		// The other containers of a stack or an egress proxy may still be around and hold on
+		// to the session's network
+		if len(session.Services) > 0 || session.EgressProxyID != "" {
+			if err := ss.teardownSession(session); err != nil {
+				ss.logger.Warn("Failed to tear down the rest of session %s: %v", id, err)
+			}
+		} else {
+			// Release allocated ports
+			for _, p := range session.Ports {
+				ss.portManager.ReleasePort(p.HostPort)
+			}
+
+			ss.removeSessionVolume(session)
+			ss.removeSessionNetwork(session)
+			release := ss.releaseCopy(session)
+			ss.removeOwnedSnapshot(&release)
 		}
 
-		// Remove from sessions map
-		delete(ss.sessions, id)
+		// Remove from sessions map and store
+		ss.forgetSession(id)
 	}
 
 	ss.logger.Info("Listed %d sessions (removed %d orphaned sessions)",
@@ -389,6 +631,32 @@ func (ss *SessionService) ListSessions() []*model.Session {
 	return sessions
 }
 
+// containerStates returns the state ("running", "exited", ...) of all containers known to
+// Docker, running or not, by container ID
+func (ss *SessionService) containerStates() (map[string]string, error) {
+	containers, err := ss.dockerManager.ListContainers(true)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]string, len(containers))
+	for _, c := range containers {
+		states[c.ID] = c.State
+	}
+	return states, nil
+}
+
+// listedContainerState converts the state of a container in a Docker container listing to its
+// API representation
+func listedContainerState(state string) *model.ContainerState {
+	return &model.ContainerState{
+		Status:     state,
+		Running:    state == "running" || state == "paused" || state == "restarting",
+		Paused:     state == "paused",
+		Restarting: state == "restarting",
+	}
+}
+
 // Helper function to check if a string is in a slice
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -424,24 +692,6 @@ func (ss *SessionService) ListImages() ([]model.DockerImageInfo, error) {
 	return result, nil
 }
 
-// Helper function to get the local IP address
-func getLocalIP() (string, error) {
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		return "", err
-	}
-
-	for _, addr := range addrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				return ipnet.IP.String(), nil
-			}
-		}
-	}
-
-	return "", fmt.Errorf("no suitable IP address found")
-}
-
 // ListAllContainers returns all Docker containers, including those not managed by the application
 func (s *SessionService) ListAllContainers(ctx context.Context) ([]model.ContainerInfo, error) {
 	containers, err := s.dockerManager.ListContainers(true) // true to include all containers, not just running ones
@@ -449,16 +699,21 @@ func (s *SessionService) ListAllContainers(ctx context.Context) ([]model.Contain
 		return nil, fmt.Errorf("failed to list containers: %v", err)
 	}
 
+	// Map container IDs to session IDs for quick lookups
+	s.mu.Lock()
+	containerSessions := make(map[string]string, len(s.sessions))
+	for _, session := range s.sessions {
+		containerSessions[session.ContainerID] = session.ID
+	}
+	s.mu.Unlock()
+
 	var containerInfos []model.ContainerInfo
 
 	for _, container := range containers {
-		// Check if this container belongs to a session
-		var sessionID string
-		for _, session := range s.sessions {
-			if session.ContainerID == container.ID {
-				sessionID = session.ID
-				break
-			}
+		// Check if this container belongs to a session, falling back to its cube labels
+		sessionID := containerSessions[container.ID]
+		if sessionID == "" && container.Labels[docker.LabelManaged] == "true" {
+			sessionID = container.Labels[docker.LabelSessionID]
 		}
 
 		// Get container name without leading slash
@@ -481,6 +736,7 @@ func (s *SessionService) ListAllContainers(ctx context.Context) ([]model.Contain
 			Created:   container.Created,
 			CreatedAt: time.Unix(container.Created, 0),
 			IsManaged: sessionID != "", // If sessionID is not empty, it's managed by our application
+			Networks:  container.Networks,
 		}
 
 		// Convert ports
@@ -514,7 +770,7 @@ func (s *SessionService) DeleteContainer(ctx context.Context, containerID string
 	var sessionID string
 	s.mu.Lock()
 	for id, session := range s.sessions {
-		if session.ContainerID == containerID {
+		if contains(sessionContainerIDs(session), containerID) {
 			sessionID = id
 			break
 		}
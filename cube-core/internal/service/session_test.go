@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker/dockertest"
+	"github.com/yourusername/session-manager/pkg/port"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// newTestService creates a session service against a fake Docker API, keeping sessions and
+// templates in memory. configure, if set, adjusts the configuration first.
+func newTestService(t *testing.T, configure func(*config.Config)) (*dockertest.FakeAPI, *SessionService) {
+	t.Helper()
+
+	fake, dockerManager := dockertest.NewFakeAPI(t)
+	cfg := config.DefaultConfig()
+	cfg.SessionStorePath = ""
+	cfg.TemplateStorePath = ""
+	if configure != nil {
+		configure(cfg)
+	}
+	return fake, NewSessionService(cfg, dockerManager, port.NewPortManager(), nil, nil)
+}
+
+// createTestSession creates a session and waits for it to settle
+func createTestSession(t *testing.T, ss *SessionService, req *model.CreateSessionRequest) *model.Session {
+	t.Helper()
+
+	session, err := ss.CreateSession(req)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	return waitSettled(t, ss, session.ID)
+}
+
+// waitSettled waits for a session to finish provisioning and starting
+func waitSettled(t *testing.T, ss *SessionService, sessionID string) *model.Session {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	settled, err := ss.WaitForSession(ctx, sessionID, IsSessionSettled)
+	if err != nil {
+		t.Fatalf("WaitForSession: %v", err)
+	}
+	if !IsSessionSettled(settled) {
+		t.Fatalf("session %s is still %s", settled.ID, settled.Status)
+	}
+	return settled
+}
+
+func TestDeleteAllSessions(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	source := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName:     "nginx:latest",
+		NumPorts:      1,
+		SessionVolume: &model.SessionVolume{Target: "/data"},
+	})
+	cloned, err := ss.CloneSession(context.Background(), source.ID, &model.CloneSessionRequest{IncludeFilesystem: true})
+	if err != nil {
+		t.Fatalf("CloneSession: %v", err)
+	}
+	if clone := waitSettled(t, ss, cloned.ID); clone.Status != model.SessionStatusRunning {
+		t.Fatalf("clone is %s: %s", clone.Status, clone.StatusReason)
+	}
+	if _, err := ss.ApplySessionOperation(source.ID, OperationPause); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+
+	count, err := ss.DeleteAllSessions()
+	if err != nil || count != 2 {
+		t.Fatalf("DeleteAllSessions = %d, %v; want 2 sessions", count, err)
+	}
+
+	// Paused containers, session volumes and the clone's snapshot all go with their sessions
+	if containers := fake.Containers(); len(containers) != 0 {
+		t.Errorf("containers = %+v, want none", containers)
+	}
+	if volumes := fake.Volumes(); len(volumes) != 0 {
+		t.Errorf("volumes = %v, want none", volumes)
+	}
+	snapshots, err := ss.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("snapshots = %+v, want none", snapshots)
+	}
+}
+
+func TestListSessionsLeavesSessionsUnchanged(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1})
+	fake.SetContainerStatus(session.ContainerID, "exited", 1)
+
+	sessions := ss.ListSessions()
+	if len(sessions) != 1 {
+		t.Fatalf("listed %d sessions, want 1", len(sessions))
+	}
+	if listed := sessions[0]; listed.Container == nil || listed.Container.Status != "exited" {
+		t.Fatalf("listed container state = %+v, want exited", listed.Container)
+	}
+
+	// The listing reports the container but leaves the session to the health monitor
+	ss.mu.Lock()
+	status := ss.sessions[session.ID].Status
+	container := ss.sessions[session.ID].Container
+	ss.mu.Unlock()
+	if status != model.SessionStatusRunning || container != nil {
+		t.Fatalf("session status %s, container %+v; want running and no container state", status, container)
+	}
+
+	ss.checkSessionContainers()
+	if got, _ := ss.GetSession(session.ID); got.Status != model.SessionStatusExited {
+		t.Fatalf("status after health check = %s, want exited", got.Status)
+	}
+}
+
+func TestGetSession(t *testing.T) {
+	tests := []struct {
+		name string
+		// change alters the container behind the session's back
+		change          func(t *testing.T, fake *dockertest.FakeAPI, ss *SessionService, containerID string)
+		wantContainer   model.ContainerState
+		wantStatus      string
+		wantHealth      string
+		wantStartedTime bool
+	}{
+		{
+			name:            "running",
+			wantContainer:   model.ContainerState{Status: "running", Running: true},
+			wantStatus:      model.SessionStatusRunning,
+			wantStartedTime: true,
+		},
+		{
+			name: "exited",
+			change: func(t *testing.T, fake *dockertest.FakeAPI, ss *SessionService, containerID string) {
+				fake.SetContainerStatus(containerID, "exited", 137)
+			},
+			wantContainer:   model.ContainerState{Status: "exited", ExitCode: 137},
+			wantStatus:      model.SessionStatusExited,
+			wantStartedTime: true,
+		},
+		{
+			name: "unhealthy",
+			change: func(t *testing.T, fake *dockertest.FakeAPI, ss *SessionService, containerID string) {
+				fake.SetContainerHealth(containerID, model.HealthStatusUnhealthy)
+			},
+			wantContainer:   model.ContainerState{Status: "running", Running: true},
+			wantStatus:      model.SessionStatusRunning,
+			wantHealth:      model.HealthStatusUnhealthy,
+			wantStartedTime: true,
+		},
+		{
+			name: "missing container",
+			change: func(t *testing.T, fake *dockertest.FakeAPI, ss *SessionService, containerID string) {
+				if err := ss.dockerManager.RemoveContainer(containerID); err != nil {
+					t.Fatalf("RemoveContainer: %v", err)
+				}
+			},
+			wantContainer: model.ContainerState{Status: "missing"},
+			wantStatus:    model.SessionStatusRunning,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, ss := newTestService(t, nil)
+			fake.AddImage("nginx:latest")
+			session := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest"})
+			if tt.change != nil {
+				tt.change(t, fake, ss, session.ContainerID)
+			}
+
+			got, err := ss.GetSession(session.ID)
+			if err != nil {
+				t.Fatalf("GetSession: %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if got.Container == nil {
+				t.Fatal("no container state")
+			}
+			if (got.Container.StartedAt != nil) != tt.wantStartedTime {
+				t.Errorf("started at = %v, want set %v", got.Container.StartedAt, tt.wantStartedTime)
+			}
+			state := *got.Container
+			state.StartedAt, state.FinishedAt = nil, nil
+			if state != tt.wantContainer {
+				t.Errorf("container = %+v, want %+v", state, tt.wantContainer)
+			}
+			health := ""
+			if got.Health != nil {
+				health = got.Health.Status
+			}
+			if health != tt.wantHealth {
+				t.Errorf("health = %q, want %q", health, tt.wantHealth)
+			}
+		})
+	}
+
+	_, ss := newTestService(t, nil)
+	if _, err := ss.GetSession("missing"); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("GetSession of a missing session = %v, want ErrNotFound", err)
+	}
+}
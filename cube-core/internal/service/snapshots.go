@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// CreateSnapshot commits a session's container filesystem to a new image. Volumes and other
+// mounts are not part of the snapshot, and secret environment variables are blanked.
+func (ss *SessionService) CreateSnapshot(ctx context.Context, sessionID string, req *model.CreateSnapshotRequest) (*model.Snapshot, error) {
+	ss.mu.Lock()
+	session, exists := ss.sessions[sessionID]
+	if !exists {
+		ss.mu.Unlock()
+		return nil, util.ErrNotFound
+	}
+	if session.ContainerID == "" || session.Status == model.SessionStatusExpired {
+		ss.mu.Unlock()
+		return nil, util.WrapError(util.ErrOperationNotValid, "session %s has no container to snapshot", sessionID)
+	}
+	if len(session.Services) > 0 {
+		ss.mu.Unlock()
+		return nil, util.WrapError(util.ErrOperationNotValid, "cannot snapshot stack session %s", sessionID)
+	}
+	source := *session
+	ss.mu.Unlock()
+
+	// Committing takes a while, so the quota is held by its own lock rather than ss.mu
+	ss.snapshotMu.Lock()
+	defer ss.snapshotMu.Unlock()
+
+	if ss.cfg.MaxSnapshots > 0 {
+		snapshots, err := ss.listSnapshots()
+		if err != nil {
+			return nil, err
+		}
+		if len(snapshots) >= ss.cfg.MaxSnapshots {
+			return nil, util.WrapError(util.ErrOperationNotValid, "snapshot quota of %d reached; delete a snapshot first", ss.cfg.MaxSnapshots)
+		}
+	}
+
+	snapshot := &model.Snapshot{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		SessionID:   source.ID,
+		SourceImage: source.ImageName,
+		CreatedAt:   time.Now(),
+	}
+	snapshot.Image = fmt.Sprintf("%s:%s", ss.cfg.SnapshotRepository, snapshot.ID)
+
+	comment := req.Comment
+	if comment == "" {
+		comment = fmt.Sprintf("snapshot of session %s", source.ID)
+	}
+
+	ss.logger.Info("Snapshotting session %s to %s", source.ID, snapshot.Image)
+	imageID, err := ss.dockerManager.CommitContainer(ctx, source.ContainerID, snapshot.Image, comment, snapshotLabels(snapshot), snapshotEnv(source.Env))
+	if err != nil {
+		ss.logger.Error("Failed to snapshot session %s: %v", source.ID, err)
+		if docker.IsNotFound(err) {
+			return nil, util.WrapError(util.ErrOperationNotValid, "container of session %s no longer exists", source.ID)
+		}
+		return nil, util.WrapError(err, "failed to snapshot session %s", source.ID)
+	}
+	snapshot.ImageID = imageID
+
+	ss.logger.Info("Created snapshot %s of session %s", snapshot.ID, source.ID)
+	return snapshot, nil
+}
+
+// ListSnapshots returns all snapshots, newest first
+func (ss *SessionService) ListSnapshots() ([]model.Snapshot, error) {
+	return ss.listSnapshots()
+}
+
+// ListSessionSnapshots returns the snapshots taken of a session, newest first
+func (ss *SessionService) ListSessionSnapshots(sessionID string) ([]model.Snapshot, error) {
+	ss.mu.Lock()
+	_, exists := ss.sessions[sessionID]
+	ss.mu.Unlock()
+	if !exists {
+		return nil, util.ErrNotFound
+	}
+
+	snapshots, err := ss.listSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []model.Snapshot{}
+	for _, snapshot := range snapshots {
+		if snapshot.SessionID == sessionID {
+			result = append(result, snapshot)
+		}
+	}
+	return result, nil
+}
+
+// GetSnapshot returns a snapshot by ID
+func (ss *SessionService) GetSnapshot(snapshotID string) (*model.Snapshot, error) {
+	snapshots, err := ss.listSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.ID == snapshotID {
+			return &snapshot, nil
+		}
+	}
+	return nil, util.WrapError(util.ErrNotFound, "snapshot %s not found", snapshotID)
+}
+
+// DeleteSnapshot removes a snapshot's image. Snapshots that sessions were started from can
+// only be deleted once those sessions are gone.
+func (ss *SessionService) DeleteSnapshot(snapshotID string) error {
+	ss.snapshotMu.Lock()
+	defer ss.snapshotMu.Unlock()
+
+	snapshot, err := ss.GetSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+
+	if err := ss.dockerManager.RemoveImage(snapshot.Image); err != nil {
+		if docker.IsConflict(err) {
+			return util.WrapError(util.ErrOperationNotValid, "snapshot %s is used by a session container", snapshotID)
+		}
+		if docker.IsNotFound(err) {
+			return util.WrapError(util.ErrNotFound, "snapshot %s not found", snapshotID)
+		}
+		ss.logger.Error("Failed to delete snapshot %s: %v", snapshotID, err)
+		return util.WrapError(err, "failed to delete snapshot %s", snapshotID)
+	}
+
+	ss.logger.Info("Deleted snapshot %s", snapshotID)
+	return nil
+}
+
+// removeOwnedSnapshot deletes the snapshot a session was started from if the session owns
+// it. It only reads the session, so callers need not hold ss.mu.
+func (ss *SessionService) removeOwnedSnapshot(session *model.Session) {
+	if !session.OwnsSnapshot || session.SnapshotID == "" {
+		return
+	}
+
+	ss.logger.Info("Deleting snapshot %s of session %s", session.SnapshotID, session.ID)
+	if err := ss.DeleteSnapshot(session.SnapshotID); err != nil && !util.IsNotFoundError(err) {
+		ss.logger.Warn("Failed to delete snapshot %s of session %s: %v", session.SnapshotID, session.ID, err)
+	}
+}
+
+// snapshotShared reports whether a session other than the given one, and not yet expired,
+// was started from the same snapshot. Callers must hold ss.mu.
+func (ss *SessionService) snapshotShared(session *model.Session) bool {
+	for id, other := range ss.sessions {
+		if id != session.ID && other.SnapshotID == session.SnapshotID && other.Status != model.SessionStatusExpired {
+			return true
+		}
+	}
+	return false
+}
+
+// listSnapshots reads all snapshots from the labels of snapshot images
+func (ss *SessionService) listSnapshots() ([]model.Snapshot, error) {
+	images, err := ss.dockerManager.ListImagesWithLabel(docker.LabelSnapshotID)
+	if err != nil {
+		return nil, util.WrapError(err, "failed to list snapshots")
+	}
+
+	snapshots := make([]model.Snapshot, 0, len(images))
+	for _, img := range images {
+		snapshots = append(snapshots, snapshotFromImage(img))
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// snapshotLabels builds the image labels that describe a snapshot. The session labels the
+// image inherits from the container are cleared, so that containers started from the
+// snapshot do not pick up the old session's identity, expiry, network or egress policy.
+func snapshotLabels(snapshot *model.Snapshot) map[string]string {
+	labels := map[string]string{
+		docker.LabelSnapshotID:          snapshot.ID,
+		docker.LabelSnapshotName:        snapshot.Name,
+		docker.LabelSnapshotSessionID:   snapshot.SessionID,
+		docker.LabelSnapshotSourceImage: snapshot.SourceImage,
+		docker.LabelSnapshotCreatedAt:   snapshot.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	for _, key := range sessionLabelKeys {
+		labels[key] = ""
+	}
+	return labels
+}
+
+// snapshotEnv blanks the secret environment variables of a session in the image committed
+// from its container, which would otherwise keep their values
+func snapshotEnv(env []model.EnvVar) []string {
+	var result []string
+	for _, e := range env {
+		if e.Secret {
+			result = append(result, e.Name+"=")
+		}
+	}
+	return result
+}
+
+// snapshotFromImage rebuilds a snapshot from the labels of its image
+func snapshotFromImage(img docker.LabeledImage) model.Snapshot {
+	snapshot := model.Snapshot{
+		ID:          img.Labels[docker.LabelSnapshotID],
+		Name:        img.Labels[docker.LabelSnapshotName],
+		SessionID:   img.Labels[docker.LabelSnapshotSessionID],
+		SourceImage: img.Labels[docker.LabelSnapshotSourceImage],
+		Image:       img.ID,
+		ImageID:     img.ID,
+		SizeBytes:   img.Size,
+		CreatedAt:   time.Unix(img.Created, 0),
+	}
+
+	if len(img.RepoTags) > 0 {
+		snapshot.Image = img.RepoTags[0]
+	}
+	if raw := img.Labels[docker.LabelSnapshotCreatedAt]; raw != "" {
+		if parsed, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			snapshot.CreatedAt = parsed
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestSnapshotLabels(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	labels := snapshotLabels(&model.Snapshot{
+		ID:          "snap-1",
+		Name:        "seeded",
+		SessionID:   "s1",
+		SourceImage: "postgres:16",
+		CreatedAt:   createdAt,
+	})
+
+	want := map[string]string{
+		docker.LabelSnapshotID:          "snap-1",
+		docker.LabelSnapshotName:        "seeded",
+		docker.LabelSnapshotSessionID:   "s1",
+		docker.LabelSnapshotSourceImage: "postgres:16",
+		docker.LabelSnapshotCreatedAt:   "2024-05-01T12:00:00Z",
+	}
+	for key, value := range want {
+		if labels[key] != value {
+			t.Errorf("label %s = %q, want %q", key, labels[key], value)
+		}
+	}
+	// Containers started from the snapshot must not look like the source session
+	for _, key := range sessionLabelKeys {
+		if value, exists := labels[key]; !exists || value != "" {
+			t.Errorf("session label %s = %q (set %v), want it cleared", key, value, exists)
+		}
+	}
+}
+
+func TestSnapshotEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  []model.EnvVar
+		want []string
+	}{
+		{name: "none"},
+		{name: "no secrets", env: []model.EnvVar{{Name: "MODE", Value: "dev"}}},
+		{
+			name: "secrets blanked",
+			env: []model.EnvVar{
+				{Name: "MODE", Value: "dev"},
+				{Name: "API_TOKEN", Value: "s3cret", Secret: true},
+				{Name: "DB_PASSWORD", Value: "hunter2", Secret: true},
+			},
+			want: []string{"API_TOKEN=", "DB_PASSWORD="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snapshotEnv(tt.env); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("snapshotEnv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotFromImage(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	labeled := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	labels := map[string]string{
+		docker.LabelSnapshotID:          "snap-1",
+		docker.LabelSnapshotName:        "seeded",
+		docker.LabelSnapshotSessionID:   "s1",
+		docker.LabelSnapshotSourceImage: "postgres:16",
+	}
+	withCreatedAt := func(value string) map[string]string {
+		result := map[string]string{docker.LabelSnapshotCreatedAt: value}
+		for key, v := range labels {
+			result[key] = v
+		}
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		img           docker.LabeledImage
+		wantImage     string
+		wantCreatedAt time.Time
+	}{
+		{
+			name:          "tagged",
+			img:           docker.LabeledImage{ID: "sha256:abc", RepoTags: []string{"cube-snapshots:snap-1"}, Labels: withCreatedAt(labeled.Format(time.RFC3339Nano)), Created: created.Unix()},
+			wantImage:     "cube-snapshots:snap-1",
+			wantCreatedAt: labeled,
+		},
+		{
+			name:          "untagged",
+			img:           docker.LabeledImage{ID: "sha256:abc", Labels: labels, Created: created.Unix()},
+			wantImage:     "sha256:abc",
+			wantCreatedAt: created,
+		},
+		{
+			name:          "invalid created at label",
+			img:           docker.LabeledImage{ID: "sha256:abc", RepoTags: []string{"cube-snapshots:snap-1"}, Labels: withCreatedAt("yesterday"), Created: created.Unix()},
+			wantImage:     "cube-snapshots:snap-1",
+			wantCreatedAt: created,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := snapshotFromImage(tt.img)
+			if snapshot.ID != "snap-1" || snapshot.Name != "seeded" || snapshot.SessionID != "s1" || snapshot.SourceImage != "postgres:16" {
+				t.Fatalf("snapshotFromImage = %+v", snapshot)
+			}
+			if snapshot.Image != tt.wantImage || snapshot.ImageID != "sha256:abc" {
+				t.Errorf("image %s (ID %s), want %s", snapshot.Image, snapshot.ImageID, tt.wantImage)
+			}
+			if !snapshot.CreatedAt.Equal(tt.wantCreatedAt) {
+				t.Errorf("created at %s, want %s", snapshot.CreatedAt, tt.wantCreatedAt)
+			}
+		})
+	}
+}
+
+func TestCreateSnapshotRejections(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+	gone := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest"})
+	if err := ss.dockerManager.RemoveContainer(gone.ContainerID); err != nil {
+		t.Fatalf("RemoveContainer: %v", err)
+	}
+	ss.sessions["provisioning"] = &model.Session{ID: "provisioning", Status: model.SessionStatusProvisioning}
+	ss.sessions["expired"] = &model.Session{ID: "expired", Status: model.SessionStatusExpired, ContainerID: "c1"}
+	ss.sessions["stack"] = &model.Session{ID: "stack", Status: model.SessionStatusRunning, ContainerID: "c2", Services: []model.ServiceContainer{{Name: "db"}}}
+
+	tests := []struct {
+		sessionID string
+		wantErr   error
+	}{
+		{sessionID: "missing", wantErr: util.ErrNotFound},
+		{sessionID: "provisioning", wantErr: util.ErrOperationNotValid},
+		{sessionID: "expired", wantErr: util.ErrOperationNotValid},
+		{sessionID: "stack", wantErr: util.ErrOperationNotValid},
+		{sessionID: gone.ID, wantErr: util.ErrOperationNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sessionID, func(t *testing.T) {
+			if _, err := ss.CreateSnapshot(context.Background(), tt.sessionID, &model.CreateSnapshotRequest{}); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateSnapshot = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnapshotQuotaAndListing(t *testing.T) {
+	fake, ss := newTestService(t, func(cfg *config.Config) {
+		cfg.MaxSnapshots = 2
+	})
+	fake.AddImage("nginx:latest")
+	first := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest"})
+	second := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest"})
+
+	older, err := ss.CreateSnapshot(context.Background(), first.ID, &model.CreateSnapshotRequest{Name: "older"})
+	if err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	newer, err := ss.CreateSnapshot(context.Background(), second.ID, &model.CreateSnapshotRequest{Name: "newer"})
+	if err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+
+	// The quota counts snapshots of every session
+	if _, err := ss.CreateSnapshot(context.Background(), first.ID, &model.CreateSnapshotRequest{}); !errors.Is(err, util.ErrOperationNotValid) {
+		t.Fatalf("CreateSnapshot over the quota = %v, want ErrOperationNotValid", err)
+	}
+
+	snapshots, err := ss.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].ID != newer.ID || snapshots[1].ID != older.ID {
+		t.Fatalf("ListSnapshots = %+v, want newest first", snapshots)
+	}
+	if snapshots[1].Name != "older" || snapshots[1].SessionID != first.ID || snapshots[1].SourceImage != "nginx:latest" {
+		t.Fatalf("listed snapshot = %+v", snapshots[1])
+	}
+
+	sessionSnapshots, err := ss.ListSessionSnapshots(first.ID)
+	if err != nil {
+		t.Fatalf("ListSessionSnapshots: %v", err)
+	}
+	if len(sessionSnapshots) != 1 || sessionSnapshots[0].ID != older.ID {
+		t.Fatalf("ListSessionSnapshots = %+v, want only %s", sessionSnapshots, older.ID)
+	}
+	if _, err := ss.ListSessionSnapshots("missing"); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("ListSessionSnapshots of unknown session = %v, want ErrNotFound", err)
+	}
+
+	// Deleting a snapshot frees its place in the quota
+	if err := ss.DeleteSnapshot(older.ID); err != nil {
+		t.Fatalf("DeleteSnapshot: %v", err)
+	}
+	if _, err := ss.CreateSnapshot(context.Background(), first.ID, &model.CreateSnapshotRequest{}); err != nil {
+		t.Fatalf("CreateSnapshot after a delete: %v", err)
+	}
+}
+
+func TestDeleteSnapshotInUse(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	source := createTestSession(t, ss, &model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 1})
+	snapshot, err := ss.CreateSnapshot(context.Background(), source.ID, &model.CreateSnapshotRequest{Name: "base"})
+	if err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	restored := createTestSession(t, ss, &model.CreateSessionRequest{SnapshotID: snapshot.ID, NumPorts: 1})
+	if restored.Status != model.SessionStatusRunning {
+		t.Fatalf("restored session is %s: %s", restored.Status, restored.StatusReason)
+	}
+
+	// The session started from the snapshot keeps it from being deleted
+	if err := ss.DeleteSnapshot(snapshot.ID); !errors.Is(err, util.ErrOperationNotValid) {
+		t.Fatalf("DeleteSnapshot of a snapshot in use = %v, want ErrOperationNotValid", err)
+	}
+
+	if err := ss.DeleteSession(restored.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if err := ss.DeleteSnapshot(snapshot.ID); err != nil {
+		t.Fatalf("DeleteSnapshot: %v", err)
+	}
+	if err := ss.DeleteSnapshot(snapshot.ID); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("DeleteSnapshot of a deleted snapshot = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSnapshotBlanksSecretEnv(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("nginx:latest")
+
+	source := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName: "nginx:latest",
+		NumPorts:  1,
+		Env: []model.EnvVar{
+			{Name: "API_TOKEN", Value: "s3cret"},
+			{Name: "MODE", Value: "dev"},
+		},
+	})
+	cloned, err := ss.CloneSession(context.Background(), source.ID, &model.CloneSessionRequest{IncludeFilesystem: true})
+	if err != nil {
+		t.Fatalf("CloneSession: %v", err)
+	}
+	cloned = waitSettled(t, ss, cloned.ID)
+
+	snapshot, err := ss.GetSnapshot(cloned.SnapshotID)
+	if err != nil {
+		t.Fatalf("GetSnapshot: %v", err)
+	}
+	config, ok := fake.ImageConfig(snapshot.Image)
+	if !ok {
+		t.Fatalf("snapshot image %s is missing", snapshot.Image)
+	}
+	if env := envMap(config.Env); env["API_TOKEN"] != "" || env["MODE"] != "dev" {
+		t.Fatalf("snapshot env = %v, want API_TOKEN blanked and MODE kept", config.Env)
+	}
+
+	// The clone gets the secret from its own create request instead
+	for _, c := range fake.Containers() {
+		if c.ID == cloned.ContainerID {
+			if env := envMap(c.Env); env["API_TOKEN"] != "s3cret" {
+				t.Fatalf("clone env = %v, want API_TOKEN set", c.Env)
+			}
+			return
+		}
+	}
+	t.Fatalf("container %s of the clone is missing", cloned.ContainerID)
+}
+
+// envMap turns KEY=value pairs into a map
+func envMap(env []string) map[string]string {
+	result := make(map[string]string, len(env))
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		result[name] = value
+	}
+	return result
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/docker"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// stackServicePattern restricts service names to what works as a DNS name on the network
+var stackServicePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// resolveStack validates the stack of a request and returns a copy of the request with the
+// primary service settled, along with the session's services in start order
+func (ss *SessionService) resolveStack(req *model.CreateSessionRequest) (*model.CreateSessionRequest, []model.ServiceContainer, error) {
+	stack := req.Stack
+
+	// Everything that describes a single container is set per service instead
+	switch {
+	case req.ImageName != "" || req.SnapshotID != "":
+		return nil, nil, util.WrapError(util.ErrInvalidRequest, "stack sessions take their images from their services")
+	case req.NumPorts > 0 || len(req.PortMappings) > 0:
+		return nil, nil, util.WrapError(util.ErrInvalidRequest, "stack sessions publish ports per service")
+	case len(req.Env) > 0 || len(req.Command) > 0 || len(req.Entrypoint) > 0 || req.WorkingDir != "" || req.User != "":
+		return nil, nil, util.WrapError(util.ErrInvalidRequest, "stack sessions configure their containers per service")
+	case len(req.Mounts) > 0 || req.SessionVolume != nil:
+		return nil, nil, util.WrapError(util.ErrInvalidRequest, "stack sessions do not support mounts")
+	}
+
+	if len(stack.Services) == 0 {
+		return nil, nil, util.WrapError(util.ErrInvalidRequest, "stack has no services")
+	}
+
+	for name, service := range stack.Services {
+		if !stackServicePattern.MatchString(name) {
+			return nil, nil, util.WrapError(util.ErrInvalidRequest, "invalid service name %q", name)
+		}
+		if service.ImageName == "" {
+			return nil, nil, util.WrapError(util.ErrInvalidRequest, "service %s has no image_name", name)
+		}
+		if err := validateEnv(service.Env); err != nil {
+			return nil, nil, util.WrapError(err, "service %s", name)
+		}
+		for _, p := range service.Ports {
+			if p.ContainerPort <= 0 || p.ContainerPort > 65535 {
+				return nil, nil, util.WrapError(util.ErrInvalidRequest, "service %s has invalid container port %d", name, p.ContainerPort)
+			}
+		}
+		for _, dep := range service.DependsOn {
+			if dep == name {
+				return nil, nil, util.WrapError(util.ErrInvalidRequest, "service %s depends on itself", name)
+			}
+			if _, exists := stack.Services[dep]; !exists {
+				return nil, nil, util.WrapError(util.ErrInvalidRequest, "service %s depends on unknown service %s", name, dep)
+			}
+		}
+	}
+
+	order, err := stackStartOrder(stack)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	primary := stack.Primary
+	if primary == "" {
+		primary = order[0]
+		for _, name := range order {
+			if len(stack.Services[name].Ports) > 0 {
+				primary = name
+				break
+			}
+		}
+	} else if _, exists := stack.Services[primary]; !exists {
+		return nil, nil, util.WrapError(util.ErrInvalidRequest, "primary service %s is not defined", primary)
+	}
+
+	services := make([]model.ServiceContainer, len(order))
+	for i, name := range order {
+		service := stack.Services[name]
+
+		// Services without limits of their own share the session's
+		requested := service.Resources
+		if requested == nil {
+			requested = req.Resources
+		}
+		resources, err := ss.resolveResources(requested)
+		if err != nil {
+			return nil, nil, util.WrapError(err, "service %s", name)
+		}
+
+		services[i] = model.ServiceContainer{
+			Name:      name,
+			ImageName: service.ImageName,
+			Primary:   name == primary,
+			Env:       redactEnv(service.Env),
+			DependsOn: service.DependsOn,
+			Resources: resources,
+		}
+	}
+
+	resolvedStack := *stack
+	resolvedStack.Primary = primary
+	resolved := *req
+	resolved.Stack = &resolvedStack
+	resolved.ImageName = stack.Services[primary].ImageName
+	return &resolved, services, nil
+}
+
+// stackStartOrder orders the services of a stack so every service comes after the services it
+// depends on, breaking ties by name so the order is stable
+func stackStartOrder(stack *model.StackSpec) ([]string, error) {
+	pending := make(map[string]int, len(stack.Services))
+	dependents := make(map[string][]string, len(stack.Services))
+	for name, service := range stack.Services {
+		pending[name] = len(service.DependsOn)
+		for _, dep := range service.DependsOn {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var ready []string
+	for name, count := range pending {
+		if count == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	order := make([]string, 0, len(stack.Services))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+
+		for _, dependent := range dependents[name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(stack.Services) {
+		return nil, util.WrapError(util.ErrInvalidRequest, "stack services have circular dependencies")
+	}
+	return order, nil
+}
+
+// servicesInStartOrder sorts recovered services by their dependencies. Dependencies whose
+// containers are gone are ignored, and services fall back to name order if the labels do
+// not add up.
+func servicesInStartOrder(services []model.ServiceContainer) []model.ServiceContainer {
+	byName := make(map[string]model.ServiceContainer, len(services))
+	for _, service := range services {
+		byName[service.Name] = service
+	}
+
+	stack := &model.StackSpec{Services: make(map[string]model.StackService, len(services))}
+	for _, service := range services {
+		var dependsOn []string
+		for _, dep := range service.DependsOn {
+			if _, exists := byName[dep]; exists {
+				dependsOn = append(dependsOn, dep)
+			}
+		}
+		stack.Services[service.Name] = model.StackService{DependsOn: dependsOn}
+	}
+
+	sorted := make([]model.ServiceContainer, len(services))
+	copy(sorted, services)
+	order, err := stackStartOrder(stack)
+	if err != nil || len(order) != len(services) {
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].Name < sorted[j].Name
+		})
+		return sorted
+	}
+	for i, name := range order {
+		sorted[i] = byName[name]
+	}
+	return sorted
+}
+
+// provisionStack pulls the images of a stack, allocates host ports, creates the session's
+// private network and creates and starts the service containers in start order. On failure
+// everything it created is released again.
+func (ss *SessionService) provisionStack(ctx context.Context, session *model.Session, req *model.CreateSessionRequest) (*provisionedContainer, error) {
+	for _, service := range session.Services {
+		if err := ss.ensureImage(ctx, service.ImageName, req.RegistryAuth); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &provisionedContainer{}
+
+	// Allocate the host ports of all services up front, so they can all go in the labels
+	servicePorts := make(map[string][]docker.PortMapping, len(session.Services))
+	for _, service := range session.Services {
+		spec := req.Stack.Services[service.Name]
+
+		var configs []portConfig
+		for _, mapping := range spec.Ports {
+			proto := mapping.Protocol
+			if proto == "" {
+				proto = "tcp"
+			}
+			configs = append(configs, portConfig{ContainerPort: mapping.ContainerPort, Protocol: proto, Description: mapping.Description, Scheme: mapping.Scheme})
+		}
+		if service.Primary {
+			if err := checkReadinessPorts(req.Readiness, configs); err != nil {
+				ss.releaseProvisioned(session, result)
+				return nil, err
+			}
+		}
+
+		for _, config := range configs {
+			hostPort, err := ss.portManager.GetAvailablePort()
+			if err != nil {
+				ss.logger.Error("Failed to get available port: %v", err)
+				ss.releaseProvisioned(session, result)
+				return nil, util.WrapError(err, "failed to get available port")
+			}
+
+			p := model.Port{
+				HostPort:      hostPort,
+				ContainerPort: config.ContainerPort,
+				Protocol:      config.Protocol,
+				Description:   config.Description,
+				Scheme:        config.Scheme,
+				Service:       service.Name,
+			}
+			result.ports = append(result.ports, p)
+			servicePorts[service.Name] = append(servicePorts[service.Name], docker.PortMapping{
+				HostPort:      hostPort,
+				ContainerPort: config.ContainerPort,
+				Protocol:      config.Protocol,
+			})
+		}
+	}
+
+	ss.setPortURLs(session.ID, result.ports)
+
+	labelled := *session
+	labelled.Ports = result.ports
+	labelled.Network = sessionNetworkName(session.ID)
+	labels, err := sessionLabels(&labelled)
+	if err != nil {
+		ss.releaseProvisioned(session, result)
+		return nil, err
+	}
+
+	networkName, err := ss.createSessionNetwork(session)
+	if err != nil {
+		ss.releaseProvisioned(session, result)
+		return nil, err
+	}
+	result.network = networkName
+
+	// With an egress policy the proxy publishes the ports, and the containers have none of their own
+	if session.Egress != nil {
+		result.egressProxyID, err = ss.startEgressProxy(ctx, session, result.ports)
+		if err != nil {
+			ss.releaseProvisioned(session, result)
+			return nil, err
+		}
+		servicePorts = nil
+	}
+
+	restartPolicy, maxRetries := dockerRestartPolicy(session.RestartPolicy)
+
+	// Dependencies are started first; like compose, this does not wait for them to be ready
+	for _, service := range session.Services {
+		spec := req.Stack.Services[service.Name]
+
+		serviceLabels := make(map[string]string, len(labels)+3)
+		for k, v := range labels {
+			serviceLabels[k] = v
+		}
+		serviceLabels[docker.LabelService] = service.Name
+		if service.Primary {
+			serviceLabels[docker.LabelPrimary] = "true"
+		}
+		if len(service.DependsOn) > 0 {
+			serviceLabels[docker.LabelDependsOn] = strings.Join(service.DependsOn, ",")
+		}
+
+		ss.logger.Info("Creating container for service %s of session %s with image %s", service.Name, session.ID, service.ImageName)
+		containerID, err := ss.dockerManager.CreateContainer(docker.ContainerOptions{
+			Image:             service.ImageName,
+			PortMappings:      servicePorts[service.Name],
+			Labels:            serviceLabels,
+			Resources:         dockerResources(service.Resources),
+			Env:               dockerEnv(withEgressEnv(session, spec.Env)),
+			Cmd:               spec.Command,
+			Entrypoint:        spec.Entrypoint,
+			WorkingDir:        spec.WorkingDir,
+			User:              spec.User,
+			RestartPolicy:     restartPolicy,
+			MaxRestartRetries: maxRetries,
+			Network:           networkName,
+			NetworkAliases:    []string{service.Name},
+		})
+		if err != nil {
+			ss.logger.Error("Failed to create container for service %s: %v", service.Name, err)
+			ss.releaseProvisioned(session, result)
+			return nil, util.WrapError(err, "failed to create container for service %s", service.Name)
+		}
+
+		service.ContainerID = containerID
+		result.services = append(result.services, service)
+		if service.Primary {
+			result.containerID = containerID
+		}
+	}
+
+	return result, nil
+}
+
+// sessionContainerIDs returns the IDs of a session's containers in start order, beginning
+// with its egress proxy if it has one
+func sessionContainerIDs(session *model.Session) []string {
+	var ids []string
+	if session.EgressProxyID != "" {
+		ids = append(ids, session.EgressProxyID)
+	}
+
+	if len(session.Services) == 0 {
+		if session.ContainerID != "" {
+			ids = append(ids, session.ContainerID)
+		}
+		return ids
+	}
+
+	for _, service := range session.Services {
+		if service.ContainerID != "" {
+			ids = append(ids, service.ContainerID)
+		}
+	}
+	return ids
+}
+
+// inspectServices returns a copy of a session's services with the live state of their
+// containers. The primary container's state has already been inspected by the caller.
+func (ss *SessionService) inspectServices(session *model.Session, primaryState *model.ContainerState) []model.ServiceContainer {
+	services := make([]model.ServiceContainer, len(session.Services))
+	copy(services, session.Services)
+
+	for i, service := range services {
+		switch {
+		case service.ContainerID == "":
+			continue
+		case service.ContainerID == session.ContainerID:
+			services[i].Container = primaryState
+			continue
+		}
+
+		state, err := ss.dockerManager.InspectContainerState(service.ContainerID)
+		if err != nil {
+			if docker.IsNotFound(err) {
+				services[i].Container = &model.ContainerState{Status: "missing"}
+			} else {
+				ss.logger.Warn("Failed to inspect container %s of session %s: %v", service.ContainerID, session.ID, err)
+			}
+			continue
+		}
+		services[i].Container = containerStateFromDocker(state)
+	}
+	return services
+}
+
+// mergeStackContainer adds a container recovered from labels to a stack session recovered
+// from another of its containers, keeping the services in start order
+func mergeStackContainer(session, recovered *model.Session) {
+	session.Services = append(session.Services, recovered.Services...)
+	session.Services = servicesInStartOrder(session.Services)
+
+	if recovered.ContainerID != "" {
+		session.ContainerID = recovered.ContainerID
+		session.Status = recovered.Status
+	}
+}
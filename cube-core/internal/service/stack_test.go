@@ -0,0 +1,303 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// stackOf builds a stack from service names and what they depend on
+func stackOf(dependsOn map[string][]string) *model.StackSpec {
+	stack := &model.StackSpec{Services: make(map[string]model.StackService, len(dependsOn))}
+	for name, deps := range dependsOn {
+		stack.Services[name] = model.StackService{ImageName: name + ":latest", DependsOn: deps}
+	}
+	return stack
+}
+
+func TestStackStartOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		dependsOn map[string][]string
+		want      []string
+		wantErr   bool
+	}{
+		{name: "single", dependsOn: map[string][]string{"app": nil}, want: []string{"app"}},
+		{name: "independent by name", dependsOn: map[string][]string{"web": nil, "cache": nil, "db": nil}, want: []string{"cache", "db", "web"}},
+		{
+			name:      "chain",
+			dependsOn: map[string][]string{"caddy": {"app"}, "app": {"db"}, "db": nil},
+			want:      []string{"db", "app", "caddy"},
+		},
+		{
+			name:      "diamond",
+			dependsOn: map[string][]string{"web": {"api", "worker"}, "api": {"db"}, "worker": {"db"}, "db": nil},
+			want:      []string{"db", "api", "worker", "web"},
+		},
+		{
+			name:      "ready services by name",
+			dependsOn: map[string][]string{"app": {"db"}, "db": nil, "admin": nil},
+			want:      []string{"admin", "db", "app"},
+		},
+		{name: "cycle", dependsOn: map[string][]string{"a": {"b"}, "b": {"a"}}, wantErr: true},
+		{name: "longer cycle", dependsOn: map[string][]string{"a": {"c"}, "b": {"a"}, "c": {"b"}, "d": nil}, wantErr: true},
+		{name: "missing dependency", dependsOn: map[string][]string{"app": {"db"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := stackStartOrder(stackOf(tt.dependsOn))
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrInvalidRequest) {
+					t.Fatalf("stackStartOrder = %v, %v; want ErrInvalidRequest", order, err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(order, tt.want) {
+				t.Fatalf("stackStartOrder = %v, %v; want %v", order, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServicesInStartOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []model.ServiceContainer
+		want     []string
+	}{
+		{
+			name:     "dependencies first",
+			services: []model.ServiceContainer{{Name: "caddy", DependsOn: []string{"app"}}, {Name: "app", DependsOn: []string{"db"}}, {Name: "db"}},
+			want:     []string{"db", "app", "caddy"},
+		},
+		{
+			name:     "missing dependency ignored",
+			services: []model.ServiceContainer{{Name: "app", DependsOn: []string{"db"}}, {Name: "caddy", DependsOn: []string{"app"}}},
+			want:     []string{"app", "caddy"},
+		},
+		{
+			name:     "cycle falls back to name order",
+			services: []model.ServiceContainer{{Name: "b", DependsOn: []string{"a"}}, {Name: "a", DependsOn: []string{"b"}}, {Name: "c"}},
+			want:     []string{"a", "b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := servicesInStartOrder(tt.services)
+			names := make([]string, len(sorted))
+			for i, service := range sorted {
+				names[i] = service.Name
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Fatalf("servicesInStartOrder = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveStack(t *testing.T) {
+	todo := func() *model.StackSpec {
+		return &model.StackSpec{Services: map[string]model.StackService{
+			"db":    {ImageName: "postgres:16", Env: []model.EnvVar{{Name: "POSTGRES_PASSWORD", Value: "s3cret", Secret: true}}},
+			"app":   {ImageName: "todo:latest", DependsOn: []string{"db"}, Ports: []model.PortMapping{{ContainerPort: 3000}}},
+			"caddy": {ImageName: "caddy:2", DependsOn: []string{"app"}, Ports: []model.PortMapping{{ContainerPort: 80}}},
+		}}
+	}
+	with := func(change func(*model.CreateSessionRequest)) *model.CreateSessionRequest {
+		req := &model.CreateSessionRequest{Stack: todo()}
+		change(req)
+		return req
+	}
+
+	tests := []struct {
+		name        string
+		req         *model.CreateSessionRequest
+		wantErr     bool
+		wantPrimary string
+	}{
+		{name: "first service with ports is primary", req: with(func(*model.CreateSessionRequest) {}), wantPrimary: "app"},
+		{name: "explicit primary", req: with(func(r *model.CreateSessionRequest) { r.Stack.Primary = "caddy" }), wantPrimary: "caddy"},
+		{
+			name:        "no ports at all",
+			req:         &model.CreateSessionRequest{Stack: &model.StackSpec{Services: map[string]model.StackService{"worker": {ImageName: "worker:1"}, "db": {ImageName: "postgres:16"}}}},
+			wantPrimary: "db",
+		},
+		{name: "image on the request", req: with(func(r *model.CreateSessionRequest) { r.ImageName = "nginx:latest" }), wantErr: true},
+		{name: "snapshot on the request", req: with(func(r *model.CreateSessionRequest) { r.SnapshotID = "snap-1" }), wantErr: true},
+		{name: "ports on the request", req: with(func(r *model.CreateSessionRequest) { r.NumPorts = 1 }), wantErr: true},
+		{name: "env on the request", req: with(func(r *model.CreateSessionRequest) { r.Env = []model.EnvVar{{Name: "A"}} }), wantErr: true},
+		{name: "mounts on the request", req: with(func(r *model.CreateSessionRequest) { r.SessionVolume = &model.SessionVolume{Target: "/data"} }), wantErr: true},
+		{name: "no services", req: &model.CreateSessionRequest{Stack: &model.StackSpec{}}, wantErr: true},
+		{name: "invalid service name", req: with(func(r *model.CreateSessionRequest) {
+			r.Stack.Services["Web_1"] = model.StackService{ImageName: "nginx"}
+		}), wantErr: true},
+		{name: "service without image", req: with(func(r *model.CreateSessionRequest) { r.Stack.Services["cache"] = model.StackService{} }), wantErr: true},
+		{
+			name: "invalid service env",
+			req: with(func(r *model.CreateSessionRequest) {
+				r.Stack.Services["cache"] = model.StackService{ImageName: "redis", Env: []model.EnvVar{{Name: ""}}}
+			}),
+			wantErr: true,
+		},
+		{
+			name: "invalid service port",
+			req: with(func(r *model.CreateSessionRequest) {
+				r.Stack.Services["cache"] = model.StackService{ImageName: "redis", Ports: []model.PortMapping{{ContainerPort: 0}}}
+			}),
+			wantErr: true,
+		},
+		{name: "depends on itself", req: with(func(r *model.CreateSessionRequest) {
+			r.Stack.Services["db"] = model.StackService{ImageName: "postgres", DependsOn: []string{"db"}}
+		}), wantErr: true},
+		{name: "unknown dependency", req: with(func(r *model.CreateSessionRequest) {
+			r.Stack.Services["db"] = model.StackService{ImageName: "postgres", DependsOn: []string{"vault"}}
+		}), wantErr: true},
+		{name: "circular dependencies", req: with(func(r *model.CreateSessionRequest) {
+			r.Stack.Services["db"] = model.StackService{ImageName: "postgres", DependsOn: []string{"caddy"}}
+		}), wantErr: true},
+		{name: "unknown primary", req: with(func(r *model.CreateSessionRequest) { r.Stack.Primary = "vault" }), wantErr: true},
+	}
+	_, ss := newTestService(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, services, err := ss.resolveStack(tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrInvalidRequest) {
+					t.Fatalf("resolveStack = %v, want ErrInvalidRequest", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveStack: %v", err)
+			}
+
+			primary := tt.req.Stack.Services[tt.wantPrimary]
+			if resolved.Stack.Primary != tt.wantPrimary || resolved.ImageName != primary.ImageName {
+				t.Fatalf("primary %s with image %s, want %s with %s", resolved.Stack.Primary, resolved.ImageName, tt.wantPrimary, primary.ImageName)
+			}
+			if resolved.Stack == tt.req.Stack {
+				t.Fatal("the primary was settled on the request's own stack")
+			}
+			for _, service := range services {
+				if service.Primary != (service.Name == tt.wantPrimary) {
+					t.Errorf("service %s primary = %v", service.Name, service.Primary)
+				}
+			}
+		})
+	}
+
+	// Services come in start order, with secrets redacted
+	_, services, err := ss.resolveStack(&model.CreateSessionRequest{Stack: todo()})
+	if err != nil {
+		t.Fatalf("resolveStack: %v", err)
+	}
+	if len(services) != 3 || services[0].Name != "db" || services[1].Name != "app" || services[2].Name != "caddy" {
+		t.Fatalf("services = %+v, want db, app, caddy", services)
+	}
+	if services[0].Env[0].Value != model.RedactedValue {
+		t.Fatalf("service env = %+v, want the secret redacted", services[0].Env)
+	}
+}
+
+func TestResolveStackResources(t *testing.T) {
+	_, ss := newTestService(t, nil)
+	_, services, err := ss.resolveStack(&model.CreateSessionRequest{
+		Resources: &model.ResourceLimits{CPUs: 1},
+		Stack: &model.StackSpec{Services: map[string]model.StackService{
+			"app": {ImageName: "todo:latest"},
+			"db":  {ImageName: "postgres:16", Resources: &model.ResourceLimits{CPUs: 0.5}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("resolveStack: %v", err)
+	}
+
+	cpus := map[string]float64{}
+	for _, service := range services {
+		cpus[service.Name] = service.Resources.CPUs
+	}
+	// Services without limits of their own share the session's
+	if cpus["app"] != 1 || cpus["db"] != 0.5 {
+		t.Fatalf("service CPUs = %v, want app 1 and db 0.5", cpus)
+	}
+}
+
+func TestSessionContainerIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		session model.Session
+		want    []string
+	}{
+		{name: "no container"},
+		{name: "single container", session: model.Session{ContainerID: "c1"}, want: []string{"c1"}},
+		{name: "with egress proxy", session: model.Session{ContainerID: "c1", EgressProxyID: "proxy"}, want: []string{"proxy", "c1"}},
+		{
+			name: "stack in start order",
+			session: model.Session{
+				ContainerID: "app",
+				Services:    []model.ServiceContainer{{Name: "db", ContainerID: "db"}, {Name: "app", ContainerID: "app"}, {Name: "caddy"}},
+			},
+			want: []string{"db", "app"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionContainerIDs(&tt.session); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("sessionContainerIDs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStackSessionLifecycle(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	for _, image := range []string{"postgres:16", "todo:latest", "caddy:2"} {
+		fake.AddImage(image)
+	}
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{Stack: &model.StackSpec{Services: map[string]model.StackService{
+		"db":    {ImageName: "postgres:16"},
+		"app":   {ImageName: "todo:latest", DependsOn: []string{"db"}, Ports: []model.PortMapping{{ContainerPort: 3000}}},
+		"caddy": {ImageName: "caddy:2", DependsOn: []string{"app"}, Ports: []model.PortMapping{{ContainerPort: 80}}},
+	}}})
+	if session.Status != model.SessionStatusRunning {
+		t.Fatalf("stack session is %s: %s", session.Status, session.StatusReason)
+	}
+
+	// Containers are created in start order on the session's own network
+	containers := fake.Containers()
+	if len(containers) != 3 {
+		t.Fatalf("containers = %+v, want 3", containers)
+	}
+	for i, want := range []string{"postgres:16", "todo:latest", "caddy:2"} {
+		c := containers[i]
+		if c.Image != want || c.Status != "running" {
+			t.Errorf("container %d runs %s (%s), want %s running", i, c.Image, c.Status, want)
+		}
+		if len(c.Networks) != 1 || c.Networks[0] != sessionNetworkName(session.ID) {
+			t.Errorf("container %s is on %v, want the session network", c.Image, c.Networks)
+		}
+		if c.ID != session.Services[i].ContainerID {
+			t.Errorf("service %s records container %s, want %s", session.Services[i].Name, session.Services[i].ContainerID, c.ID)
+		}
+	}
+	if session.ContainerID != containers[1].ID || len(session.Ports) != 2 {
+		t.Fatalf("primary container %s with ports %+v, want the app container and two ports", session.ContainerID, session.Ports)
+	}
+
+	// Deleting the session tears down every container and the network
+	if err := ss.DeleteSession(session.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if containers := fake.Containers(); len(containers) != 0 {
+		t.Fatalf("containers after delete = %+v", containers)
+	}
+	for _, network := range fake.Networks() {
+		if network == sessionNetworkName(session.ID) {
+			t.Fatalf("session network %s was kept", network)
+		}
+	}
+}
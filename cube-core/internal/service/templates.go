@@ -0,0 +1,546 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// templateNamePattern restricts template names to something safe in URLs and file names
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
+// RestoreTemplates loads all template versions from the template store
+func (ss *SessionService) RestoreTemplates() (int, error) {
+	ss.templatesMu.Lock()
+	defer ss.templatesMu.Unlock()
+
+	templates, err := ss.templateStore.Load()
+	if err != nil {
+		return 0, util.WrapError(err, "failed to load templates")
+	}
+
+	for i := range templates {
+		template := templates[i]
+		ss.templates[template.Name] = append(ss.templates[template.Name], &template)
+	}
+	for _, versions := range ss.templates {
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].Version < versions[j].Version
+		})
+	}
+
+	ss.logger.Info("Restored %d templates from template store", len(ss.templates))
+	return len(ss.templates), nil
+}
+
+// LoadTemplateDir registers the templates defined in the *.yaml and *.yml files of a
+// directory, one template per file, named after the file unless it sets a name. A file
+// that differs from the version last loaded from it becomes a new version, so versions
+// added through the API in the meantime are only replaced when the file is edited. A
+// missing directory is not an error.
+func (ss *SessionService) LoadTemplateDir(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			ss.logger.Debug("Template directory %s does not exist", dir)
+			return 0, nil
+		}
+		return 0, util.WrapError(err, "failed to read template directory %s", dir)
+	}
+
+	loaded := 0
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		template, err := parseTemplateFile(path)
+		if err != nil {
+			return loaded, err
+		}
+		if template.Name == "" {
+			template.Name = strings.TrimSuffix(entry.Name(), ext)
+		}
+		template.Source = path
+
+		changed, err := ss.registerTemplate(template)
+		if err != nil {
+			return loaded, util.WrapError(err, "invalid template in %s", path)
+		}
+		if changed {
+			ss.logger.Info("Loaded template %s version %d from %s", template.Name, template.Version, path)
+			loaded++
+		}
+	}
+
+	return loaded, nil
+}
+
+// parseTemplateFile decodes a YAML template. The YAML uses the same field names as the
+// JSON API, so it is decoded through JSON rather than with separate YAML tags.
+func parseTemplateFile(path string) (*model.SessionTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, util.WrapError(err, "failed to read template %s", path)
+	}
+
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, util.WrapError(util.ErrInvalidRequest, "failed to parse template %s: %v", path, err)
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, util.WrapError(util.ErrInvalidRequest, "failed to parse template %s: %v", path, err)
+	}
+
+	var template model.SessionTemplate
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&template); err != nil {
+		return nil, util.WrapError(util.ErrInvalidRequest, "failed to parse template %s: %v", path, err)
+	}
+	return &template, nil
+}
+
+// registerTemplate stores a template loaded at startup as a new version, unless it is the
+// same as the version last loaded from its file. Templates stored before sources were
+// recorded are compared with their latest version instead. It reports whether a version
+// was added.
+func (ss *SessionService) registerTemplate(template *model.SessionTemplate) (bool, error) {
+	if err := ss.validateTemplate(template); err != nil {
+		return false, err
+	}
+
+	ss.templatesMu.Lock()
+	defer ss.templatesMu.Unlock()
+
+	previous := ss.latestTemplate(template.Name)
+	versions := ss.templates[template.Name]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Source == template.Source {
+			previous = versions[i]
+			break
+		}
+	}
+	if previous != nil && sameTemplate(previous, template) {
+		return false, nil
+	}
+	if err := ss.addTemplateVersion(template); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListTemplates returns the latest version of every template, ordered by name
+func (ss *SessionService) ListTemplates() []model.SessionTemplate {
+	ss.templatesMu.Lock()
+	defer ss.templatesMu.Unlock()
+
+	result := make([]model.SessionTemplate, 0, len(ss.templates))
+	for name := range ss.templates {
+		result = append(result, redactTemplate(ss.latestTemplate(name)))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// ListTemplateVersions returns every version of a template, oldest first
+func (ss *SessionService) ListTemplateVersions(name string) ([]model.SessionTemplate, error) {
+	ss.templatesMu.Lock()
+	defer ss.templatesMu.Unlock()
+
+	versions, exists := ss.templates[name]
+	if !exists {
+		return nil, util.WrapError(util.ErrNotFound, "template %s not found", name)
+	}
+
+	result := make([]model.SessionTemplate, len(versions))
+	for i, template := range versions {
+		result[i] = redactTemplate(template)
+	}
+	return result, nil
+}
+
+// GetTemplate returns a version of a template, or its latest version if version is zero
+func (ss *SessionService) GetTemplate(name string, version int) (*model.SessionTemplate, error) {
+	ss.templatesMu.Lock()
+	defer ss.templatesMu.Unlock()
+
+	template, err := ss.findTemplate(name, version)
+	if err != nil {
+		return nil, err
+	}
+	result := redactTemplate(template)
+	return &result, nil
+}
+
+// CreateTemplate registers a new template at version 1
+func (ss *SessionService) CreateTemplate(template *model.SessionTemplate) (*model.SessionTemplate, error) {
+	template.Source = ""
+	if err := ss.validateTemplate(template); err != nil {
+		return nil, err
+	}
+
+	ss.templatesMu.Lock()
+	defer ss.templatesMu.Unlock()
+
+	if _, exists := ss.templates[template.Name]; exists {
+		return nil, util.WrapError(util.ErrOperationNotValid, "template %s already exists", template.Name)
+	}
+	if err := ss.addTemplateVersion(template); err != nil {
+		return nil, err
+	}
+
+	ss.logger.Info("Created template %s", template.Name)
+	result := redactTemplate(template)
+	return &result, nil
+}
+
+// UpdateTemplate replaces a template with a new version. Secret environment values sent
+// back redacted keep their value from the previous version.
+func (ss *SessionService) UpdateTemplate(name string, template *model.SessionTemplate) (*model.SessionTemplate, error) {
+	if template.Name != "" && template.Name != name {
+		return nil, util.WrapError(util.ErrInvalidRequest, "template name %s does not match %s", template.Name, name)
+	}
+	template.Name = name
+	template.Source = ""
+
+	ss.templatesMu.Lock()
+	defer ss.templatesMu.Unlock()
+
+	latest := ss.latestTemplate(name)
+	if latest == nil {
+		return nil, util.WrapError(util.ErrNotFound, "template %s not found", name)
+	}
+
+	for i, e := range template.Env {
+		if e.Value != model.RedactedValue {
+			continue
+		}
+		for _, previous := range latest.Env {
+			if previous.Name == e.Name {
+				template.Env[i].Value = previous.Value
+			}
+		}
+	}
+
+	if err := ss.validateTemplate(template); err != nil {
+		return nil, err
+	}
+	if err := ss.addTemplateVersion(template); err != nil {
+		return nil, err
+	}
+
+	ss.logger.Info("Updated template %s to version %d", name, template.Version)
+	result := redactTemplate(template)
+	return &result, nil
+}
+
+// DeleteTemplate removes all versions of a template. Sessions created from it keep
+// recording the name and version.
+func (ss *SessionService) DeleteTemplate(name string) error {
+	ss.templatesMu.Lock()
+	defer ss.templatesMu.Unlock()
+
+	if _, exists := ss.templates[name]; !exists {
+		return util.WrapError(util.ErrNotFound, "template %s not found", name)
+	}
+	if err := ss.templateStore.Delete(name); err != nil {
+		ss.logger.Error("Failed to delete template %s from store: %v", name, err)
+		return util.WrapError(err, "failed to delete template")
+	}
+	delete(ss.templates, name)
+
+	ss.logger.Info("Deleted template %s", name)
+	return nil
+}
+
+// addTemplateVersion stores a template as the next version of its name. Callers must hold
+// ss.templatesMu.
+func (ss *SessionService) addTemplateVersion(template *model.SessionTemplate) error {
+	template.Version = 1
+	if latest := ss.latestTemplate(template.Name); latest != nil {
+		template.Version = latest.Version + 1
+	}
+	template.CreatedAt = time.Now()
+
+	stored := *template
+	if err := ss.templateStore.Save(&stored); err != nil {
+		ss.logger.Error("Failed to persist template %s: %v", template.Name, err)
+		return util.WrapError(err, "failed to persist template")
+	}
+	ss.templates[template.Name] = append(ss.templates[template.Name], &stored)
+	return nil
+}
+
+// latestTemplate returns the latest version of a template, or nil if there is none.
+// Callers must hold ss.templatesMu.
+func (ss *SessionService) latestTemplate(name string) *model.SessionTemplate {
+	versions := ss.templates[name]
+	if len(versions) == 0 {
+		return nil
+	}
+	return versions[len(versions)-1]
+}
+
+// findTemplate returns a version of a template, or its latest version if version is zero.
+// Callers must hold ss.templatesMu.
+func (ss *SessionService) findTemplate(name string, version int) (*model.SessionTemplate, error) {
+	if version == 0 {
+		if latest := ss.latestTemplate(name); latest != nil {
+			return latest, nil
+		}
+		return nil, util.WrapError(util.ErrNotFound, "template %s not found", name)
+	}
+
+	for _, template := range ss.templates[name] {
+		if template.Version == version {
+			return template, nil
+		}
+	}
+	return nil, util.WrapError(util.ErrNotFound, "template %s version %d not found", name, version)
+}
+
+// validateTemplate checks a template the way a create request using it would be checked
+func (ss *SessionService) validateTemplate(template *model.SessionTemplate) error {
+	if !templateNamePattern.MatchString(template.Name) {
+		return util.WrapError(util.ErrInvalidRequest, "invalid template name %q: use lowercase letters, digits, '.', '_' and '-'", template.Name)
+	}
+	if template.ImageName == "" {
+		return util.WrapError(util.ErrInvalidRequest, "template %s needs an image_name", template.Name)
+	}
+
+	for _, mapping := range template.PortMappings {
+		if mapping.ContainerPort <= 0 || mapping.ContainerPort > 65535 {
+			return util.WrapError(util.ErrInvalidRequest, "invalid container port %d", mapping.ContainerPort)
+		}
+		if mapping.Protocol != "" && mapping.Protocol != "tcp" && mapping.Protocol != "udp" {
+			return util.WrapError(util.ErrInvalidRequest, "invalid protocol %q for container port %d", mapping.Protocol, mapping.ContainerPort)
+		}
+	}
+	if err := validatePortSchemes(template.PortMappings); err != nil {
+		return err
+	}
+
+	if _, err := ss.resolveExpiry(&model.CreateSessionRequest{TTLSeconds: template.TTLSeconds}); err != nil {
+		return err
+	}
+	if _, err := ss.resolveResources(template.Resources); err != nil {
+		return err
+	}
+	if err := validateEnv(template.Env); err != nil {
+		return err
+	}
+	if err := validateReadiness(template.Readiness); err != nil {
+		return err
+	}
+	if _, err := resolveRestartPolicy(template.RestartPolicy); err != nil {
+		return err
+	}
+	return nil
+}
+
+// sameTemplate reports whether two templates define the same session, ignoring their
+// version and creation time
+func sameTemplate(a, b *model.SessionTemplate) bool {
+	left, right := *a, *b
+	left.Version, right.Version = 0, 0
+	left.CreatedAt, right.CreatedAt = time.Time{}, time.Time{}
+	left.Source, right.Source = "", ""
+
+	leftJSON, errLeft := json.Marshal(left)
+	rightJSON, errRight := json.Marshal(right)
+	return errLeft == nil && errRight == nil && bytes.Equal(leftJSON, rightJSON)
+}
+
+// redactTemplate returns a copy of a template with secret environment values redacted
+func redactTemplate(template *model.SessionTemplate) model.SessionTemplate {
+	result := *template
+	result.Env = redactEnv(template.Env)
+	return result
+}
+
+// resolveTemplate expands a create request that names a template into a full request: the
+// template's values, overridden by the request's own fields and then by its overrides. The
+// result records the template version it was built from.
+func (ss *SessionService) resolveTemplate(req *model.CreateSessionRequest) (*model.CreateSessionRequest, error) {
+	if req.Template == "" {
+		if req.Overrides != nil || req.TemplateVersion != 0 {
+			return nil, util.WrapError(util.ErrInvalidRequest, "overrides and template_version need a template")
+		}
+		return req, nil
+	}
+	if req.Overrides != nil && (req.Overrides.Template != "" || req.Overrides.TemplateVersion != 0 || req.Overrides.Overrides != nil) {
+		return nil, util.WrapError(util.ErrInvalidRequest, "overrides cannot name a template")
+	}
+
+	ss.templatesMu.Lock()
+	template, err := ss.findTemplate(req.Template, req.TemplateVersion)
+	if err != nil {
+		ss.templatesMu.Unlock()
+		if util.IsNotFoundError(err) {
+			return nil, util.WrapError(util.ErrInvalidRequest, "%v", err)
+		}
+		return nil, err
+	}
+	resolved := templateRequest(template)
+	ss.templatesMu.Unlock()
+
+	own := *req
+	own.Template, own.TemplateVersion, own.Overrides = "", 0, nil
+	mergeCreateRequest(resolved, &own)
+	if req.Overrides != nil {
+		mergeCreateRequest(resolved, req.Overrides)
+	}
+
+	resolved.Template = template.Name
+	resolved.TemplateVersion = template.Version
+	return resolved, nil
+}
+
+// templateRequest builds a create request from a template
+func templateRequest(template *model.SessionTemplate) *model.CreateSessionRequest {
+	req := &model.CreateSessionRequest{
+		ImageName:    template.ImageName,
+		PortMappings: append([]model.PortMapping(nil), template.PortMappings...),
+		Env:          append([]model.EnvVar(nil), template.Env...),
+		Readiness:    template.Readiness,
+		TTLSeconds:   template.TTLSeconds,
+		Command:      template.Command,
+		Entrypoint:   template.Entrypoint,
+		WorkingDir:   template.WorkingDir,
+		User:         template.User,
+	}
+	if template.Resources != nil {
+		resources := *template.Resources
+		req.Resources = &resources
+	}
+	if template.RestartPolicy != nil {
+		policy := *template.RestartPolicy
+		req.RestartPolicy = &policy
+	}
+	return req
+}
+
+// mergeCreateRequest applies the fields set in override to base. Resource limits and
+// environment variables are merged one by one; any other field replaces the base value.
+func mergeCreateRequest(base, override *model.CreateSessionRequest) {
+	if override.ImageName != "" {
+		base.ImageName = override.ImageName
+		base.SnapshotID = ""
+	}
+	if override.SnapshotID != "" {
+		base.SnapshotID = override.SnapshotID
+		base.ImageName = ""
+	}
+	if override.NumPorts > 0 {
+		base.NumPorts = override.NumPorts
+		base.PortMappings = nil
+	}
+	if len(override.PortMappings) > 0 {
+		base.PortMappings = override.PortMappings
+		base.NumPorts = 0
+	}
+	if override.TTLSeconds > 0 {
+		base.TTLSeconds = override.TTLSeconds
+		base.ExpiresAt = nil
+	}
+	if override.ExpiresAt != nil {
+		base.ExpiresAt = override.ExpiresAt
+		base.TTLSeconds = 0
+	}
+
+	if override.Resources != nil {
+		if base.Resources == nil {
+			base.Resources = &model.ResourceLimits{}
+		}
+		if override.Resources.CPUs != 0 {
+			base.Resources.CPUs = override.Resources.CPUs
+		}
+		if override.Resources.MemoryBytes != 0 {
+			base.Resources.MemoryBytes = override.Resources.MemoryBytes
+		}
+		if override.Resources.MemorySwapBytes != 0 {
+			base.Resources.MemorySwapBytes = override.Resources.MemorySwapBytes
+		}
+		if override.Resources.PidsLimit != 0 {
+			base.Resources.PidsLimit = override.Resources.PidsLimit
+		}
+		if override.Resources.BlkioWeight != 0 {
+			base.Resources.BlkioWeight = override.Resources.BlkioWeight
+		}
+	}
+
+	for _, e := range override.Env {
+		replaced := false
+		for i := range base.Env {
+			if base.Env[i].Name == e.Name {
+				base.Env[i] = e
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			base.Env = append(base.Env, e)
+		}
+	}
+
+	if override.Command != nil {
+		base.Command = override.Command
+	}
+	if override.Entrypoint != nil {
+		base.Entrypoint = override.Entrypoint
+	}
+	if override.WorkingDir != "" {
+		base.WorkingDir = override.WorkingDir
+	}
+	if override.User != "" {
+		base.User = override.User
+	}
+	if len(override.Mounts) > 0 {
+		base.Mounts = override.Mounts
+	}
+	if override.SessionVolume != nil {
+		base.SessionVolume = override.SessionVolume
+	}
+	if override.Readiness != nil {
+		base.Readiness = override.Readiness
+	}
+	if override.RestartPolicy != nil {
+		base.RestartPolicy = override.RestartPolicy
+	}
+	if override.RegistryAuth != nil {
+		base.RegistryAuth = override.RegistryAuth
+	}
+	if override.Stack != nil {
+		base.Stack = override.Stack
+	}
+	if override.InternalNetwork {
+		base.InternalNetwork = true
+	}
+	if override.Egress != nil {
+		base.Egress = override.Egress
+	}
+}
+
+// templateLabel describes the template a session was created from, for logs
+func templateLabel(session *model.Session) string {
+	if session.Template == "" {
+		return ""
+	}
+	return fmt.Sprintf(" from template %s version %d", session.Template, session.TemplateVersion)
+}
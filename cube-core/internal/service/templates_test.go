@@ -0,0 +1,429 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/internal/store"
+	"github.com/yourusername/session-manager/pkg/port"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// startTemplateService starts a service on a shared template store, the way main does
+func startTemplateService(t *testing.T, templateStore store.TemplateStore, dir string) *SessionService {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.SessionStorePath = ""
+	ss := NewSessionService(cfg, nil, port.NewPortManager(), nil, templateStore)
+	if _, err := ss.RestoreTemplates(); err != nil {
+		t.Fatalf("RestoreTemplates: %v", err)
+	}
+	if _, err := ss.LoadTemplateDir(dir); err != nil {
+		t.Fatalf("LoadTemplateDir: %v", err)
+	}
+	return ss
+}
+
+func writeTemplateFile(t *testing.T, dir, image string) {
+	t.Helper()
+
+	content := "image_name: " + image + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "web.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+}
+
+func latestImage(t *testing.T, ss *SessionService) (string, int) {
+	t.Helper()
+
+	template, err := ss.GetTemplate("web", 0)
+	if err != nil {
+		t.Fatalf("GetTemplate: %v", err)
+	}
+	return template.ImageName, template.Version
+}
+
+func TestLoadTemplateDirKeepsAPIUpdates(t *testing.T) {
+	dir := t.TempDir()
+	templateStore := store.NewMemoryTemplateStore()
+	writeTemplateFile(t, dir, "nginx:1.25")
+
+	ss := startTemplateService(t, templateStore, dir)
+	if image, version := latestImage(t, ss); image != "nginx:1.25" || version != 1 {
+		t.Fatalf("loaded %s at version %d, want nginx:1.25 at version 1", image, version)
+	}
+	if _, err := ss.UpdateTemplate("web", &model.SessionTemplate{ImageName: "nginx:1.27"}); err != nil {
+		t.Fatalf("UpdateTemplate: %v", err)
+	}
+
+	// An unchanged file does not revert the update on restart
+	ss = startTemplateService(t, templateStore, dir)
+	if image, version := latestImage(t, ss); image != "nginx:1.27" || version != 2 {
+		t.Fatalf("after restart got %s at version %d, want nginx:1.27 at version 2", image, version)
+	}
+
+	// Editing the file adds it as a new version
+	writeTemplateFile(t, dir, "nginx:1.28")
+	ss = startTemplateService(t, templateStore, dir)
+	if image, version := latestImage(t, ss); image != "nginx:1.28" || version != 3 {
+		t.Fatalf("after editing got %s at version %d, want nginx:1.28 at version 3", image, version)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template model.SessionTemplate
+		wantErr  bool
+	}{
+		{name: "image only", template: model.SessionTemplate{Name: "web", ImageName: "nginx:latest"}},
+		{
+			name: "full",
+			template: model.SessionTemplate{
+				Name:          "todo-app.v2",
+				ImageName:     "todo:latest",
+				PortMappings:  []model.PortMapping{{ContainerPort: 3000, Protocol: "tcp", Description: "app"}},
+				Env:           []model.EnvVar{{Name: "MODE", Value: "demo"}},
+				Resources:     &model.ResourceLimits{CPUs: 1, MemoryBytes: 256 << 20},
+				Readiness:     &model.ReadinessConfig{Checks: []model.ReadinessCheck{{Type: model.ReadinessCheckTCP, Port: 3000}}},
+				TTLSeconds:    3600,
+				RestartPolicy: &model.RestartPolicy{Name: "on-failure:3"},
+			},
+		},
+		{name: "uppercase name", template: model.SessionTemplate{Name: "Web", ImageName: "nginx:latest"}, wantErr: true},
+		{name: "name with slash", template: model.SessionTemplate{Name: "web/app", ImageName: "nginx:latest"}, wantErr: true},
+		{name: "no name", template: model.SessionTemplate{ImageName: "nginx:latest"}, wantErr: true},
+		{name: "no image", template: model.SessionTemplate{Name: "web"}, wantErr: true},
+		{name: "invalid port", template: model.SessionTemplate{Name: "web", ImageName: "nginx:latest", PortMappings: []model.PortMapping{{ContainerPort: 70000}}}, wantErr: true},
+		{name: "invalid protocol", template: model.SessionTemplate{Name: "web", ImageName: "nginx:latest", PortMappings: []model.PortMapping{{ContainerPort: 80, Protocol: "sctp"}}}, wantErr: true},
+		{name: "negative ttl", template: model.SessionTemplate{Name: "web", ImageName: "nginx:latest", TTLSeconds: -1}, wantErr: true},
+		{name: "duplicate env", template: model.SessionTemplate{Name: "web", ImageName: "nginx:latest", Env: []model.EnvVar{{Name: "A"}, {Name: "A"}}}, wantErr: true},
+		{name: "invalid readiness", template: model.SessionTemplate{Name: "web", ImageName: "nginx:latest", Readiness: &model.ReadinessConfig{}}, wantErr: true},
+		{name: "invalid restart policy", template: model.SessionTemplate{Name: "web", ImageName: "nginx:latest", RestartPolicy: &model.RestartPolicy{Name: "sometimes"}}, wantErr: true},
+	}
+	_, ss := newTestService(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ss.validateTemplate(&tt.template)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateTemplate = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("validateTemplate = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestMergeCreateRequest(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		base     model.CreateSessionRequest
+		override model.CreateSessionRequest
+		want     model.CreateSessionRequest
+	}{
+		{
+			name:     "empty override",
+			base:     model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 2, TTLSeconds: 60},
+			override: model.CreateSessionRequest{},
+			want:     model.CreateSessionRequest{ImageName: "nginx:latest", NumPorts: 2, TTLSeconds: 60},
+		},
+		{
+			name:     "snapshot replaces image",
+			base:     model.CreateSessionRequest{ImageName: "nginx:latest"},
+			override: model.CreateSessionRequest{SnapshotID: "snap-1"},
+			want:     model.CreateSessionRequest{SnapshotID: "snap-1"},
+		},
+		{
+			name:     "image replaces snapshot",
+			base:     model.CreateSessionRequest{SnapshotID: "snap-1"},
+			override: model.CreateSessionRequest{ImageName: "nginx:1.27"},
+			want:     model.CreateSessionRequest{ImageName: "nginx:1.27"},
+		},
+		{
+			name:     "port mappings replace port count",
+			base:     model.CreateSessionRequest{NumPorts: 2},
+			override: model.CreateSessionRequest{PortMappings: []model.PortMapping{{ContainerPort: 8080}}},
+			want:     model.CreateSessionRequest{PortMappings: []model.PortMapping{{ContainerPort: 8080}}},
+		},
+		{
+			name:     "port count replaces port mappings",
+			base:     model.CreateSessionRequest{PortMappings: []model.PortMapping{{ContainerPort: 8080}}},
+			override: model.CreateSessionRequest{NumPorts: 3},
+			want:     model.CreateSessionRequest{NumPorts: 3},
+		},
+		{
+			name:     "expires_at replaces ttl",
+			base:     model.CreateSessionRequest{TTLSeconds: 60},
+			override: model.CreateSessionRequest{ExpiresAt: &expiresAt},
+			want:     model.CreateSessionRequest{ExpiresAt: &expiresAt},
+		},
+		{
+			name:     "ttl replaces expires_at",
+			base:     model.CreateSessionRequest{ExpiresAt: &expiresAt},
+			override: model.CreateSessionRequest{TTLSeconds: 60},
+			want:     model.CreateSessionRequest{TTLSeconds: 60},
+		},
+		{
+			name:     "resources merged by field",
+			base:     model.CreateSessionRequest{Resources: &model.ResourceLimits{CPUs: 1, MemoryBytes: 512 << 20}},
+			override: model.CreateSessionRequest{Resources: &model.ResourceLimits{MemoryBytes: 1 << 30, PidsLimit: 100}},
+			want:     model.CreateSessionRequest{Resources: &model.ResourceLimits{CPUs: 1, MemoryBytes: 1 << 30, PidsLimit: 100}},
+		},
+		{
+			name:     "resources without base",
+			override: model.CreateSessionRequest{Resources: &model.ResourceLimits{CPUs: 2}},
+			want:     model.CreateSessionRequest{Resources: &model.ResourceLimits{CPUs: 2}},
+		},
+		{
+			name:     "env merged by name",
+			base:     model.CreateSessionRequest{Env: []model.EnvVar{{Name: "MODE", Value: "demo"}, {Name: "PORT", Value: "3000"}}},
+			override: model.CreateSessionRequest{Env: []model.EnvVar{{Name: "MODE", Value: "dev"}, {Name: "DEBUG", Value: "1"}}},
+			want:     model.CreateSessionRequest{Env: []model.EnvVar{{Name: "MODE", Value: "dev"}, {Name: "PORT", Value: "3000"}, {Name: "DEBUG", Value: "1"}}},
+		},
+		{
+			name:     "command and user replaced",
+			base:     model.CreateSessionRequest{Command: []string{"npm", "start"}, User: "node", WorkingDir: "/app"},
+			override: model.CreateSessionRequest{Command: []string{"npm", "run", "dev"}, User: "root"},
+			want:     model.CreateSessionRequest{Command: []string{"npm", "run", "dev"}, User: "root", WorkingDir: "/app"},
+		},
+		{
+			name:     "internal network only turned on",
+			base:     model.CreateSessionRequest{InternalNetwork: true},
+			override: model.CreateSessionRequest{},
+			want:     model.CreateSessionRequest{InternalNetwork: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mergeCreateRequest(&tt.base, &tt.override)
+			if !reflect.DeepEqual(tt.base, tt.want) {
+				t.Fatalf("mergeCreateRequest = %+v, want %+v", tt.base, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTemplate(t *testing.T) {
+	_, ss := newTestService(t, nil)
+	if _, err := ss.CreateTemplate(&model.SessionTemplate{
+		Name:         "todo-app",
+		ImageName:    "todo:1",
+		PortMappings: []model.PortMapping{{ContainerPort: 3000, Description: "app"}},
+		Env:          []model.EnvVar{{Name: "MODE", Value: "demo"}, {Name: "SEED", Value: "1"}},
+		TTLSeconds:   600,
+	}); err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	if _, err := ss.UpdateTemplate("todo-app", &model.SessionTemplate{ImageName: "todo:2", TTLSeconds: 600}); err != nil {
+		t.Fatalf("UpdateTemplate: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		req         model.CreateSessionRequest
+		wantErr     error
+		wantImage   string
+		wantVersion int
+		wantEnv     []model.EnvVar
+		wantTTL     int
+	}{
+		{name: "latest version", req: model.CreateSessionRequest{Template: "todo-app"}, wantImage: "todo:2", wantVersion: 2, wantTTL: 600},
+		{
+			name:        "pinned version",
+			req:         model.CreateSessionRequest{Template: "todo-app", TemplateVersion: 1},
+			wantImage:   "todo:1",
+			wantVersion: 1,
+			wantEnv:     []model.EnvVar{{Name: "MODE", Value: "demo"}, {Name: "SEED", Value: "1"}},
+			wantTTL:     600,
+		},
+		{
+			name: "own fields then overrides",
+			req: model.CreateSessionRequest{
+				Template:        "todo-app",
+				TemplateVersion: 1,
+				TTLSeconds:      60,
+				Env:             []model.EnvVar{{Name: "MODE", Value: "own"}},
+				Overrides:       &model.CreateSessionRequest{Env: []model.EnvVar{{Name: "MODE", Value: "override"}}, TTLSeconds: 120},
+			},
+			wantImage:   "todo:1",
+			wantVersion: 1,
+			wantEnv:     []model.EnvVar{{Name: "MODE", Value: "override"}, {Name: "SEED", Value: "1"}},
+			wantTTL:     120,
+		},
+		{name: "unknown template", req: model.CreateSessionRequest{Template: "missing"}, wantErr: util.ErrInvalidRequest},
+		{name: "unknown version", req: model.CreateSessionRequest{Template: "todo-app", TemplateVersion: 9}, wantErr: util.ErrInvalidRequest},
+		{name: "overrides without template", req: model.CreateSessionRequest{ImageName: "nginx:latest", Overrides: &model.CreateSessionRequest{}}, wantErr: util.ErrInvalidRequest},
+		{name: "version without template", req: model.CreateSessionRequest{ImageName: "nginx:latest", TemplateVersion: 1}, wantErr: util.ErrInvalidRequest},
+		{
+			name:    "overrides naming a template",
+			req:     model.CreateSessionRequest{Template: "todo-app", Overrides: &model.CreateSessionRequest{Template: "other"}},
+			wantErr: util.ErrInvalidRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := ss.resolveTemplate(&tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveTemplate = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if resolved.ImageName != tt.wantImage || resolved.Template != "todo-app" || resolved.TemplateVersion != tt.wantVersion {
+				t.Errorf("resolved %s from %s version %d, want %s from version %d", resolved.ImageName, resolved.Template, resolved.TemplateVersion, tt.wantImage, tt.wantVersion)
+			}
+			if resolved.Overrides != nil {
+				t.Errorf("resolved request keeps its overrides")
+			}
+			if !reflect.DeepEqual(resolved.Env, tt.wantEnv) || resolved.TTLSeconds != tt.wantTTL {
+				t.Errorf("resolved env %+v, ttl %d; want %+v, %d", resolved.Env, resolved.TTLSeconds, tt.wantEnv, tt.wantTTL)
+			}
+		})
+	}
+
+	// Merging into a resolved request leaves the stored template alone
+	template, err := ss.GetTemplate("todo-app", 1)
+	if err != nil {
+		t.Fatalf("GetTemplate: %v", err)
+	}
+	if want := []model.EnvVar{{Name: "MODE", Value: "demo"}, {Name: "SEED", Value: "1"}}; !reflect.DeepEqual(template.Env, want) {
+		t.Fatalf("template env = %+v, want %+v", template.Env, want)
+	}
+
+	// No template leaves the request as it is
+	req := &model.CreateSessionRequest{ImageName: "nginx:latest"}
+	if resolved, err := ss.resolveTemplate(req); err != nil || resolved != req {
+		t.Fatalf("resolveTemplate without template = %+v, %v", resolved, err)
+	}
+}
+
+func TestTemplateVersions(t *testing.T) {
+	fake, ss := newTestService(t, nil)
+	fake.AddImage("postgres:16")
+
+	created, err := ss.CreateTemplate(&model.SessionTemplate{
+		Name:      "db",
+		ImageName: "postgres:16",
+		Env:       []model.EnvVar{{Name: "POSTGRES_PASSWORD", Value: "s3cret", Secret: true}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	if created.Version != 1 || created.Env[0].Value != model.RedactedValue {
+		t.Fatalf("created version %d with env %+v, want version 1 with the secret redacted", created.Version, created.Env)
+	}
+	if _, err := ss.CreateTemplate(&model.SessionTemplate{Name: "db", ImageName: "postgres:15"}); !errors.Is(err, util.ErrOperationNotValid) {
+		t.Fatalf("CreateTemplate of an existing name = %v, want ErrOperationNotValid", err)
+	}
+
+	// A secret sent back redacted keeps its value
+	updated, err := ss.UpdateTemplate("db", &model.SessionTemplate{
+		ImageName: "postgres:16",
+		Env:       []model.EnvVar{{Name: "POSTGRES_PASSWORD", Value: model.RedactedValue, Secret: true}},
+		User:      "postgres",
+	})
+	if err != nil {
+		t.Fatalf("UpdateTemplate: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("updated version = %d, want 2", updated.Version)
+	}
+	if _, err := ss.UpdateTemplate("db", &model.SessionTemplate{Name: "other", ImageName: "postgres:16"}); !errors.Is(err, util.ErrInvalidRequest) {
+		t.Fatalf("UpdateTemplate with another name = %v, want ErrInvalidRequest", err)
+	}
+	if _, err := ss.UpdateTemplate("missing", &model.SessionTemplate{ImageName: "postgres:16"}); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("UpdateTemplate of unknown template = %v, want ErrNotFound", err)
+	}
+
+	versions, err := ss.ListTemplateVersions("db")
+	if err != nil || len(versions) != 2 || versions[0].Version != 1 || versions[1].User != "postgres" {
+		t.Fatalf("ListTemplateVersions = %+v, %v", versions, err)
+	}
+
+	// Sessions record the version that created them and get the secret value
+	session := createTestSession(t, ss, &model.CreateSessionRequest{Template: "db"})
+	if session.Template != "db" || session.TemplateVersion != 2 {
+		t.Fatalf("session records template %q version %d, want db version 2", session.Template, session.TemplateVersion)
+	}
+	if env := envMap(fake.Containers()[0].Env); env["POSTGRES_PASSWORD"] != "s3cret" {
+		t.Fatalf("container env = %v, want the template secret", fake.Containers()[0].Env)
+	}
+
+	if err := ss.DeleteTemplate("db"); err != nil {
+		t.Fatalf("DeleteTemplate: %v", err)
+	}
+	if _, err := ss.GetTemplate("db", 0); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("GetTemplate after delete = %v, want ErrNotFound", err)
+	}
+	if err := ss.DeleteTemplate("db"); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("DeleteTemplate again = %v, want ErrNotFound", err)
+	}
+	if got, err := ss.GetSession(session.ID); err != nil || got.TemplateVersion != 2 {
+		t.Fatalf("session after template delete = %+v, %v", got, err)
+	}
+}
+
+func TestParseTemplateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    model.SessionTemplate
+		wantErr bool
+	}{
+		{
+			name: "full",
+			content: `name: todo-app
+image_name: todo:latest
+port_mappings:
+  - container_port: 3000
+    description: app
+env:
+  - name: MODE
+    value: demo
+ttl_seconds: 3600
+`,
+			want: model.SessionTemplate{
+				Name:         "todo-app",
+				ImageName:    "todo:latest",
+				PortMappings: []model.PortMapping{{ContainerPort: 3000, Description: "app"}},
+				Env:          []model.EnvVar{{Name: "MODE", Value: "demo"}},
+				TTLSeconds:   3600,
+			},
+		},
+		{name: "unknown field", content: "image_name: todo:latest\nimage: todo:latest\n", wantErr: true},
+		{name: "invalid yaml", content: "image_name: [todo\n", wantErr: true},
+		{name: "wrong type", content: "image_name: todo:latest\nttl_seconds: soon\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "template.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("write template: %v", err)
+			}
+
+			template, err := parseTemplateFile(path)
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrInvalidRequest) {
+					t.Fatalf("parseTemplateFile = %+v, %v; want ErrInvalidRequest", template, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTemplateFile: %v", err)
+			}
+			if !reflect.DeepEqual(*template, tt.want) {
+				t.Fatalf("parseTemplateFile = %+v, want %+v", *template, tt.want)
+			}
+		})
+	}
+}
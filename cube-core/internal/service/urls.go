@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// portURLPlaceholder matches the placeholders of a port URL template
+var portURLPlaceholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// portURLPlaceholders are the placeholders a port URL template may use
+var portURLPlaceholders = map[string]bool{
+	"{scheme}":         true,
+	"{host}":           true,
+	"{host_port}":      true,
+	"{container_port}": true,
+	"{session_id}":     true,
+	"{port}":           true,
+	"{service}":        true,
+}
+
+// ValidatePortURLTemplate checks that a port URL template only uses known placeholders
+func ValidatePortURLTemplate(template string) error {
+	for _, placeholder := range portURLPlaceholder.FindAllString(template, -1) {
+		if !portURLPlaceholders[placeholder] {
+			return fmt.Errorf("unknown placeholder %s in port URL template", placeholder)
+		}
+	}
+	return nil
+}
+
+// validatePortSchemes checks the scheme overrides of port mappings
+func validatePortSchemes(mappings []model.PortMapping) error {
+	for _, mapping := range mappings {
+		switch mapping.Scheme {
+		case "":
+		case "http", "https":
+			if mapping.Protocol != "" && mapping.Protocol != "tcp" {
+				return util.WrapError(util.ErrInvalidRequest, "container port %d is not tcp and cannot have a URL scheme", mapping.ContainerPort)
+			}
+		default:
+			return util.WrapError(util.ErrInvalidRequest, "invalid scheme %q for container port %d: use http or https", mapping.Scheme, mapping.ContainerPort)
+		}
+	}
+	return nil
+}
+
+// setPortURLs fills in the URLs of a session's ports: from the port URL template if there is
+// one, else the reverse proxy's routes if it is enabled, else the host ports. Non-TCP ports
+// get no URL. The URLs are only handed to clients; the server reaches ports through portTarget.
+func (ss *SessionService) setPortURLs(sessionID string, ports []model.Port) {
+	host := ss.publicHost()
+	slugs := portSlugs(ports)
+	for i, p := range ports {
+		switch {
+		case p.Protocol != "tcp":
+			ports[i].URL = ""
+		case ss.cfg.PortURLTemplate != "":
+			ports[i].URL = strings.NewReplacer(
+				"{scheme}", portScheme(p),
+				"{host}", host,
+				"{host_port}", strconv.Itoa(p.HostPort),
+				"{container_port}", strconv.Itoa(p.ContainerPort),
+				"{session_id}", sessionID,
+				"{port}", slugs[i],
+				"{service}", p.Service,
+			).Replace(ss.cfg.PortURLTemplate)
+		case ss.cfg.ProxyPort > 0:
+			ports[i].URL = ss.proxyPortURL(sessionID, slugs[i])
+		default:
+			ports[i].URL = fmt.Sprintf("%s://%s", portScheme(p), net.JoinHostPort(host, strconv.Itoa(p.HostPort)))
+		}
+	}
+}
+
+// publicHost returns the host name used in port URLs
+func (ss *SessionService) publicHost() string {
+	if ss.cfg.PublicHost != "" {
+		return ss.cfg.PublicHost
+	}
+
+	hostname, _ := getLocalIP()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	return hostname
+}
+
+// portScheme returns the scheme a port is served with, guessed from well-known HTTPS ports
+// unless the port names one
+func portScheme(p model.Port) string {
+	if p.Scheme != "" {
+		return p.Scheme
+	}
+	if p.ContainerPort == 443 || p.ContainerPort == 8443 {
+		return "https"
+	}
+	return "http"
+}
+
+// Helper function to get the local IP address
+func getLocalIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				return ipnet.IP.String(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no suitable IP address found")
+}
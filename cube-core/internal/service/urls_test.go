@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/yourusername/session-manager/internal/config"
+	"github.com/yourusername/session-manager/internal/model"
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+func TestValidatePortURLTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{template: ""},
+		{template: "{scheme}://{host}:{host_port}"},
+		{template: "https://{session_id}-{port}.apps.example.com/"},
+		{template: "https://proxy.example.com/{session_id}/{service}/{container_port}"},
+		{template: "https://example.com/{hostname}", wantErr: true},
+		{template: "https://{host}/{session}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if err := ValidatePortURLTemplate(tt.template); tt.wantErr != (err != nil) {
+				t.Fatalf("ValidatePortURLTemplate = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePortSchemes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings []model.PortMapping
+		wantErr  bool
+	}{
+		{name: "no scheme", mappings: []model.PortMapping{{ContainerPort: 80}, {ContainerPort: 53, Protocol: "udp"}}},
+		{name: "http", mappings: []model.PortMapping{{ContainerPort: 443, Scheme: "http"}}},
+		{name: "https", mappings: []model.PortMapping{{ContainerPort: 8080, Protocol: "tcp", Scheme: "https"}}},
+		{name: "unknown scheme", mappings: []model.PortMapping{{ContainerPort: 80, Scheme: "ftp"}}, wantErr: true},
+		{name: "upper case scheme", mappings: []model.PortMapping{{ContainerPort: 80, Scheme: "HTTP"}}, wantErr: true},
+		{name: "udp port", mappings: []model.PortMapping{{ContainerPort: 53, Protocol: "udp", Scheme: "http"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePortSchemes(tt.mappings)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validatePortSchemes = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, util.ErrInvalidRequest) {
+				t.Fatalf("validatePortSchemes = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestPortScheme(t *testing.T) {
+	tests := []struct {
+		name string
+		port model.Port
+		want string
+	}{
+		{name: "http port", port: model.Port{ContainerPort: 80}, want: "http"},
+		{name: "https port", port: model.Port{ContainerPort: 443}, want: "https"},
+		{name: "alternative https port", port: model.Port{ContainerPort: 8443}, want: "https"},
+		{name: "https override", port: model.Port{ContainerPort: 8080, Scheme: "https"}, want: "https"},
+		{name: "http override", port: model.Port{ContainerPort: 443, Scheme: "http"}, want: "http"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := portScheme(tt.port); got != tt.want {
+				t.Fatalf("portScheme = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPortURLs(t *testing.T) {
+	const sessionID = "0b7ff3b4-5f6a-4a53-9d3c-4d6b6c0e1a2f"
+	ports := func() []model.Port {
+		return []model.Port{
+			{HostPort: 32768, ContainerPort: 80, Protocol: "tcp", Description: "Web UI"},
+			{HostPort: 32769, ContainerPort: 443, Protocol: "tcp", Service: "api"},
+			{HostPort: 32770, ContainerPort: 53, Protocol: "udp", Description: "DNS", URL: "stale"},
+		}
+	}
+	tests := []struct {
+		name      string
+		configure func(*config.Config)
+		want      []string
+	}{
+		{
+			name: "host ports",
+			want: []string{"http://cube.example.com:32768", "https://cube.example.com:32769", ""},
+		},
+		{
+			name:      "proxy routes",
+			configure: func(cfg *config.Config) { cfg.ProxyPort = 8081 },
+			want: []string{
+				"http://cube.example.com:8081/s/" + sessionID + "/web-ui/",
+				"http://cube.example.com:8081/s/" + sessionID + "/api/",
+				"",
+			},
+		},
+		{
+			name: "proxy host routes",
+			configure: func(cfg *config.Config) {
+				cfg.ProxyPort = 8081
+				cfg.ProxyBaseDomain = "apps.example.com"
+				cfg.ProxyPublicURL = "https://apps.example.com"
+			},
+			want: []string{
+				"https://" + sessionID + "-web-ui.apps.example.com/",
+				"https://" + sessionID + "-api.apps.example.com/",
+				"",
+			},
+		},
+		{
+			name: "template wins over the proxy",
+			configure: func(cfg *config.Config) {
+				cfg.ProxyPort = 8081
+				cfg.PortURLTemplate = "{scheme}://{host}/{session_id}/{port}/{service}/{container_port}/{host_port}"
+			},
+			want: []string{
+				"http://cube.example.com/" + sessionID + "/web-ui//80/32768",
+				"https://cube.example.com/" + sessionID + "/api/api/443/32769",
+				"",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ss := newTestService(t, func(cfg *config.Config) {
+				cfg.PublicHost = "cube.example.com"
+				if tt.configure != nil {
+					tt.configure(cfg)
+				}
+			})
+
+			got := ports()
+			ss.setPortURLs(sessionID, got)
+			for i, p := range got {
+				if p.URL != tt.want[i] {
+					t.Errorf("URL of port %d = %q, want %q", p.ContainerPort, p.URL, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSessionPortURLs(t *testing.T) {
+	fake, ss := newTestService(t, func(cfg *config.Config) {
+		cfg.PublicHost = "cube.example.com"
+	})
+	fake.AddImage("nginx:latest")
+
+	session := createTestSession(t, ss, &model.CreateSessionRequest{
+		ImageName:    "nginx:latest",
+		PortMappings: []model.PortMapping{{ContainerPort: 8080, Scheme: "https"}, {ContainerPort: 53, Protocol: "udp"}},
+	})
+	if len(session.Ports) != 2 {
+		t.Fatalf("ports = %+v, want 2", session.Ports)
+	}
+	for _, p := range session.Ports {
+		want := ""
+		if p.Protocol == "tcp" {
+			want = "https://cube.example.com:" + strconv.Itoa(p.HostPort)
+		}
+		if p.URL != want {
+			t.Errorf("URL of port %d = %q, want %q", p.ContainerPort, p.URL, want)
+		}
+	}
+
+	_, err := ss.CreateSession(&model.CreateSessionRequest{
+		ImageName:    "nginx:latest",
+		PortMappings: []model.PortMapping{{ContainerPort: 80, Scheme: "ws"}},
+	})
+	if !errors.Is(err, util.ErrInvalidRequest) {
+		t.Fatalf("CreateSession with an invalid scheme = %v, want ErrInvalidRequest", err)
+	}
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+// SessionStore persists sessions so they survive server restarts
+type SessionStore interface {
+	// Load returns all persisted sessions
+	Load() ([]*model.Session, error)
+	// Save inserts or replaces a session
+	Save(session *model.Session) error
+	// Delete removes a session by ID; deleting an unknown session is not an error
+	Delete(sessionID string) error
+}
+
+// MemoryStore is a SessionStore that keeps sessions in memory only
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]model.Session
+}
+
+// NewMemoryStore creates a new in-memory session store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]model.Session),
+	}
+}
+
+// Load returns all sessions held in memory
+func (ms *MemoryStore) Load() ([]*model.Session, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	sessions := make([]*model.Session, 0, len(ms.sessions))
+	for _, session := range ms.sessions {
+		s := session
+		sessions = append(sessions, &s)
+	}
+	return sessions, nil
+}
+
+// Save stores a copy of the session in memory
+func (ms *MemoryStore) Save(session *model.Session) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.sessions[session.ID] = *session
+	return nil
+}
+
+// Delete removes a session from memory
+func (ms *MemoryStore) Delete(sessionID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.sessions, sessionID)
+	return nil
+}
+
+// FileStore is a SessionStore backed by a single JSON file.
+// Every write rewrites the file atomically via a temporary file and rename.
+type FileStore struct {
+	mu       sync.Mutex
+	path     string
+	sessions map[string]model.Session
+}
+
+// NewFileStore creates a file-backed session store, loading any existing state from path
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("session store path is required")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create session store directory: %v", err)
+	}
+
+	fs := &FileStore{
+		path:     path,
+		sessions: make(map[string]model.Session),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fs, nil
+		}
+		return nil, fmt.Errorf("failed to read session store: %v", err)
+	}
+
+	if len(data) == 0 {
+		return fs, nil
+	}
+
+	var sessions []model.Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode session store: %v", err)
+	}
+
+	for _, session := range sessions {
+		fs.sessions[session.ID] = session
+	}
+
+	return fs, nil
+}
+
+// Load returns all sessions persisted in the file
+func (fs *FileStore) Load() ([]*model.Session, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	sessions := make([]*model.Session, 0, len(fs.sessions))
+	for _, session := range fs.sessions {
+		s := session
+		sessions = append(sessions, &s)
+	}
+	return sessions, nil
+}
+
+// Save inserts or replaces a session and flushes the file
+func (fs *FileStore) Save(session *model.Session) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	previous, existed := fs.sessions[session.ID]
+	fs.sessions[session.ID] = *session
+
+	if err := fs.flush(); err != nil {
+		// Keep the in-memory view consistent with what is on disk
+		if existed {
+			fs.sessions[session.ID] = previous
+		} else {
+			delete(fs.sessions, session.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a session and flushes the file
+func (fs *FileStore) Delete(sessionID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	previous, existed := fs.sessions[sessionID]
+	if !existed {
+		return nil
+	}
+	delete(fs.sessions, sessionID)
+
+	if err := fs.flush(); err != nil {
+		fs.sessions[sessionID] = previous
+		return err
+	}
+	return nil
+}
+
+// flush writes all sessions to disk. Callers must hold fs.mu.
+func (fs *FileStore) flush() error {
+	sessions := make([]model.Session, 0, len(fs.sessions))
+	for _, session := range fs.sessions {
+		sessions = append(sessions, session)
+	}
+
+	// Keep the file stable between writes to make it easy to inspect and diff
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sessions: %v", err)
+	}
+
+	return writeFileAtomic(fs.path, data, "session store")
+}
+
+// writeFileAtomic replaces the file at path with data via a temporary file and rename, so
+// readers never see a partial write. what names the file in errors.
+func writeFileAtomic(path string, data []byte, what string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary %s file: %v", what, err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %v", what, err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync %s: %v", what, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close %s: %v", what, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %v", what, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/yourusername/session-manager/internal/model"
+)
+
+// TemplateStore persists every version of every session template
+type TemplateStore interface {
+	// Load returns all persisted template versions
+	Load() ([]model.SessionTemplate, error)
+	// Save inserts or replaces a template version
+	Save(template *model.SessionTemplate) error
+	// Delete removes all versions of a template; deleting an unknown template is not an error
+	Delete(name string) error
+}
+
+// templateKey identifies a single template version
+type templateKey struct {
+	name    string
+	version int
+}
+
+// MemoryTemplateStore is a TemplateStore that keeps templates in memory only
+type MemoryTemplateStore struct {
+	mu        sync.Mutex
+	templates map[templateKey]model.SessionTemplate
+}
+
+// NewMemoryTemplateStore creates a new in-memory template store
+func NewMemoryTemplateStore() *MemoryTemplateStore {
+	return &MemoryTemplateStore{
+		templates: make(map[templateKey]model.SessionTemplate),
+	}
+}
+
+// Load returns all template versions held in memory
+func (ms *MemoryTemplateStore) Load() ([]model.SessionTemplate, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return sortedTemplates(ms.templates), nil
+}
+
+// Save stores a copy of the template version in memory
+func (ms *MemoryTemplateStore) Save(template *model.SessionTemplate) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.templates[templateKey{template.Name, template.Version}] = *template
+	return nil
+}
+
+// Delete removes all versions of a template from memory
+func (ms *MemoryTemplateStore) Delete(name string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for key := range ms.templates {
+		if key.name == name {
+			delete(ms.templates, key)
+		}
+	}
+	return nil
+}
+
+// FileTemplateStore is a TemplateStore backed by a single JSON file.
+// Every write rewrites the file atomically via a temporary file and rename.
+type FileTemplateStore struct {
+	mu        sync.Mutex
+	path      string
+	templates map[templateKey]model.SessionTemplate
+}
+
+// NewFileTemplateStore creates a file-backed template store, loading any existing state from path
+func NewFileTemplateStore(path string) (*FileTemplateStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("template store path is required")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create template store directory: %v", err)
+	}
+
+	fs := &FileTemplateStore{
+		path:      path,
+		templates: make(map[templateKey]model.SessionTemplate),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fs, nil
+		}
+		return nil, fmt.Errorf("failed to read template store: %v", err)
+	}
+
+	if len(data) == 0 {
+		return fs, nil
+	}
+
+	var templates []model.SessionTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to decode template store: %v", err)
+	}
+
+	for _, template := range templates {
+		fs.templates[templateKey{template.Name, template.Version}] = template
+	}
+
+	return fs, nil
+}
+
+// Load returns all template versions persisted in the file
+func (fs *FileTemplateStore) Load() ([]model.SessionTemplate, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return sortedTemplates(fs.templates), nil
+}
+
+// Save inserts or replaces a template version and flushes the file
+func (fs *FileTemplateStore) Save(template *model.SessionTemplate) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	key := templateKey{template.Name, template.Version}
+	previous, existed := fs.templates[key]
+	fs.templates[key] = *template
+
+	if err := fs.flush(); err != nil {
+		// Keep the in-memory view consistent with what is on disk
+		if existed {
+			fs.templates[key] = previous
+		} else {
+			delete(fs.templates, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes all versions of a template and flushes the file
+func (fs *FileTemplateStore) Delete(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	removed := make(map[templateKey]model.SessionTemplate)
+	for key, template := range fs.templates {
+		if key.name == name {
+			removed[key] = template
+			delete(fs.templates, key)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if err := fs.flush(); err != nil {
+		for key, template := range removed {
+			fs.templates[key] = template
+		}
+		return err
+	}
+	return nil
+}
+
+// flush writes all template versions to disk. Callers must hold fs.mu.
+func (fs *FileTemplateStore) flush() error {
+	data, err := json.MarshalIndent(sortedTemplates(fs.templates), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode templates: %v", err)
+	}
+
+	return writeFileAtomic(fs.path, data, "template store")
+}
+
+// sortedTemplates returns template versions ordered by name and version
+func sortedTemplates(templates map[templateKey]model.SessionTemplate) []model.SessionTemplate {
+	result := make([]model.SessionTemplate, 0, len(templates))
+	for _, template := range templates {
+		result = append(result, template)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Version < result[j].Version
+	})
+	return result
+}
@@ -0,0 +1,342 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// fakeContainer is a container that only keeps its configuration and state
+type fakeContainer struct {
+	id       string
+	name     string
+	config   container.Config
+	host     container.HostConfig
+	networks map[string]*network.EndpointSettings
+	// status is "created", "running", "paused" or "exited"
+	status    string
+	health    string
+	exitCode  int
+	created   time.Time
+	startedAt time.Time
+	// stdout and stderr are what the container printed
+	stdout string
+	stderr string
+	// files holds the content of the files in the container by path, and dirs the
+	// directories created in it; the parents of files exist implicitly
+	files map[string]string
+	dirs  map[string]bool
+}
+
+// ContainerInfo describes a container of the fake
+type ContainerInfo struct {
+	ID       string
+	Image    string
+	Status   string
+	Labels   map[string]string
+	Env      []string
+	Networks []string
+	// Cmd, Entrypoint, WorkingDir and User are the overrides the container was created with
+	Cmd        []string
+	Entrypoint []string
+	WorkingDir string
+	User       string
+	// Mounts, Resources and RestartPolicy are the host settings the container was created with
+	Mounts        []mount.Mount
+	Resources     container.Resources
+	RestartPolicy container.RestartPolicy
+}
+
+// Containers returns the containers of the fake, in creation order
+func (f *FakeAPI) Containers() []ContainerInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make([]ContainerInfo, 0, len(f.containers))
+	for _, c := range f.containers {
+		info := ContainerInfo{
+			ID:            c.id,
+			Image:         c.config.Image,
+			Status:        c.status,
+			Labels:        c.config.Labels,
+			Env:           c.config.Env,
+			Cmd:           c.config.Cmd,
+			Entrypoint:    c.config.Entrypoint,
+			WorkingDir:    c.config.WorkingDir,
+			User:          c.config.User,
+			Mounts:        c.host.Mounts,
+			Resources:     c.host.Resources,
+			RestartPolicy: c.host.RestartPolicy,
+		}
+		for name := range c.networks {
+			info.Networks = append(info.Networks, name)
+		}
+		sort.Strings(info.Networks)
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// SetContainerStatus changes the status of a container behind the caller's back, as if it
+// exited ("exited") or was started by someone else ("running")
+func (f *FakeAPI) SetContainerStatus(id, status string, exitCode int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if c := f.findContainer(id); c != nil {
+		c.status = status
+		c.exitCode = exitCode
+	}
+}
+
+// SetContainerHealth sets the HEALTHCHECK status of a container: "starting", "healthy" or
+// "unhealthy"
+func (f *FakeAPI) SetContainerHealth(id, health string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if c := f.findContainer(id); c != nil {
+		c.health = health
+	}
+}
+
+// SetContainerLogs sets what a container printed to stdout and stderr
+func (f *FakeAPI) SetContainerLogs(id, stdout, stderr string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if c := f.findContainer(id); c != nil {
+		c.stdout = stdout
+		c.stderr = stderr
+	}
+}
+
+// findContainer looks up a container by ID, ID prefix or name. Callers must hold f.mu.
+func (f *FakeAPI) findContainer(ref string) *fakeContainer {
+	if ref == "" {
+		return nil
+	}
+	if c, exists := f.containers[ref]; exists {
+		return c
+	}
+	for _, c := range f.containers {
+		if strings.HasPrefix(c.id, ref) || c.name == strings.TrimPrefix(ref, "/") {
+			return c
+		}
+	}
+	return nil
+}
+
+// serveContainers implements the container endpoints
+func (f *FakeAPI) serveContainers(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "json":
+		f.listContainers(w, r)
+		return
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "create":
+		f.createContainer(w, r)
+		return
+	case len(parts) == 0:
+		writeError(w, http.StatusNotFound, "page not found")
+		return
+	}
+
+	c := f.findContainer(parts[0])
+	if c == nil {
+		writeError(w, http.StatusNotFound, "No such container: "+parts[0])
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	switch {
+	case r.Method == http.MethodGet && action == "json":
+		f.inspectContainer(w, c)
+	case r.Method == http.MethodGet && action == "logs":
+		f.containerLogs(w, r, c)
+	case action == "archive":
+		f.serveArchive(w, r, c)
+	case r.Method == http.MethodDelete && action == "":
+		delete(f.containers, c.id)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && (action == "start" || action == "restart"):
+		if c.status == "paused" {
+			writeError(w, http.StatusConflict, "cannot start a paused container, try unpause instead")
+			return
+		}
+		c.status = "running"
+		c.startedAt = time.Now()
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && action == "stop":
+		c.status = "exited"
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && action == "pause":
+		if c.status != "running" {
+			writeError(w, http.StatusConflict, "container "+c.id+" is not running")
+			return
+		}
+		c.status = "paused"
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && action == "unpause":
+		if c.status != "paused" {
+			writeError(w, http.StatusConflict, "container "+c.id+" is not paused")
+			return
+		}
+		c.status = "running"
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+// createContainer creates a container from a local image, attached to its network
+func (f *FakeAPI) createContainer(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		container.Config
+		HostConfig       *container.HostConfig
+		NetworkingConfig *network.NetworkingConfig
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if f.findImage(body.Image) == nil {
+		writeError(w, http.StatusNotFound, "No such image: "+body.Image)
+		return
+	}
+
+	c := &fakeContainer{
+		id:       f.newID(),
+		name:     r.URL.Query().Get("name"),
+		config:   body.Config,
+		networks: make(map[string]*network.EndpointSettings),
+		status:   "created",
+		created:  time.Now(),
+		files:    make(map[string]string),
+		dirs:     make(map[string]bool),
+	}
+	if body.HostConfig != nil {
+		c.host = *body.HostConfig
+	}
+
+	networkName := "bridge"
+	if mode := c.host.NetworkMode; mode != "" && mode.IsUserDefined() {
+		networkName = string(mode)
+		if f.findNetwork(networkName) == nil {
+			writeError(w, http.StatusNotFound, "network "+networkName+" not found")
+			return
+		}
+	}
+	endpoint := &network.EndpointSettings{}
+	if body.NetworkingConfig != nil && body.NetworkingConfig.EndpointsConfig[networkName] != nil {
+		endpoint = body.NetworkingConfig.EndpointsConfig[networkName]
+	}
+	c.networks[networkName] = endpoint
+
+	f.containers[c.id] = c
+	writeJSON(w, http.StatusCreated, container.CreateResponse{ID: c.id, Warnings: []string{}})
+}
+
+// inspectContainer answers a container inspect
+func (f *FakeAPI) inspectContainer(w http.ResponseWriter, c *fakeContainer) {
+	state := &types.ContainerState{
+		Status:     c.status,
+		Running:    c.status == "running" || c.status == "paused",
+		Paused:     c.status == "paused",
+		ExitCode:   c.exitCode,
+		StartedAt:  formatTime(c.startedAt),
+		FinishedAt: formatTime(time.Time{}),
+	}
+	if c.health != "" {
+		state.Health = &types.Health{Status: c.health}
+	}
+
+	config := c.config
+	host := c.host
+	writeJSON(w, http.StatusOK, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         c.id,
+			Created:    formatTime(c.created),
+			State:      state,
+			Image:      c.config.Image,
+			Name:       "/" + c.name,
+			HostConfig: &host,
+		},
+		Config:          &config,
+		NetworkSettings: &types.NetworkSettings{Networks: c.networks},
+	})
+}
+
+// containerLogs writes the selected output of a container in Docker's multiplexed format
+func (f *FakeAPI) containerLogs(w http.ResponseWriter, r *http.Request, c *fakeContainer) {
+	query := r.URL.Query()
+	w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+	w.WriteHeader(http.StatusOK)
+	if query.Get("stdout") == "1" && c.stdout != "" {
+		stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte(c.stdout))
+	}
+	if query.Get("stderr") == "1" && c.stderr != "" {
+		stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte(c.stderr))
+	}
+}
+
+// listContainers lists the containers matching the id and label filters
+func (f *FakeAPI) listContainers(w http.ResponseWriter, r *http.Request) {
+	args, ok := listFilters(w, r)
+	if !ok {
+		return
+	}
+	all := r.URL.Query().Get("all") == "1"
+
+	result := []types.Container{}
+	for _, c := range f.containers {
+		if !all && c.status != "running" && c.status != "paused" {
+			continue
+		}
+		if ids := args.Get("id"); len(ids) > 0 && !hasPrefix(c.id, ids) {
+			continue
+		}
+		if !matchLabels(args, c.config.Labels) {
+			continue
+		}
+
+		result = append(result, types.Container{
+			ID:              c.id,
+			Names:           []string{"/" + c.name},
+			Image:           c.config.Image,
+			Created:         c.created.Unix(),
+			Labels:          c.config.Labels,
+			State:           c.status,
+			Status:          c.status,
+			NetworkSettings: &types.SummaryNetworkSettings{Networks: c.networks},
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// hasPrefix reports whether id starts with any of the prefixes
+func hasPrefix(id string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatTime formats a time like the Docker API, which reports unset times as year one
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,144 @@
+// Package dockertest provides a fake Docker API for testing code built on the docker package
+package dockertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/yourusername/session-manager/pkg/docker"
+)
+
+// apiVersion is the API version the fake serves; the client is pinned to it so it does not
+// negotiate
+const apiVersion = "1.43"
+
+// FakeAPI is a fake Docker API serving the image, container, network and volume endpoints
+// the docker package uses. Containers do not run anything: starting one only changes its
+// state. Images pulled successfully become present locally.
+type FakeAPI struct {
+	mu sync.Mutex
+	// images holds the images present locally by reference
+	images map[string]*fakeImage
+	// pullMessages holds the JSON messages a pull of a reference streams
+	pullMessages map[string][]string
+	// pulls records the references pulled, in order
+	pulls []string
+	// pullGate, if set, holds pulls until it is closed
+	pullGate chan struct{}
+	// containers holds the containers by ID
+	containers map[string]*fakeContainer
+	// networks holds the networks by name
+	networks map[string]*fakeNetwork
+	// volumes holds the names of the volumes
+	volumes map[string]bool
+	// nextID numbers the objects created, so their IDs are unique
+	nextID int
+}
+
+// NewFakeAPI starts a fake Docker API and returns it with a manager talking to it. The
+// server is closed when the test ends.
+func NewFakeAPI(t *testing.T) (*FakeAPI, *docker.DockerManager) {
+	t.Helper()
+
+	fake := &FakeAPI{
+		images:       make(map[string]*fakeImage),
+		pullMessages: make(map[string][]string),
+		containers:   make(map[string]*fakeContainer),
+		networks:     make(map[string]*fakeNetwork),
+		volumes:      make(map[string]bool),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cli, err := client.NewClientWithOpts(
+		client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")),
+		client.WithVersion(apiVersion),
+		client.WithHTTPClient(server.Client()),
+	)
+	if err != nil {
+		t.Fatalf("failed to create Docker client: %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+
+	return fake, docker.NewDockerManagerWithClient(cli)
+}
+
+// ServeHTTP routes a request to the endpoint it is for
+func (f *FakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v"+apiVersion)
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if len(parts) == 2 && parts[0] == "images" && parts[1] == "create" {
+		f.mu.Lock()
+		gate := f.pullGate
+		f.mu.Unlock()
+		if gate != nil {
+			<-gate
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch parts[0] {
+	case "images":
+		f.serveImages(w, r, parts[1:])
+	case "commit":
+		f.commitContainer(w, r)
+	case "containers":
+		f.serveContainers(w, r, parts[1:])
+	case "networks":
+		f.serveNetworks(w, r, parts[1:])
+	case "volumes":
+		f.serveVolumes(w, r, parts[1:])
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+// newID returns a fresh 64 character hex ID
+func (f *FakeAPI) newID() string {
+	f.nextID++
+	return fmt.Sprintf("%064x", f.nextID)
+}
+
+// listFilters parses the filters query parameter of a list request
+func listFilters(w http.ResponseWriter, r *http.Request) (filters.Args, bool) {
+	args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return args, false
+	}
+	return args, true
+}
+
+// matchLabels reports whether labels satisfy every "key" or "key=value" label filter
+func matchLabels(args filters.Args, labels map[string]string) bool {
+	for _, filter := range args.Get("label") {
+		key, value, hasValue := strings.Cut(filter, "=")
+		actual, exists := labels[key]
+		if !exists || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the shape of the Docker API
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
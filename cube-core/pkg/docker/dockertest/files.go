@@ -0,0 +1,158 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// SetContainerFile writes a file into a container, creating its parent directories
+func (f *FakeAPI) SetContainerFile(id, filePath, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if c := f.findContainer(id); c != nil {
+		c.files[path.Clean(filePath)] = content
+	}
+}
+
+// ContainerFile returns the content of a file in a container
+func (f *FakeAPI) ContainerFile(id, filePath string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.findContainer(id)
+	if c == nil {
+		return "", false
+	}
+	content, exists := c.files[path.Clean(filePath)]
+	return content, exists
+}
+
+// isDir reports whether a path is a directory of a container
+func (c *fakeContainer) isDir(dir string) bool {
+	if dir == "/" || c.dirs[dir] {
+		return true
+	}
+	for name := range c.files {
+		if strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+	for name := range c.dirs {
+		if strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// serveArchive implements the archive endpoints: HEAD stats a path, GET returns it as a tar
+// archive and PUT extracts a tar archive into a directory
+func (f *FakeAPI) serveArchive(w http.ResponseWriter, r *http.Request, c *fakeContainer) {
+	target := path.Clean(r.URL.Query().Get("path"))
+
+	if r.Method == http.MethodPut {
+		f.extractArchive(w, r, c, target)
+		return
+	}
+
+	stat := types.ContainerPathStat{Name: path.Base(target), Mtime: time.Now()}
+	content, isFile := c.files[target]
+	switch {
+	case isFile:
+		stat.Size = int64(len(content))
+		stat.Mode = 0644
+	case c.isDir(target):
+		stat.Mode = os.ModeDir | 0755
+	default:
+		writeError(w, http.StatusNotFound, "Could not find the file "+target+" in container "+c.id)
+		return
+	}
+	encoded, _ := json.Marshal(stat)
+	w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(encoded))
+
+	switch r.Method {
+	case http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.WriteHeader(http.StatusOK)
+		tw := tar.NewWriter(w)
+		if isFile {
+			writeTarFile(tw, stat.Name, content)
+		} else {
+			writeTarDir(tw, c, target, stat.Name)
+		}
+		tw.Close()
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+// writeTarDir writes a directory of a container and everything below it, named after name
+func writeTarDir(tw *tar.Writer, c *fakeContainer, dir, name string) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0755})
+
+	var names []string
+	for filePath := range c.files {
+		if strings.HasPrefix(filePath, prefix) {
+			names = append(names, filePath)
+		}
+	}
+	sort.Strings(names)
+	for _, filePath := range names {
+		writeTarFile(tw, name+"/"+strings.TrimPrefix(filePath, prefix), c.files[filePath])
+	}
+}
+
+// writeTarFile writes a regular file entry
+func writeTarFile(tw *tar.Writer, name, content string) {
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(content))})
+	io.WriteString(tw, content)
+}
+
+// extractArchive extracts the regular files and directories of a tar archive into a
+// directory that exists
+func (f *FakeAPI) extractArchive(w http.ResponseWriter, r *http.Request, c *fakeContainer, dir string) {
+	if !c.isDir(dir) {
+		writeError(w, http.StatusNotFound, "Could not find the file "+dir+" in container "+c.id)
+		return
+	}
+
+	tr := tar.NewReader(r.Body)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		name := path.Join(dir, header.Name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			c.dirs[name] = true
+		case tar.TypeReg:
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			c.files[name] = string(content)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
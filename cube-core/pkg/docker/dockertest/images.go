@@ -0,0 +1,260 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// fakeImage is an image present locally, under one or more references
+type fakeImage struct {
+	id      string
+	refs    []string
+	config  container.Config
+	created time.Time
+}
+
+// AddImage makes a reference present locally
+func (f *FakeAPI) AddImage(ref string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addImage(ref, container.Config{})
+}
+
+// SetPullMessages sets the JSON messages streamed when ref is pulled. A message with an
+// "error" field fails the pull, and the image stays missing.
+func (f *FakeAPI) SetPullMessages(ref string, messages ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pullMessages[ref] = messages
+}
+
+// HoldPulls makes pulls wait until release is called, so tests can act while an image is
+// being pulled. Release may be called more than once.
+func (f *FakeAPI) HoldPulls() (release func()) {
+	gate := make(chan struct{})
+	f.mu.Lock()
+	f.pullGate = gate
+	f.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			f.pullGate = nil
+			f.mu.Unlock()
+			close(gate)
+		})
+	}
+}
+
+// Pulls returns the references pulled so far
+func (f *FakeAPI) Pulls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.pulls...)
+}
+
+// ImageConfig returns the configuration of a local image, such as the environment and
+// labels of a committed one
+func (f *FakeAPI) ImageConfig(ref string) (container.Config, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	img := f.findImage(ref)
+	if img == nil {
+		return container.Config{}, false
+	}
+	return img.config, true
+}
+
+// addImage stores an image under a reference. Callers must hold f.mu.
+func (f *FakeAPI) addImage(ref string, config container.Config) *fakeImage {
+	img := &fakeImage{
+		id:      "sha256:" + f.newID(),
+		refs:    []string{ref},
+		config:  config,
+		created: time.Now(),
+	}
+	f.images[ref] = img
+	return img
+}
+
+// findImage looks up an image by reference or ID. Callers must hold f.mu.
+func (f *FakeAPI) findImage(ref string) *fakeImage {
+	if img, exists := f.images[ref]; exists {
+		return img
+	}
+	for _, img := range f.images {
+		if img.id == ref {
+			return img
+		}
+	}
+	return nil
+}
+
+// serveImages implements the image endpoints
+func (f *FakeAPI) serveImages(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "json":
+		f.listImages(w, r)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "create":
+		ref := r.URL.Query().Get("fromImage")
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			ref += ":" + tag
+		}
+		f.pullImage(w, ref)
+	case r.Method == http.MethodGet && len(parts) >= 2 && parts[len(parts)-1] == "json":
+		f.inspectImage(w, strings.Join(parts[:len(parts)-1], "/"))
+	case r.Method == http.MethodDelete && len(parts) >= 1:
+		f.removeImage(w, strings.Join(parts, "/"))
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+// inspectImage answers an image inspect for present references only
+func (f *FakeAPI) inspectImage(w http.ResponseWriter, ref string) {
+	img := f.findImage(ref)
+	if img == nil {
+		writeError(w, http.StatusNotFound, "No such image: "+ref)
+		return
+	}
+
+	config := img.config
+	writeJSON(w, http.StatusOK, types.ImageInspect{
+		ID:       img.id,
+		RepoTags: img.refs,
+		Created:  img.created.Format(time.RFC3339Nano),
+		Config:   &config,
+	})
+}
+
+// listImages lists the local images matching the label filters
+func (f *FakeAPI) listImages(w http.ResponseWriter, r *http.Request) {
+	args, ok := listFilters(w, r)
+	if !ok {
+		return
+	}
+
+	seen := make(map[string]bool)
+	result := []types.ImageSummary{}
+	for _, img := range f.images {
+		if seen[img.id] || !matchLabels(args, img.config.Labels) {
+			continue
+		}
+		seen[img.id] = true
+		result = append(result, types.ImageSummary{
+			ID:       img.id,
+			RepoTags: img.refs,
+			Labels:   img.config.Labels,
+			Created:  img.created.Unix(),
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// removeImage removes an image, unless a container still uses it
+func (f *FakeAPI) removeImage(w http.ResponseWriter, ref string) {
+	img := f.findImage(ref)
+	if img == nil {
+		writeError(w, http.StatusNotFound, "No such image: "+ref)
+		return
+	}
+
+	for _, c := range f.containers {
+		if f.findImage(c.config.Image) == img {
+			writeError(w, http.StatusConflict, "conflict: unable to remove repository reference \""+ref+"\" (must force) - container "+c.id[:12]+" is using its referenced image")
+			return
+		}
+	}
+
+	for _, r := range img.refs {
+		delete(f.images, r)
+	}
+	writeJSON(w, http.StatusOK, []types.ImageDeleteResponseItem{{Untagged: ref}, {Deleted: img.id}})
+}
+
+// pullImage streams the configured messages of a pull, like Docker does
+func (f *FakeAPI) pullImage(w http.ResponseWriter, ref string) {
+	f.pulls = append(f.pulls, ref)
+	messages, configured := f.pullMessages[ref]
+	if !configured {
+		messages = []string{`{"status":"Pulling from library/` + ref + `"}`, `{"status":"Status: Downloaded newer image for ` + ref + `"}`}
+	}
+	failed := false
+	for _, message := range messages {
+		if strings.Contains(message, `"error"`) {
+			failed = true
+		}
+	}
+	if !failed && f.findImage(ref) == nil {
+		f.addImage(ref, container.Config{})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	for _, message := range messages {
+		w.Write([]byte(message + "\n"))
+	}
+}
+
+// commitContainer commits a container to a new image. Like Docker, the configuration sent
+// along overrides the container's, one environment variable and label at a time.
+func (f *FakeAPI) commitContainer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusNotFound, "page not found")
+		return
+	}
+
+	query := r.URL.Query()
+	c := f.findContainer(query.Get("container"))
+	if c == nil {
+		writeError(w, http.StatusNotFound, "No such container: "+query.Get("container"))
+		return
+	}
+
+	var override container.Config
+	if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	config := c.config
+	config.Env = mergeEnv(override.Env, c.config.Env)
+	config.Labels = make(map[string]string)
+	for key, value := range c.config.Labels {
+		config.Labels[key] = value
+	}
+	for key, value := range override.Labels {
+		config.Labels[key] = value
+	}
+
+	ref := query.Get("repo")
+	if tag := query.Get("tag"); tag != "" {
+		ref += ":" + tag
+	}
+	img := f.addImage(ref, config)
+	writeJSON(w, http.StatusCreated, types.IDResponse{ID: img.id})
+}
+
+// mergeEnv adds the variables of base that env does not set to env
+func mergeEnv(env, base []string) []string {
+	merged := append([]string(nil), env...)
+	set := make(map[string]bool, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		set[name] = true
+	}
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if !set[name] {
+			merged = append(merged, kv)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,152 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// fakeNetwork is a user-defined network
+type fakeNetwork struct {
+	id       string
+	name     string
+	internal bool
+	labels   map[string]string
+}
+
+// AddNetwork creates a network, as if it was left behind by an earlier run
+func (f *FakeAPI) AddNetwork(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.networks[name] = &fakeNetwork{id: f.newID(), name: name}
+}
+
+// Networks returns the names of the user-defined networks, sorted
+func (f *FakeAPI) Networks() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.networks))
+	for name := range f.networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NetworkInternal reports whether a network exists and is internal, without outside access
+func (f *FakeAPI) NetworkInternal(name string) (internal, exists bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := f.findNetwork(name)
+	if n == nil {
+		return false, false
+	}
+	return n.internal, true
+}
+
+// Volumes returns the names of the volumes, sorted
+func (f *FakeAPI) Volumes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.volumes))
+	for name := range f.volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findNetwork looks up a network by name or ID. Callers must hold f.mu.
+func (f *FakeAPI) findNetwork(ref string) *fakeNetwork {
+	if n, exists := f.networks[ref]; exists {
+		return n
+	}
+	for _, n := range f.networks {
+		if n.id == ref {
+			return n
+		}
+	}
+	return nil
+}
+
+// serveNetworks implements the network create, remove and connect endpoints
+func (f *FakeAPI) serveNetworks(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "create":
+		var req types.NetworkCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if f.findNetwork(req.Name) != nil {
+			writeError(w, http.StatusConflict, "network with name "+req.Name+" already exists")
+			return
+		}
+		n := &fakeNetwork{id: f.newID(), name: req.Name, internal: req.Internal, labels: req.Labels}
+		f.networks[n.name] = n
+		writeJSON(w, http.StatusCreated, types.NetworkCreateResponse{ID: n.id})
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		n := f.findNetwork(parts[0])
+		if n == nil {
+			writeError(w, http.StatusNotFound, "network "+parts[0]+" not found")
+			return
+		}
+		for _, c := range f.containers {
+			if _, attached := c.networks[n.name]; attached {
+				writeError(w, http.StatusForbidden, "error while removing network: network "+n.name+" has active endpoints")
+				return
+			}
+		}
+		delete(f.networks, n.name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "connect":
+		n := f.findNetwork(parts[0])
+		if n == nil {
+			writeError(w, http.StatusNotFound, "network "+parts[0]+" not found")
+			return
+		}
+		var req types.NetworkConnect
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		c := f.findContainer(req.Container)
+		if c == nil {
+			writeError(w, http.StatusNotFound, "No such container: "+req.Container)
+			return
+		}
+		c.networks[n.name] = req.EndpointConfig
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+// serveVolumes implements the volume create and remove endpoints
+func (f *FakeAPI) serveVolumes(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "create":
+		var req volume.CreateOptions
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		f.volumes[req.Name] = true
+		writeJSON(w, http.StatusCreated, volume.Volume{Name: req.Name, Driver: "local", Labels: req.Labels})
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		if !f.volumes[parts[0]] {
+			writeError(w, http.StatusNotFound, "get "+parts[0]+": no such volume")
+			return
+		}
+		delete(f.volumes, parts[0])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
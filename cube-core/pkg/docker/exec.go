@@ -0,0 +1,188 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// maxExecOutputBytes caps how much output of a command is kept per stream
+const maxExecOutputBytes = 64 * 1024
+
+// ExecOptions describes a command to run inside a container
+type ExecOptions struct {
+	Cmd        []string
+	Env        []string // KEY=value pairs
+	User       string
+	WorkingDir string
+}
+
+// ExecResult is the outcome of a command run inside a container
+type ExecResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// RunCommand runs a command inside a running container, waits for it to finish and returns
+// its exit code and (truncated) output. Cancelling ctx stops waiting for the command.
+func (dm *DockerManager) RunCommand(ctx context.Context, containerID string, opts ExecOptions) (*ExecResult, error) {
+	created, err := dm.client.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd:          opts.Cmd,
+		Env:          opts.Env,
+		User:         opts.User,
+		WorkingDir:   opts.WorkingDir,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attached, err := dm.client.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start exec: %w", err)
+	}
+	defer attached.Close()
+
+	// Close the connection when ctx ends so the copy below returns
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			attached.Close()
+		case <-done:
+		}
+	}()
+
+	stdout := &limitedBuffer{limit: maxExecOutputBytes}
+	stderr := &limitedBuffer{limit: maxExecOutputBytes}
+	if _, err := stdcopy.StdCopy(stdout, stderr, attached.Reader); err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("failed to read exec output: %v", err)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	inspect, err := dm.client.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+
+	return &ExecResult{
+		ExitCode: inspect.ExitCode,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	}, nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+// InteractiveExec is a running command attached to a TTY inside a container
+type InteractiveExec struct {
+	dm        *DockerManager
+	id        string
+	attached  types.HijackedResponse
+	closeOnce sync.Once
+}
+
+// StartInteractiveExec starts a command with a TTY of rows x cols inside a running container.
+// Output is read from the returned exec and input written to it; closing it hangs up the TTY,
+// which ends the command.
+func (dm *DockerManager) StartInteractiveExec(ctx context.Context, containerID string, opts ExecOptions, rows, cols uint) (*InteractiveExec, error) {
+	config := types.ExecConfig{
+		Cmd:          opts.Cmd,
+		Env:          opts.Env,
+		User:         opts.User,
+		WorkingDir:   opts.WorkingDir,
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	if rows > 0 && cols > 0 {
+		config.ConsoleSize = &[2]uint{rows, cols}
+	}
+
+	created, err := dm.client.ContainerExecCreate(ctx, containerID, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attached, err := dm.client.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{
+		Tty:         true,
+		ConsoleSize: config.ConsoleSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start exec: %w", err)
+	}
+
+	return &InteractiveExec{dm: dm, id: created.ID, attached: attached}, nil
+}
+
+// Read reads terminal output
+func (e *InteractiveExec) Read(p []byte) (int, error) {
+	return e.attached.Reader.Read(p)
+}
+
+// Write writes terminal input
+func (e *InteractiveExec) Write(p []byte) (int, error) {
+	return e.attached.Conn.Write(p)
+}
+
+// Resize changes the size of the terminal
+func (e *InteractiveExec) Resize(rows, cols uint) error {
+	if err := e.dm.client.ContainerExecResize(e.dm.ctx, e.id, types.ResizeOptions{Height: rows, Width: cols}); err != nil {
+		return fmt.Errorf("failed to resize exec: %v", err)
+	}
+	return nil
+}
+
+// ExitCode returns the exit code of the command, waiting up to a second for it to finish
+// after its output ended. It returns false if the command is still running.
+func (e *InteractiveExec) ExitCode() (int, bool) {
+	for i := 0; i < 10; i++ {
+		inspect, err := e.dm.client.ContainerExecInspect(e.dm.ctx, e.id)
+		if err != nil {
+			return 0, false
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return 0, false
+}
+
+// Close hangs up the terminal. It is safe to call more than once.
+func (e *InteractiveExec) Close() error {
+	e.closeOnce.Do(func() {
+		e.attached.Close()
+	})
+	return nil
+}
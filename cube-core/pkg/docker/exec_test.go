@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLimitedBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		limit  int
+		want   string
+	}{
+		{name: "under limit", writes: []string{"ab", "cd"}, limit: 8, want: "abcd"},
+		{name: "at limit", writes: []string{"abcd"}, limit: 4, want: "abcd"},
+		{name: "cut within write", writes: []string{"ab", "cdef"}, limit: 3, want: "abc"},
+		{name: "writes after limit", writes: []string{"abc", "def", "g"}, limit: 3, want: "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &limitedBuffer{limit: tt.limit}
+			for _, w := range tt.writes {
+				// Output past the limit is discarded, not reported as a short write
+				if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+			if got := b.String(); got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	b := &limitedBuffer{limit: maxExecOutputBytes}
+	b.Write([]byte(strings.Repeat("x", maxExecOutputBytes+1)))
+	if len(b.String()) != maxExecOutputBytes {
+		t.Fatalf("kept %d bytes, want %d", len(b.String()), maxExecOutputBytes)
+	}
+}
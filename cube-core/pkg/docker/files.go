@@ -0,0 +1,60 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// PathStat describes a file or directory inside a container
+type PathStat struct {
+	Name       string
+	Size       int64
+	Mode       os.FileMode
+	ModTime    time.Time
+	LinkTarget string
+}
+
+// CopyToContainer extracts a tar archive into a directory that exists inside a container.
+// Cancelling ctx aborts the copy.
+func (dm *DockerManager) CopyToContainer(ctx context.Context, containerID, dstDir string, archive io.Reader) error {
+	err := dm.client.CopyToContainer(ctx, containerID, dstDir, archive, types.CopyToContainerOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to copy to container: %w", err)
+	}
+	return nil
+}
+
+// CopyFromContainer returns a tar archive of a file or directory inside a container, along
+// with what the path refers to. The caller must close the archive.
+func (dm *DockerManager) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, PathStat, error) {
+	archive, stat, err := dm.client.CopyFromContainer(ctx, containerID, srcPath)
+	if err != nil {
+		return nil, PathStat{}, fmt.Errorf("failed to copy from container: %w", err)
+	}
+	return archive, pathStat(stat), nil
+}
+
+// StatContainerPath describes a file or directory inside a container
+func (dm *DockerManager) StatContainerPath(ctx context.Context, containerID, path string) (PathStat, error) {
+	stat, err := dm.client.ContainerStatPath(ctx, containerID, path)
+	if err != nil {
+		return PathStat{}, fmt.Errorf("failed to stat container path: %w", err)
+	}
+	return pathStat(stat), nil
+}
+
+// pathStat converts a Docker container path stat
+func pathStat(stat types.ContainerPathStat) PathStat {
+	return PathStat{
+		Name:       stat.Name,
+		Size:       stat.Size,
+		Mode:       stat.Mode,
+		ModTime:    stat.Mtime,
+		LinkTarget: stat.LinkTarget,
+	}
+}
@@ -0,0 +1,183 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// RegistryAuth holds credentials for pulling from a private registry
+type RegistryAuth struct {
+	Username      string
+	Password      string
+	ServerAddress string
+	IdentityToken string
+}
+
+// PullProgress is a single progress update reported while pulling an image
+type PullProgress struct {
+	ID       string // layer ID, empty for image-level messages
+	Status   string
+	Progress string // human-readable progress bar
+	Current  int64
+	Total    int64
+}
+
+// ImageExists reports whether an image reference is present locally
+func (dm *DockerManager) ImageExists(ref string) (bool, error) {
+	_, _, err := dm.client.ImageInspectWithRaw(dm.ctx, ref)
+	if err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to inspect image: %v", err)
+	}
+	return true, nil
+}
+
+// ImageExposedPorts returns the container ports an image reference exposes
+func (dm *DockerManager) ImageExposedPorts(ref string) ([]int, error) {
+	return dm.getImageExposedPorts(ref)
+}
+
+// PullImage pulls an image reference, calling progress (if not nil) for every progress
+// message Docker reports. Cancelling ctx aborts the pull.
+func (dm *DockerManager) PullImage(ctx context.Context, ref string, auth *RegistryAuth, progress func(PullProgress)) error {
+	options := types.ImagePullOptions{}
+	if auth != nil {
+		encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			ServerAddress: auth.ServerAddress,
+			IdentityToken: auth.IdentityToken,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode registry credentials: %v", err)
+		}
+		options.RegistryAuth = encoded
+	}
+
+	body, err := dm.client.ImagePull(ctx, ref, options)
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %v", ref, err)
+	}
+	defer body.Close()
+
+	if err := decodePullProgress(body, progress); err != nil {
+		return fmt.Errorf("failed to pull image %s: %v", ref, err)
+	}
+	return nil
+}
+
+// decodePullProgress reads Docker's JSON message stream until it ends, returning the
+// first error message in the stream
+func decodePullProgress(r io.Reader, progress func(PullProgress)) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to decode pull progress: %v", err)
+		}
+
+		if msg.Error != nil {
+			return errors.New(msg.Error.Message)
+		}
+		if msg.ErrorMessage != "" {
+			return errors.New(msg.ErrorMessage)
+		}
+
+		if progress == nil {
+			continue
+		}
+
+		update := PullProgress{
+			ID:     msg.ID,
+			Status: msg.Status,
+		}
+		if msg.Progress != nil {
+			update.Progress = msg.Progress.String()
+			update.Current = msg.Progress.Current
+			update.Total = msg.Progress.Total
+		}
+		progress(update)
+	}
+}
+
+// Labels stamped on images committed from session containers
+const (
+	LabelSnapshotID          = "cube.snapshot.id"
+	LabelSnapshotName        = "cube.snapshot.name"
+	LabelSnapshotSessionID   = "cube.snapshot.session-id"
+	LabelSnapshotSourceImage = "cube.snapshot.source-image"
+	LabelSnapshotCreatedAt   = "cube.snapshot.created-at"
+)
+
+// LabeledImage is a local image along with its labels
+type LabeledImage struct {
+	ID       string
+	RepoTags []string
+	Labels   map[string]string
+	Created  int64
+	Size     int64
+}
+
+// CommitContainer commits a container's filesystem to a new image tagged ref, pausing the
+// container while it is copied. Labels and env (KEY=value pairs) are added to the image's
+// configuration; the labels and environment variables the container already has are kept
+// unless overridden here.
+func (dm *DockerManager) CommitContainer(ctx context.Context, containerID, ref, comment string, labels map[string]string, env []string) (string, error) {
+	resp, err := dm.client.ContainerCommit(ctx, containerID, types.ContainerCommitOptions{
+		Reference: ref,
+		Comment:   comment,
+		Pause:     true,
+		Config:    &container.Config{Labels: labels, Env: env},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to commit container: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// ListImagesWithLabel returns the local images that carry a label, whatever its value
+func (dm *DockerManager) ListImagesWithLabel(label string) ([]LabeledImage, error) {
+	filter := filters.NewArgs()
+	filter.Add("label", label)
+
+	images, err := dm.client.ImageList(dm.ctx, types.ImageListOptions{Filters: filter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %v", err)
+	}
+
+	result := make([]LabeledImage, 0, len(images))
+	for _, img := range images {
+		result = append(result, LabeledImage{
+			ID:       img.ID,
+			RepoTags: img.RepoTags,
+			Labels:   img.Labels,
+			Created:  img.Created,
+			Size:     img.Size,
+		})
+	}
+	return result, nil
+}
+
+// RemoveImage removes an image and any untagged parents. Images used by a container are
+// left in place and reported as a conflict.
+func (dm *DockerManager) RemoveImage(ref string) error {
+	_, err := dm.client.ImageRemove(dm.ctx, ref, types.ImageRemoveOptions{PruneChildren: true})
+	if err != nil {
+		return fmt.Errorf("failed to remove image: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LogOptions selects which container logs to read
+type LogOptions struct {
+	Follow     bool
+	Since      string // timestamp or relative duration such as "10m"
+	Tail       string // number of lines from the end, or "all"
+	Timestamps bool
+	Stdout     bool
+	Stderr     bool
+}
+
+// LogStream is an open stream of container logs
+type LogStream struct {
+	body io.ReadCloser
+	tty  bool
+}
+
+// ContainerLogs opens a container's log stream. Cancelling ctx aborts the request to Docker,
+// which also ends a followed stream.
+func (dm *DockerManager) ContainerLogs(ctx context.Context, containerID string, opts LogOptions) (*LogStream, error) {
+	// Containers with a TTY have a single raw stream instead of the multiplexed format
+	inspect, err := dm.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	body, err := dm.client.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: opts.Stdout,
+		ShowStderr: opts.Stderr,
+		Since:      opts.Since,
+		Tail:       opts.Tail,
+		Timestamps: opts.Timestamps,
+		Follow:     opts.Follow,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container logs: %w", err)
+	}
+
+	return &LogStream{
+		body: body,
+		tty:  inspect.Config != nil && inspect.Config.Tty,
+	}, nil
+}
+
+// Copy demultiplexes the log stream into stdout and stderr until it ends. Output of TTY
+// containers all goes to stdout.
+func (ls *LogStream) Copy(stdout, stderr io.Writer) error {
+	var err error
+	if ls.tty {
+		_, err = io.Copy(stdout, ls.body)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, ls.body)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy container logs: %v", err)
+	}
+	return nil
+}
+
+// Close closes the log stream
+func (ls *LogStream) Close() error {
+	return ls.body.Close()
+}
@@ -3,7 +3,9 @@ package docker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -11,7 +13,10 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/go-connections/nat"
 )
 
@@ -26,9 +31,75 @@ type PortMapping struct {
 	Protocol      string
 }
 
-func NewDockerManager() (*DockerManager, error) {
+// Labels stamped on containers managed by cube
+const (
+	LabelManaged   = "cube.managed"
+	LabelSessionID = "cube.session.id"
+	LabelImage     = "cube.session.image"
+	LabelPorts     = "cube.session.ports"
+	LabelCreatedAt = "cube.session.created-at"
+	LabelExpiresAt = "cube.session.expires-at"
+	// Containers of multi-container sessions also carry their service name, and the
+	// primary service is marked. LabelDependsOn lists the services a service starts after.
+	LabelService   = "cube.session.service"
+	LabelPrimary   = "cube.session.primary"
+	LabelDependsOn = "cube.session.depends-on"
+	// Containers on a private session network carry its name, and whether it is internal
+	LabelNetwork         = "cube.session.network"
+	LabelInternalNetwork = "cube.session.internal-network"
+	// LabelEgress holds the egress policy of a session's containers as JSON, and
+	// LabelEgressProxy marks the proxy container enforcing it
+	LabelEgress      = "cube.session.egress"
+	LabelEgressProxy = "cube.session.egress-proxy"
+	// LabelOwnedSnapshot names the snapshot a session was started from and deletes with it
+	LabelOwnedSnapshot = "cube.session.owned-snapshot"
+)
+
+// MountSpec describes a volume, bind mount or tmpfs to attach to a container
+type MountSpec struct {
+	Type           string // "volume", "bind" or "tmpfs"
+	Source         string
+	Target         string
+	ReadOnly       bool
+	TmpfsSizeBytes int64
+}
+
+// ResourceLimits caps the resources a container may use; zero values mean no limit
+type ResourceLimits struct {
+	NanoCPUs    int64
+	Memory      int64
+	MemorySwap  int64
+	PidsLimit   int64
+	BlkioWeight uint16
+}
+
+// ContainerOptions describes a container to create
+type ContainerOptions struct {
+	Image        string
+	PortMappings []PortMapping
+	Labels       map[string]string
+	Resources    ResourceLimits
+	Env          []string // KEY=value pairs
+	Cmd          []string
+	Entrypoint   []string
+	WorkingDir   string
+	User         string
+	Mounts       []MountSpec
+	// RestartPolicy is the Docker restart policy: "no", "always", "on-failure" or "unless-stopped"
+	RestartPolicy     string
+	MaxRestartRetries int // only used with "on-failure"
+	// Network is a user-defined network to attach the container to, where other containers
+	// reach it under NetworkAliases
+	Network        string
+	NetworkAliases []string
+}
+
+// NewDockerManager connects to the Docker daemon configured in the environment. Extra client
+// options, such as client.WithHost, are applied on top of the environment.
+func NewDockerManager(opts ...client.Opt) (*DockerManager, error) {
 	// Create Docker client using environment variables
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	clientOpts := append([]client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}, opts...)
+	cli, err := client.NewClientWithOpts(clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %v", err)
 	}
@@ -46,12 +117,21 @@ func NewDockerManager() (*DockerManager, error) {
 	}, nil
 }
 
-func (dm *DockerManager) CreateContainer(imageName string, portMappings []PortMapping) (string, error) {
+// NewDockerManagerWithClient wraps an existing Docker client without pinging it, e.g. one
+// pointed at the fake Docker API of the dockertest package
+func NewDockerManagerWithClient(cli *client.Client) *DockerManager {
+	return &DockerManager{
+		client: cli,
+		ctx:    context.Background(),
+	}
+}
+
+func (dm *DockerManager) CreateContainer(opts ContainerOptions) (string, error) {
 	// Prepare port bindings
 	portBindings := nat.PortMap{}
 	exposedPorts := nat.PortSet{}
 
-	for _, mapping := range portMappings {
+	for _, mapping := range opts.PortMappings {
 		protocol := mapping.Protocol
 		if protocol == "" {
 			protocol = "tcp"
@@ -72,13 +152,48 @@ func (dm *DockerManager) CreateContainer(imageName string, portMappings []PortMa
 
 	// Create container configuration
 	containerConfig := &container.Config{
-		Image:        imageName,
+		Image:        opts.Image,
 		ExposedPorts: exposedPorts,
+		Labels:       opts.Labels,
+		Env:          opts.Env,
+		Cmd:          opts.Cmd,
+		Entrypoint:   opts.Entrypoint,
+		WorkingDir:   opts.WorkingDir,
+		User:         opts.User,
 	}
 
-	// Configure host settings including port bindings
+	// Configure host settings including port bindings and resource limits
 	hostConfig := &container.HostConfig{
 		PortBindings: portBindings,
+		Resources: container.Resources{
+			NanoCPUs:    opts.Resources.NanoCPUs,
+			Memory:      opts.Resources.Memory,
+			MemorySwap:  opts.Resources.MemorySwap,
+			BlkioWeight: opts.Resources.BlkioWeight,
+		},
+		RestartPolicy: container.RestartPolicy{
+			Name:              opts.RestartPolicy,
+			MaximumRetryCount: opts.MaxRestartRetries,
+		},
+	}
+	for _, m := range opts.Mounts {
+		hostMount := mount.Mount{
+			Type:     mount.Type(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		}
+		if m.Type == string(mount.TypeTmpfs) && m.TmpfsSizeBytes > 0 {
+			hostMount.TmpfsOptions = &mount.TmpfsOptions{SizeBytes: m.TmpfsSizeBytes}
+		}
+		hostConfig.Mounts = append(hostConfig.Mounts, hostMount)
+	}
+	if opts.Resources.PidsLimit > 0 {
+		pidsLimit := opts.Resources.PidsLimit
+		hostConfig.Resources.PidsLimit = &pidsLimit
+	}
+	if opts.Network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(opts.Network)
 	}
 
 	// Create the container
@@ -86,7 +201,7 @@ func (dm *DockerManager) CreateContainer(imageName string, portMappings []PortMa
 		dm.ctx,
 		containerConfig,
 		hostConfig,
-		nil,
+		networkingConfig(opts.Network, opts.NetworkAliases),
 		nil,
 		"",
 	)
@@ -96,24 +211,111 @@ func (dm *DockerManager) CreateContainer(imageName string, portMappings []PortMa
 
 	// Start the container
 	if err := dm.client.ContainerStart(dm.ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		// Don't leave a labelled container behind to be reconciled into a session later
+		if removeErr := dm.RemoveContainer(resp.ID); removeErr != nil {
+			return "", fmt.Errorf("failed to start container: %v (and failed to remove it: %v)", err, removeErr)
+		}
 		return "", fmt.Errorf("failed to start container: %v", err)
 	}
 
 	return resp.ID, nil
 }
 
+// IsNotFound reports whether err is a Docker "no such container/image" error. Like
+// IsConflict, it also finds not found errors wrapped with %w.
+func IsNotFound(err error) bool {
+	var notFound errdefs.ErrNotFound
+	return client.IsErrNotFound(err) || errors.As(err, &notFound)
+}
+
+// IsConflict reports whether err is a Docker conflict error, such as removing an image
+// that a container still uses. Unlike errdefs.IsConflict, it also finds conflicts wrapped
+// with %w.
+func IsConflict(err error) bool {
+	var conflict errdefs.ErrConflict
+	return errdefs.IsConflict(err) || errors.As(err, &conflict)
+}
+
 func (dm *DockerManager) StopContainer(containerID string) error {
 	// Default timeout is 10 seconds
 	timeoutSeconds := 10
 	return dm.client.ContainerStop(dm.ctx, containerID, container.StopOptions{Timeout: &timeoutSeconds})
 }
 
+// StartContainer starts a stopped container, reusing its existing port bindings
+func (dm *DockerManager) StartContainer(containerID string) error {
+	return dm.client.ContainerStart(dm.ctx, containerID, types.ContainerStartOptions{})
+}
+
+// RestartContainer stops (with the default timeout) and starts a container again
+func (dm *DockerManager) RestartContainer(containerID string) error {
+	timeoutSeconds := 10
+	return dm.client.ContainerRestart(dm.ctx, containerID, container.StopOptions{Timeout: &timeoutSeconds})
+}
+
+// PauseContainer freezes all processes in a container
+func (dm *DockerManager) PauseContainer(containerID string) error {
+	return dm.client.ContainerPause(dm.ctx, containerID)
+}
+
+// UnpauseContainer resumes all processes in a paused container
+func (dm *DockerManager) UnpauseContainer(containerID string) error {
+	return dm.client.ContainerUnpause(dm.ctx, containerID)
+}
+
 func (dm *DockerManager) RemoveContainer(containerID string) error {
 	return dm.client.ContainerRemove(dm.ctx, containerID, types.ContainerRemoveOptions{
 		Force: true,
 	})
 }
 
+// RecreateContainer replaces a container with a fresh one created from the same
+// configuration, keeping its labels, mounts, network and host port bindings, and starts it.
+// It returns the ID of the new container.
+func (dm *DockerManager) RecreateContainer(containerID string) (string, error) {
+	inspect, err := dm.client.ContainerInspect(dm.ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	// The old container has to go first to free its host ports
+	if err := dm.RemoveContainer(containerID); err != nil && !IsNotFound(err) {
+		return "", fmt.Errorf("failed to remove container: %w", err)
+	}
+
+	resp, err := dm.client.ContainerCreate(dm.ctx, inspect.Config, inspect.HostConfig, inspectedNetworkingConfig(inspect), nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %v", err)
+	}
+
+	if err := dm.client.ContainerStart(dm.ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		dm.RemoveContainer(resp.ID)
+		return "", fmt.Errorf("failed to start container: %v", err)
+	}
+
+	return resp.ID, nil
+}
+
+// CreateVolume creates a named volume with the given labels
+func (dm *DockerManager) CreateVolume(name string, labels map[string]string) error {
+	_, err := dm.client.VolumeCreate(dm.ctx, volume.CreateOptions{
+		Name:   name,
+		Labels: labels,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create volume %s: %v", name, err)
+	}
+	return nil
+}
+
+// RemoveVolume removes a named volume
+func (dm *DockerManager) RemoveVolume(name string) error {
+	if err := dm.client.VolumeRemove(dm.ctx, name, true); err != nil {
+		return fmt.Errorf("failed to remove volume %s: %v", name, err)
+	}
+	return nil
+}
+
 type Container struct {
 	ID      string
 	Image   string
@@ -123,6 +325,9 @@ type Container struct {
 	Names   []string
 	Created int64
 	Ports   []PortInfo
+	Labels  map[string]string
+	// Networks are the names of the networks the container is attached to
+	Networks []string
 }
 
 type PortInfo struct {
@@ -138,9 +343,24 @@ func (dm *DockerManager) ListContainers(includeAll ...bool) ([]Container, error)
 		all = includeAll[0]
 	}
 
-	containers, err := dm.client.ContainerList(dm.ctx, types.ContainerListOptions{
+	return dm.listContainers(types.ContainerListOptions{
 		All: all,
 	})
+}
+
+// ListManagedContainers returns all containers, running or not, that carry the cube managed label
+func (dm *DockerManager) ListManagedContainers() ([]Container, error) {
+	filter := filters.NewArgs()
+	filter.Add("label", LabelManaged+"=true")
+
+	return dm.listContainers(types.ContainerListOptions{
+		All:     true,
+		Filters: filter,
+	})
+}
+
+func (dm *DockerManager) listContainers(options types.ContainerListOptions) ([]Container, error) {
+	containers, err := dm.client.ContainerList(dm.ctx, options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list containers: %v", err)
 	}
@@ -155,6 +375,7 @@ func (dm *DockerManager) ListContainers(includeAll ...bool) ([]Container, error)
 			Command: c.Command,
 			Names:   c.Names,
 			Created: c.Created,
+			Labels:  c.Labels,
 		}
 
 		// Parse port information
@@ -169,6 +390,13 @@ func (dm *DockerManager) ListContainers(includeAll ...bool) ([]Container, error)
 		}
 		container.Ports = ports
 
+		if c.NetworkSettings != nil {
+			for name := range c.NetworkSettings.Networks {
+				container.Networks = append(container.Networks, name)
+			}
+			sort.Strings(container.Networks)
+		}
+
 		result = append(result, container)
 	}
 
@@ -275,6 +503,105 @@ func (dm *DockerManager) InspectContainer(containerID string) (map[string]interf
 	return containerInfo, nil
 }
 
+// ContainerEnv returns the environment of a container in KEY=value form, including the
+// variables it inherits from its image
+func (dm *DockerManager) ContainerEnv(containerID string) ([]string, error) {
+	inspect, err := dm.client.ContainerInspect(dm.ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	if inspect.Config == nil {
+		return nil, nil
+	}
+	return inspect.Config.Env, nil
+}
+
+// ContainerState is the live state of a container as reported by inspect
+type ContainerState struct {
+	Status       string // "created", "running", "paused", "restarting", "removing", "exited" or "dead"
+	Running      bool
+	Paused       bool
+	Restarting   bool
+	OOMKilled    bool
+	Dead         bool
+	ExitCode     int
+	Error        string
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	RestartCount int
+	Health       *HealthState // nil if the container has no healthcheck
+}
+
+// HealthState is the result of a container's Docker HEALTHCHECK
+type HealthState struct {
+	Status        string // "starting", "healthy" or "unhealthy"
+	FailingStreak int
+	LastOutput    string
+	LastExitCode  int
+	LastCheckedAt time.Time
+}
+
+// InspectContainerState returns the live state of a container. A missing container
+// yields an error for which IsNotFound reports true.
+func (dm *DockerManager) InspectContainerState(containerID string) (*ContainerState, error) {
+	inspect, err := dm.client.ContainerInspect(dm.ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	state := &ContainerState{
+		RestartCount: inspect.RestartCount,
+	}
+	if inspect.State == nil {
+		return state, nil
+	}
+
+	state.Status = inspect.State.Status
+	state.Running = inspect.State.Running
+	state.Paused = inspect.State.Paused
+	state.Restarting = inspect.State.Restarting
+	state.OOMKilled = inspect.State.OOMKilled
+	state.Dead = inspect.State.Dead
+	state.ExitCode = inspect.State.ExitCode
+	state.Error = inspect.State.Error
+	state.StartedAt = parseDockerTime(inspect.State.StartedAt)
+	state.FinishedAt = parseDockerTime(inspect.State.FinishedAt)
+	state.Health = ContainerHealth(inspect.State.Health)
+
+	return state, nil
+}
+
+// ContainerHealth converts the health section of a container inspect response, returning
+// nil if the container has no healthcheck
+func ContainerHealth(health *types.Health) *HealthState {
+	if health == nil || health.Status == "" || health.Status == types.NoHealthcheck {
+		return nil
+	}
+
+	state := &HealthState{
+		Status:        health.Status,
+		FailingStreak: health.FailingStreak,
+	}
+	// Docker keeps the last few probe results, most recent last
+	if n := len(health.Log); n > 0 {
+		last := health.Log[n-1]
+		state.LastOutput = strings.TrimSpace(last.Output)
+		state.LastExitCode = last.ExitCode
+		state.LastCheckedAt = last.End
+	}
+	return state
+}
+
+// parseDockerTime parses a timestamp from the Docker API, returning the zero time for
+// unset values such as "0001-01-01T00:00:00Z"
+func parseDockerTime(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || parsed.Year() <= 1 {
+		return time.Time{}
+	}
+	return parsed
+}
+
 // ContainerExists checks if a container with the given ID exists
 func (dm *DockerManager) ContainerExists(containerID string) (bool, error) {
 	// Create a filter to search by container ID
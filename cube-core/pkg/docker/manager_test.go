@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+)
+
+func TestIsConflict(t *testing.T) {
+	conflict := errdefs.Conflict(errors.New("network with name cube-egress already exists"))
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"conflict", conflict, true},
+		{"wrapped with %w", fmt.Errorf("failed to create network: %w", conflict), true},
+		{"wrapped twice", fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", conflict)), true},
+		{"flattened with %v", fmt.Errorf("failed to create network: %v", conflict), false},
+		{"not found", errdefs.NotFound(errors.New("no such image")), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsConflict(tt.err); got != tt.want {
+				t.Errorf("IsConflict(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	notFound := errdefs.NotFound(errors.New("No such container: abc"))
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"not found", notFound, true},
+		{"wrapped with %w", fmt.Errorf("failed to inspect container: %w", notFound), true},
+		{"wrapped twice", fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", notFound)), true},
+		{"flattened with %v", fmt.Errorf("failed to inspect container: %v", notFound), false},
+		{"conflict", errdefs.Conflict(errors.New("in use")), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNotFound(tt.err); got != tt.want {
+				t.Errorf("IsNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+)
+
+// CreateNetwork creates a bridge network with the given labels and returns its ID. Containers
+// on an internal network can only reach each other: they have no route out, and Docker does
+// not publish their ports on the host.
+func (dm *DockerManager) CreateNetwork(name string, internal bool, labels map[string]string) (string, error) {
+	resp, err := dm.client.NetworkCreate(dm.ctx, name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+		Internal:       internal,
+		Labels:         labels,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create network %s: %w", name, err)
+	}
+	return resp.ID, nil
+}
+
+// RemoveNetwork removes a network. Containers still attached to it make this fail.
+func (dm *DockerManager) RemoveNetwork(name string) error {
+	if err := dm.client.NetworkRemove(dm.ctx, name); err != nil {
+		return fmt.Errorf("failed to remove network %s: %w", name, err)
+	}
+	return nil
+}
+
+// ConnectNetwork attaches a container to another network, where other containers reach it
+// under the given aliases
+func (dm *DockerManager) ConnectNetwork(networkName, containerID string, aliases []string) error {
+	err := dm.client.NetworkConnect(dm.ctx, networkName, containerID, &network.EndpointSettings{Aliases: aliases})
+	if err != nil {
+		return fmt.Errorf("failed to connect container %s to network %s: %w", containerID, networkName, err)
+	}
+	return nil
+}
+
+// networkingConfig attaches a container to a user-defined network under the given aliases
+func networkingConfig(networkName string, aliases []string) *network.NetworkingConfig {
+	if networkName == "" {
+		return nil
+	}
+	return &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			networkName: {Aliases: aliases},
+		},
+	}
+}
+
+// inspectedNetworkingConfig rebuilds the networking configuration of an inspected container,
+// so a recreated container rejoins its user-defined network under the same aliases
+func inspectedNetworkingConfig(inspect types.ContainerJSON) *network.NetworkingConfig {
+	if inspect.HostConfig == nil || inspect.NetworkSettings == nil {
+		return nil
+	}
+
+	mode := inspect.HostConfig.NetworkMode
+	if !mode.IsUserDefined() {
+		return nil
+	}
+
+	endpoint, ok := inspect.NetworkSettings.Networks[string(mode)]
+	if !ok {
+		return nil
+	}
+
+	// Docker adds the short container ID as an alias; the new container gets its own
+	var aliases []string
+	for _, alias := range endpoint.Aliases {
+		if len(inspect.ID) >= 12 && alias == inspect.ID[:12] {
+			continue
+		}
+		aliases = append(aliases, alias)
+	}
+	return networkingConfig(string(mode), aliases)
+}
@@ -0,0 +1,156 @@
+// Package egress implements the proxy that controls outbound traffic of sessions with an
+// egress policy, and the configuration cube hands to it.
+package egress
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Environment variables the proxy is configured through
+const (
+	EnvRules    = "CUBE_EGRESS_RULES"
+	EnvForwards = "CUBE_EGRESS_FORWARDS"
+	EnvListen   = "CUBE_EGRESS_LISTEN"
+)
+
+const (
+	// ProxyPort is where the proxy accepts HTTP proxy requests from sessions
+	ProxyPort = 3128
+	// ForwardBasePort is the first proxy port that forwards to a published session port
+	ForwardBasePort = 20000
+	// StatsPath is the proxy path that reports its counters
+	StatsPath = "/stats"
+)
+
+// Rule allows connections to a network, on the listed ports or on any port if there are none
+type Rule struct {
+	CIDR  string `json:"cidr"`
+	Ports []int  `json:"ports,omitempty"`
+}
+
+// Forward makes the proxy accept connections on ListenPort and pass them on to Target
+// (host:port), which is how published ports reach a session without a route out
+type Forward struct {
+	ListenPort int    `json:"listen_port"`
+	Target     string `json:"target"`
+}
+
+// Stats counts the connections the proxy allowed and denied
+type Stats struct {
+	Allowed    uint64 `json:"allowed"`
+	Denied     uint64 `json:"denied"`
+	LastDenied string `json:"last_denied,omitempty"`
+}
+
+// Policy is a compiled list of rules. The zero Policy denies everything.
+type Policy struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	network *net.IPNet
+	ports   []int
+}
+
+// ParseCIDR parses a CIDR, accepting a bare IP address as a single-host network
+func ParseCIDR(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR %q", value)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q", value)
+	}
+	return network, nil
+}
+
+// NewPolicy compiles rules into a policy
+func NewPolicy(rules []Rule) (*Policy, error) {
+	policy := &Policy{}
+	for _, rule := range rules {
+		network, err := ParseCIDR(rule.CIDR)
+		if err != nil {
+			return nil, err
+		}
+		for _, port := range rule.Ports {
+			if port <= 0 || port > 65535 {
+				return nil, fmt.Errorf("invalid port %d for %s", port, rule.CIDR)
+			}
+		}
+		policy.rules = append(policy.rules, compiledRule{network: network, ports: rule.Ports})
+	}
+	return policy, nil
+}
+
+// Allows reports whether a connection to ip:port is allowed
+func (p *Policy) Allows(ip net.IP, port int) bool {
+	for _, rule := range p.rules {
+		if !rule.network.Contains(ip) {
+			continue
+		}
+		if len(rule.ports) == 0 {
+			return true
+		}
+		for _, allowed := range rule.ports {
+			if allowed == port {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EncodeEnv returns the environment that configures a proxy with the given rules and forwards
+func EncodeEnv(rules []Rule, forwards []Forward) ([]string, error) {
+	if rules == nil {
+		rules = []Rule{}
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode egress rules: %v", err)
+	}
+	if forwards == nil {
+		forwards = []Forward{}
+	}
+	forwardsJSON, err := json.Marshal(forwards)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode egress forwards: %v", err)
+	}
+
+	return []string{
+		EnvRules + "=" + string(rulesJSON),
+		EnvForwards + "=" + string(forwardsJSON),
+	}, nil
+}
+
+// ConfigFromEnv reads the proxy configuration from the environment
+func ConfigFromEnv() ([]Rule, []Forward, error) {
+	var rules []Rule
+	if raw := os.Getenv(EnvRules); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %v", EnvRules, err)
+		}
+	}
+
+	var forwards []Forward
+	if raw := os.Getenv(EnvForwards); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &forwards); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %v", EnvForwards, err)
+		}
+	}
+	return rules, forwards, nil
+}
@@ -0,0 +1,129 @@
+package egress
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseCIDR(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "10.0.0.0/8", want: "10.0.0.0/8"},
+		{value: "10.1.2.3/8", want: "10.0.0.0/8"},
+		{value: "10.1.2.3", want: "10.1.2.3/32"},
+		{value: "2001:db8::/32", want: "2001:db8::/32"},
+		{value: "2001:db8::1", want: "2001:db8::1/128"},
+		{value: "::ffff:10.1.2.3", want: "10.1.2.3/32"},
+		{value: "example.com", wantErr: true},
+		{value: "10.0.0.0/33", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		network, err := ParseCIDR(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCIDR(%q) = %v, want an error", tt.value, network)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCIDR(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if network.String() != tt.want {
+			t.Errorf("ParseCIDR(%q) = %s, want %s", tt.value, network, tt.want)
+		}
+	}
+}
+
+func TestNewPolicyRejectsInvalidRules(t *testing.T) {
+	for _, rules := range [][]Rule{
+		{{CIDR: "not-an-ip"}},
+		{{CIDR: "10.0.0.0/8", Ports: []int{0}}},
+		{{CIDR: "10.0.0.0/8", Ports: []int{65536}}},
+	} {
+		if _, err := NewPolicy(rules); err == nil {
+			t.Errorf("NewPolicy(%+v) succeeded, want an error", rules)
+		}
+	}
+}
+
+func TestPolicyAllows(t *testing.T) {
+	policy, err := NewPolicy([]Rule{
+		{CIDR: "140.82.112.0/20", Ports: []int{443}},
+		{CIDR: "10.1.2.3"},
+		{CIDR: "2001:db8::/32", Ports: []int{80, 443}},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		port int
+		want bool
+	}{
+		{ip: "140.82.112.3", port: 443, want: true},
+		{ip: "140.82.127.255", port: 443, want: true},
+		{ip: "140.82.112.3", port: 80, want: false},
+		{ip: "140.82.128.0", port: 443, want: false},
+		{ip: "10.1.2.3", port: 22, want: true},
+		{ip: "10.1.2.3", port: 65535, want: true},
+		{ip: "10.1.2.4", port: 22, want: false},
+		{ip: "::ffff:10.1.2.3", port: 22, want: true},
+		{ip: "2001:db8::1", port: 443, want: true},
+		{ip: "2001:db8:ffff::1", port: 80, want: true},
+		{ip: "2001:db8::1", port: 22, want: false},
+		{ip: "2001:db9::1", port: 443, want: false},
+		{ip: "127.0.0.1", port: 443, want: false},
+		{ip: "::1", port: 443, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Allows(net.ParseIP(tt.ip), tt.port); got != tt.want {
+			t.Errorf("Allows(%s, %d) = %v, want %v", tt.ip, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestEmptyPolicyDeniesEverything(t *testing.T) {
+	policy, err := NewPolicy(nil)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	for _, ip := range []string{"1.1.1.1", "10.0.0.1", "::1"} {
+		if policy.Allows(net.ParseIP(ip), 443) {
+			t.Errorf("empty policy allows %s", ip)
+		}
+	}
+}
+
+func TestEnvRoundTrip(t *testing.T) {
+	rules := []Rule{{CIDR: "10.0.0.0/8", Ports: []int{443}}}
+	forwards := []Forward{{ListenPort: ForwardBasePort, Target: "session:80"}}
+
+	env, err := EncodeEnv(rules, forwards)
+	if err != nil {
+		t.Fatalf("EncodeEnv: %v", err)
+	}
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		t.Setenv(name, value)
+	}
+
+	gotRules, gotForwards, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv: %v", err)
+	}
+	if len(gotRules) != 1 || gotRules[0].CIDR != "10.0.0.0/8" || len(gotRules[0].Ports) != 1 || gotRules[0].Ports[0] != 443 {
+		t.Errorf("rules = %+v, want %+v", gotRules, rules)
+	}
+	if len(gotForwards) != 1 || gotForwards[0] != forwards[0] {
+		t.Errorf("forwards = %+v, want %+v", gotForwards, forwards)
+	}
+}
@@ -0,0 +1,224 @@
+package egress
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourusername/session-manager/pkg/util"
+)
+
+// errDenied is returned for connections the policy does not allow
+var errDenied = errors.New("destination not allowed by egress policy")
+
+// Proxy is an HTTP proxy that only connects to destinations its policy allows. Sessions
+// reach it through HTTP_PROXY and HTTPS_PROXY; plain HTTP requests are forwarded and
+// anything else, HTTPS included, is tunnelled with CONNECT.
+type Proxy struct {
+	policy   *Policy
+	resolver *net.Resolver
+	dialer   *net.Dialer
+	logger   *util.Logger
+
+	allowed    atomic.Uint64
+	denied     atomic.Uint64
+	lastDenied atomic.Value // string
+
+	forwarder *httputil.ReverseProxy
+}
+
+// NewProxy creates a proxy enforcing a policy
+func NewProxy(policy *Policy) *Proxy {
+	p := &Proxy{
+		policy:   policy,
+		resolver: net.DefaultResolver,
+		dialer:   &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		logger:   util.NewLogger(),
+	}
+
+	p.forwarder = &httputil.ReverseProxy{
+		// Requests to a proxy already carry their absolute destination URL
+		Director: func(r *http.Request) {},
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           p.dial,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			ResponseHeaderTimeout: time.Minute,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, errDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+	return p
+}
+
+// Stats returns the proxy's counters
+func (p *Proxy) Stats() Stats {
+	stats := Stats{Allowed: p.allowed.Load(), Denied: p.denied.Load()}
+	if last, ok := p.lastDenied.Load().(string); ok {
+		stats.LastDenied = last
+	}
+	return stats
+}
+
+// ServeHTTP handles proxy requests, and requests for the proxy's own stats
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodConnect:
+		p.tunnel(w, r)
+	case r.URL.IsAbs():
+		p.forwarder.ServeHTTP(w, r)
+	case r.URL.Path == StatsPath:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p.Stats())
+	default:
+		http.Error(w, "this is an egress proxy", http.StatusBadRequest)
+	}
+}
+
+// tunnel connects a CONNECT request to its destination and relays bytes both ways
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errDenied) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer upstream.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection cannot be tunnelled", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		p.logger.Error("Failed to take over connection for %s: %v", r.Host, err)
+		return
+	}
+	defer client.Close()
+
+	if _, err := io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		return
+	}
+
+	// Anything the client sent after the request headers is already buffered
+	if n := buffered.Reader.Buffered(); n > 0 {
+		data, _ := buffered.Reader.Peek(n)
+		if _, err := upstream.Write(data); err != nil {
+			return
+		}
+	}
+	relay(client, upstream)
+}
+
+// dial resolves a destination and connects to the first of its addresses the policy allows
+func (p *Proxy) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	host, portValue, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in %s", address)
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := p.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %v", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if !p.policy.Allows(ip, port) {
+			continue
+		}
+		conn, err := p.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), portValue))
+		if err != nil {
+			return nil, err
+		}
+		p.allowed.Add(1)
+		return conn, nil
+	}
+
+	p.denied.Add(1)
+	p.lastDenied.Store(address)
+	p.logger.Warn("Denied connection to %s", address)
+	return nil, errDenied
+}
+
+// Forward accepts connections on a port and relays them to a target until ctx ends
+func (p *Proxy) Forward(ctx context.Context, forward Forward) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", forward.ListenPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %v", forward.ListenPort, err)
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		client, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer client.Close()
+			upstream, err := p.dialer.DialContext(ctx, "tcp", forward.Target)
+			if err != nil {
+				p.logger.Warn("Failed to reach %s: %v", forward.Target, err)
+				return
+			}
+			defer upstream.Close()
+			relay(client, upstream)
+		}()
+	}
+}
+
+// relay copies bytes between two connections until either side is done
+func relay(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copyHalf := func(dst, src net.Conn) {
+		defer wg.Done()
+		io.Copy(dst, src)
+		// Let the other side see EOF without tearing down the reverse direction
+		if conn, ok := dst.(interface{ CloseWrite() error }); ok {
+			conn.CloseWrite()
+		} else {
+			dst.Close()
+		}
+	}
+	go copyHalf(a, b)
+	go copyHalf(b, a)
+	wg.Wait()
+}
@@ -0,0 +1,127 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// listenLocal starts a TCP listener on the loopback address that accepts and closes connections
+func listenLocal(t *testing.T) (string, int) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return listener.Addr().String(), portNumber
+}
+
+func newTestProxy(t *testing.T, rules ...Rule) *Proxy {
+	t.Helper()
+
+	policy, err := NewPolicy(rules)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	return NewProxy(policy)
+}
+
+func TestDialAllowsPermittedAddress(t *testing.T) {
+	address, port := listenLocal(t)
+	proxy := newTestProxy(t, Rule{CIDR: "127.0.0.1", Ports: []int{port}})
+
+	conn, err := proxy.dial(context.Background(), "tcp", address)
+	if err != nil {
+		t.Fatalf("dial(%s) failed: %v", address, err)
+	}
+	conn.Close()
+
+	if stats := proxy.Stats(); stats.Allowed != 1 || stats.Denied != 0 {
+		t.Fatalf("stats = %+v, want one allowed connection", stats)
+	}
+}
+
+func TestDialDeniesOtherPorts(t *testing.T) {
+	address, port := listenLocal(t)
+	proxy := newTestProxy(t, Rule{CIDR: "127.0.0.1", Ports: []int{port + 1}})
+
+	if _, err := proxy.dial(context.Background(), "tcp", address); !errors.Is(err, errDenied) {
+		t.Fatalf("dial(%s) error = %v, want errDenied", address, err)
+	}
+
+	stats := proxy.Stats()
+	if stats.Allowed != 0 || stats.Denied != 1 || stats.LastDenied != address {
+		t.Fatalf("stats = %+v, want one denied connection to %s", stats, address)
+	}
+}
+
+func TestDialDeniesHostnameResolvingToDeniedAddress(t *testing.T) {
+	_, port := listenLocal(t)
+	// localhost resolves to loopback addresses, none of which are in the allowlist
+	proxy := newTestProxy(t, Rule{CIDR: "10.0.0.0/8"}, Rule{CIDR: "2001:db8::/32"})
+
+	address := net.JoinHostPort("localhost", strconv.Itoa(port))
+	if _, err := proxy.dial(context.Background(), "tcp", address); !errors.Is(err, errDenied) {
+		t.Fatalf("dial(%s) error = %v, want errDenied", address, err)
+	}
+	if stats := proxy.Stats(); stats.Denied != 1 || stats.LastDenied != address {
+		t.Fatalf("stats = %+v, want one denied connection to %s", stats, address)
+	}
+}
+
+func TestDialAllowsHostnameResolvingToAllowedAddress(t *testing.T) {
+	_, port := listenLocal(t)
+	proxy := newTestProxy(t, Rule{CIDR: "127.0.0.0/8", Ports: []int{port}})
+
+	conn, err := proxy.dial(context.Background(), "tcp", net.JoinHostPort("localhost", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestDialDeniesIPv6Address(t *testing.T) {
+	proxy := newTestProxy(t, Rule{CIDR: "2001:db8::/32", Ports: []int{443}})
+
+	if _, err := proxy.dial(context.Background(), "tcp", "[2001:db9::1]:443"); !errors.Is(err, errDenied) {
+		t.Fatalf("dial error = %v, want errDenied", err)
+	}
+	if stats := proxy.Stats(); stats.Denied != 1 {
+		t.Fatalf("stats = %+v, want one denied connection", stats)
+	}
+}
+
+func TestConnectToDeniedDestinationIsForbidden(t *testing.T) {
+	address, _ := listenLocal(t)
+	proxy := newTestProxy(t)
+
+	req := httptest.NewRequest(http.MethodConnect, "http://"+address, nil)
+	req.Host = address
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rec.Code)
+	}
+	if stats := proxy.Stats(); stats.Denied != 1 {
+		t.Fatalf("stats = %+v, want one denied connection", stats)
+	}
+}
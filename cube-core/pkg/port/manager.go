@@ -50,6 +50,19 @@ func (pm *PortManager) GetAvailablePort() (int, error) {
 	return pm.GetAvailablePort()
 }
 
+// ReservePort marks a specific port as used, e.g. when restoring persisted sessions.
+// It returns false if the port was already reserved.
+func (pm *PortManager) ReservePort(port int) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.usedPorts[port] {
+		return false
+	}
+	pm.usedPorts[port] = true
+	return true
+}
+
 // ReleasePort releases a single previously used port
 func (pm *PortManager) ReleasePort(port int) {
 	pm.mu.Lock()
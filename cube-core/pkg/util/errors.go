@@ -14,6 +14,7 @@ var (
 	ErrResourceBusy      = errors.New("resource is busy")
 	ErrUnavailable       = errors.New("resource is unavailable")
 	ErrOperationNotValid = errors.New("operation not valid")
+	ErrTooLarge          = errors.New("resource too large")
 )
 
 // WrapError wraps an error with a prefix
@@ -39,3 +40,18 @@ func IsInvalidRequestError(err error) bool {
 func IsInternalServerError(err error) bool {
 	return errors.Is(err, ErrInternalServer)
 }
+
+// IsOperationNotValidError checks if the error is an operation not valid error
+func IsOperationNotValidError(err error) bool {
+	return errors.Is(err, ErrOperationNotValid)
+}
+
+// IsUnavailableError checks if the error is a resource busy or unavailable error
+func IsUnavailableError(err error) bool {
+	return errors.Is(err, ErrResourceBusy) || errors.Is(err, ErrUnavailable)
+}
+
+// IsTooLargeError checks if the error is a too large error
+func IsTooLargeError(err error) bool {
+	return errors.Is(err, ErrTooLarge)
+}
@@ -101,6 +101,25 @@ function App() {
     fetchImages();
   }, []);
 
+  // Follow a new session until it is provisioned and ready, or gives up
+  const followSession = async (session) => {
+    let current = session;
+    while (api.PENDING_STATUSES.includes(current.status)) {
+      try {
+        current = await api.waitForSession(current.id);
+      } catch (err) {
+        if (err.response?.status === 404) {
+          setSessions((prev) => prev.filter((s) => s.id !== session.id));
+        }
+        return;
+      }
+      const updated = current;
+      setSessions((prev) =>
+        prev.map((s) => (s.id === updated.id ? updated : s)),
+      );
+    }
+  };
+
   const handleCreateSession = async () => {
     if (!selectedImage) {
       setSnackbar({
@@ -117,11 +136,13 @@ function App() {
         image_name: selectedImage,
       };
 
+      // The session comes back while its container is still being provisioned
       const newSession = await api.createSession(config);
       setSessions((prev) => [...prev, newSession]);
+      followSession(newSession);
       setSnackbar({
         open: true,
-        message: "New session created successfully!",
+        message: "New session is being created",
         severity: "success",
       });
       setCreateDialogOpen(false);
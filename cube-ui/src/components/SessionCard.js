@@ -22,6 +22,20 @@ import {
 } from "@mui/icons-material";
 import SessionMetrics from "./SessionMetrics";
 
+// Chip colours per session status; anything not listed is an error
+const STATUS_COLORS = {
+  provisioning: "info",
+  starting: "info",
+  running: "success",
+  ready: "success",
+  stopped: "default",
+  paused: "default",
+  expired: "default",
+};
+
+// Statuses in which the container is up and has metrics
+const METRICS_STATUSES = ["running", "ready", "starting", "failed"];
+
 const SessionCard = ({ session, onDelete }) => {
   const [isDeleting, setIsDeleting] = useState(false);
 
@@ -67,13 +81,22 @@ const SessionCard = ({ session, onDelete }) => {
                   Session: {shortId}...
                 </Typography>
                 <Typography variant="subtitle2" color="text.secondary">
-                  Container: {shortContainerId}...
+                  Container:{" "}
+                  {shortContainerId
+                    ? `${shortContainerId}...`
+                    : "not created yet"}
                 </Typography>
               </Box>
               <Box>
                 <Chip
                   label={session.status}
-                  color={session.status === "running" ? "success" : "error"}
+                  color={STATUS_COLORS[session.status] || "error"}
+                  icon={
+                    session.status === "provisioning" ||
+                    session.status === "starting" ? (
+                      <CircularProgress size={12} color="inherit" />
+                    ) : undefined
+                  }
                   size="small"
                   sx={{ mr: 1 }}
                 />
@@ -88,6 +111,11 @@ const SessionCard = ({ session, onDelete }) => {
             <Typography color="text.secondary" gutterBottom>
               Created: {formatDate(session.created_at)}
             </Typography>
+            {session.status_reason && (
+              <Typography variant="body2" color="text.secondary">
+                {session.status_reason}
+              </Typography>
+            )}
           </Grid>
 
           <Grid item xs={12}>
@@ -129,13 +157,15 @@ const SessionCard = ({ session, onDelete }) => {
               </List>
             ) : (
               <Typography variant="body2" color="text.secondary">
-                No ports exposed
+                {session.status === "provisioning"
+                  ? "Ports are allocated once the container is created"
+                  : "No ports exposed"}
               </Typography>
             )}
           </Grid>
 
-          {/* Add the SessionMetrics component if the session is running */}
-          {session.status === "running" && (
+          {/* Add the SessionMetrics component while the container is up */}
+          {METRICS_STATUSES.includes(session.status) && (
             <Grid item xs={12}>
               <SessionMetrics sessionId={session.id} />
             </Grid>
@@ -18,6 +18,22 @@ export const createSession = async (imageConfig) => {
   }
 };
 
+// Statuses of sessions whose container is still being created or checked for readiness
+export const PENDING_STATUSES = ["provisioning", "starting"];
+
+// Long-polls a session until provisioning and readiness checks finish, or the timeout passes
+export const waitForSession = async (sessionId, timeout = "50s") => {
+  try {
+    const response = await axios.get(`${API_BASE_URL}/sessions/${sessionId}`, {
+      params: { wait: "ready", timeout },
+    });
+    return response.data.session;
+  } catch (error) {
+    console.error(`Error waiting for session ${sessionId}:`, error);
+    throw error;
+  }
+};
+
 export const listSessions = async () => {
   try {
     const response = await axios.get(`${API_BASE_URL}/sessions`);